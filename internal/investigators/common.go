@@ -24,12 +24,26 @@ import (
 
 var lock = &sync.Mutex{}
 
+// Confidence contributed by investigators, between 0 and 1.
+const (
+	// an event matches a known error message
+	MatchedEventConfidence = 0.8
+	// the container exit code indicates the cause
+	ExitCodeConfidence = 0.7
+)
+
+// Max log lines added as evidence.
+const maxLogEvidence = 5
+
 // Default Timespan, used in Event Filtering.
 var DefaultTimespan = problem.TimeSpan{
 	Timespan:     48,
 	TimespanType: time.Hour,
 }
 
+// Events and logs of a historical detection are retrieved from this long before the window.
+const historicalLookback = time.Hour
+
 // A general template instance.
 var solutionTemp = template.New("solutionTemp")
 
@@ -69,6 +83,8 @@ func getPodSolutionFromEvents(ctx context.Context, problem *problem.Problem,
 				matched, err := regexp.MatchString(strings.ToLower(msg), strings.ToLower(event.Message))
 				if err == nil && matched {
 					l.Infof("Found event with error '%s'", msg)
+					addEventEvidence(problem, event)
+					setConfidence(problem, MatchedEventConfidence)
 					addSolutionFromMap(ctx, problem, pod, status, msg, solutions)
 					return msg
 				}
@@ -81,8 +97,12 @@ func getPodSolutionFromEvents(ctx context.Context, problem *problem.Problem,
 
 func GetResourceEvents(ctx context.Context, input *problem.DetectorCreationInput, name string, namespace string) ([]observability.EventRecord, error) {
 
-	filter := CreateEventFilterCriteria(DefaultTimespan, input.EventRetriever.AddFilters(name, namespace))
-	eventDataRef := input.EventRetriever.Retrieve(filter)
+	start, end := timeRange(input)
+	eventDataRef := input.EventRetriever.Retrieve(observability.EventFilterCriteria{
+		StartTime:      start,
+		EndTime:        end,
+		FilterCriteria: input.EventRetriever.AddFilters(name, namespace),
+	})
 	return eventDataRef.GetEvents(ctx)
 }
 
@@ -153,3 +173,59 @@ func appendSolution(problem *problem.Problem, solutions interface{}, commands in
 		}
 	}
 }
+
+func addEventEvidence(p *problem.Problem, event observability.EventRecord) {
+	if p.Findings == nil {
+		return
+	}
+	msg := event.Message
+	if event.Reason != "" {
+		msg = event.Reason + ": " + msg
+	}
+	p.Findings.AddEvidence(problem.EvidenceEvent, msg, event.LastTimestamp)
+}
+
+func addStatusEvidence(p *problem.Problem, msg string) {
+	if p.Findings == nil || msg == "" {
+		return
+	}
+	p.Findings.AddEvidence(problem.EvidenceStatus, msg, time.Time{})
+}
+
+// Adds the latest log lines of the container as evidence, if log driver is configured.
+func addLogEvidence(ctx context.Context, p *problem.Problem, input *problem.DetectorCreationInput,
+	pod *v1.Pod, container string) {
+	if p.Findings == nil || input.LogRetriever == nil || container == "" {
+		return
+	}
+	filter := input.LogRetriever.AddFilters(pod.Name, container, pod.Namespace)
+	start, end := timeRange(input)
+	logs, err := input.LogRetriever.Retrieve(observability.LogFilterCriteria{
+		FilterCriteria: filter,
+		StartTime:      start,
+		EndTime:        end,
+	}).GetLogs(ctx)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Got error when retrieving logs of container %s, error is %s", container, err)
+		return
+	}
+	// logs are sorted by timestamp descending
+	for i := 0; i < len(logs) && i < maxLogEvidence; i++ {
+		p.Findings.AddEvidence(problem.EvidenceLog, logs[i].Message, logs[i].Timestamp)
+	}
+}
+
+func setConfidence(p *problem.Problem, confidence float64) {
+	if p.Findings != nil {
+		p.Findings.SetConfidence(confidence)
+	}
+}
+
+// Time range of events and logs, around the window of historical detection, or DefaultTimespan until now.
+func timeRange(input *problem.DetectorCreationInput) (time.Time, time.Time) {
+	if input.Window != nil {
+		return input.Window.From.Add(-historicalLookback), input.Window.To
+	}
+	now := time.Now()
+	return SetStartTime(now, DefaultTimespan), now
+}
@@ -7,6 +7,7 @@ package investigators
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	com "github.com/fidelity/theliv/pkg/common"
@@ -17,6 +18,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -64,8 +66,11 @@ func CommonInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *proble
 	switch problem.Tags[com.Resourcetype] {
 	case com.Pod:
 		loadPodDetails(ctx, problem)
+		addMetrics(ctx, problem, input, podMetricTarget(problem.AffectedResources.Resource.(*v1.Pod), ""))
 	case com.Container:
 		loadContainerDetails(ctx, problem)
+		addMetrics(ctx, problem, input,
+			podMetricTarget(problem.AffectedResources.Resource.(*v1.Pod), problem.Tags[com.Container]))
 	case com.Initcontainer:
 		loadContainerDetails(ctx, problem)
 	case com.Deployment:
@@ -89,6 +94,10 @@ func CommonInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *proble
 	case com.Endpoint:
 		loadEndpointsDetails(ctx, problem)
 	default:
+		if obj, ok := problem.AffectedResources.Resource.(*unstructured.Unstructured); ok {
+			loadCustomResourceDetails(ctx, problem, obj)
+			return
+		}
 		log.SWithContext(ctx).Warnf("Not found investigator function for resource type %s", problem.Tags[com.Resourcetype])
 	}
 }
@@ -273,8 +282,10 @@ func buildReasonMsg(reason string, message string) []string {
 
 func appendDetail(problem *problem.Problem, detail []string,
 	msg string, reason string) {
-	details := append(detail, buildReasonMsg(reason, msg)...)
+	reasonMsg := buildReasonMsg(reason, msg)
+	details := append(detail, reasonMsg...)
 	appendSolution(problem, details, nil)
+	addStatusEvidence(problem, strings.Join(reasonMsg, " "))
 }
 
 func appendNonEmptyDetail(problem *problem.Problem, conType string,
@@ -33,9 +33,6 @@ const (
 
 	CrushLoopBackOffMsg = `
 1. Container {{.ContainerName}} has been restarted more than 10 times in the last few minutes.
-`
-	CrashLoopBackOffDocLink = `
-https://containersolutions.github.io/runbooks/posts/kubernetes/crashloopbackoff
 `
 )
 
@@ -76,17 +73,18 @@ const (
 	SolutionStartupProbeFailMsg = `2. Following startup probe has failed for the container {{.ContainerName}}.
 `
 	SolutionExecutableNotFoundMsg = `2. Container {{.ContainerName}} has EXITED with a non-zero exit code (127). Check your command or application startup logs.
-3. Give more insights in the UI based on this https://intl.cloud.tencent.com/document/product/457/35758. E.g if exit code is 127, then look at your \"command\" and make sure it is correct. Problem is there.
+3. Exit code 127 generally means the \"command\" of the container is incorrect, please make sure it is correct. See the documents below for more exit codes.
 4. Container {{.ContainerName}} was unable to start, logs can be retrieved by 1 of the following useful commands.
 `
 	SolutionNoSuchFile = `2. Container {{.ContainerName}} has EXITED with a non-zero exit code (127). Check your commands or application startup logs.
-3. Give more insights in the UI based on this https://intl.cloud.tencent.com/document/product/457/35758. E.g if exit code is 127, then look at your \"command\" and make sure it is correct. Problem is there.
+3. Exit code 127 generally means the \"command\" of the container is incorrect, please make sure it is correct. See the documents below for more exit codes.
 4. Container {{.ContainerName}} was unable to start, logs can be retrieved by 1 of the following useful commands.
 `
 	DefaultSolution = `2. This may due to container resource request not enough, readiness probe or liveness probe failed. Not enough initial delay.
 3. Or may due to commands inside container failed, command not found in path, readonly file system, missing configurations or dependencies.
 4. Use the commands below to check logs or events can help found the root cause.
-5. Below docs can help understand this issue:` + CrashLoopBackOffDocLink
+5. The documents below can help understand this issue.
+`
 )
 
 type CrushLoopPodInfo struct {
@@ -116,12 +114,17 @@ func ContainerCrashLoopBackoffInvestigator(ctx context.Context, wg *sync.WaitGro
 	defer wg.Done()
 
 	pod := *problem.AffectedResources.Resource.(*v1.Pod)
+	container := getContainerName(&pod, CrashLoopBackOff)
+	addLogEvidence(ctx, problem, input, &pod, container)
+	addMetrics(ctx, problem, input, podMetricTarget(&pod, container))
 
 	for _, status := range pod.Status.ContainerStatuses {
 		if status.State.Waiting != nil && status.State.Waiting.Reason == CrashLoopBackOff {
 			if getPodSolutionFromEvents(ctx, problem, input, &pod, &status, CrashLoopBackOffSolutions) == "" {
 				code := getRootCauseByExitCode(&pod)
 				if code != "" {
+					addStatusEvidence(problem, code)
+					setConfidence(problem, ExitCodeConfidence)
 					addSolutionFromMap(ctx, problem, &pod, nil, code, CrashLoopBackOffSolutions)
 					return
 				}
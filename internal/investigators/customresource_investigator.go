@@ -0,0 +1,142 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package investigators
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// A failed status condition of the custom resource has a message.
+const ConditionConfidence = 0.6
+
+const (
+	GenerationLagSolution = `
+{{.Kind}} '{{.Name}}' has not reconciled the latest spec, status.observedGeneration is {{.ObservedGeneration}} while metadata.generation is {{.Generation}}.
+Please check the logs of the controller of {{.Kind}}.
+`
+	NoConditionSolution = `
+No failed condition found in the status of {{.Kind}} '{{.Name}}'.
+`
+	DescribeCustomResourceCmd = `
+kubectl describe {{.Resource}} {{.Name}}{{if .Namespace}} -n {{.Namespace}}{{end}}
+`
+)
+
+// Conditions of a healthy custom resource when True, e.g. Ready and Synced of Crossplane resources.
+var readyConditions = []string{"Ready", "Available", "Synced"}
+
+// Conditions of an unhealthy custom resource when True, by the kstatus convention.
+var failedConditions = []string{"Stalled", "Degraded"}
+
+type customResource struct {
+	Kind               string
+	Resource           string
+	Name               string
+	Namespace          string
+	Generation         int64
+	ObservedGeneration int64
+}
+
+// ResourceCondition is a status condition of a resource of any kind.
+type ResourceCondition struct {
+	Type               string
+	Status             string
+	Reason             string
+	Message            string
+	ObservedGeneration int64
+}
+
+// Checks the status conditions and observed generation of a resource of any kind.
+func loadCustomResourceDetails(ctx context.Context, problem *problem.Problem, obj *unstructured.Unstructured) {
+	logChecking(ctx, obj.GetKind()+com.Blank+obj.GetName())
+	cr := customResource{
+		Kind:       obj.GetKind(),
+		Resource:   strings.ToLower(obj.GetKind()),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		Generation: obj.GetGeneration(),
+	}
+	if group := obj.GroupVersionKind().Group; group != "" {
+		cr.Resource += "." + group
+	}
+	cr.ObservedGeneration, _, _ = unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+
+	found := appendFailedConditions(problem, obj, conditionFailed)
+	if cr.ObservedGeneration > 0 && cr.ObservedGeneration < cr.Generation {
+		found = true
+		solution := GetSolutionsByTemplate(ctx, GenerationLagSolution, cr, true)
+		appendSolution(problem, solution, nil)
+		addStatusEvidence(problem, strings.Join(solution, " "))
+	}
+	if !found {
+		appendSolution(problem, GetSolutionsByTemplate(ctx, NoConditionSolution, cr, true), nil)
+	}
+	appendSolution(problem, nil, GetSolutionsByTemplate(ctx, DescribeCustomResourceCmd, cr, true))
+}
+
+// Appends the details and evidence of the failed conditions, returns false if no condition failed.
+func appendFailedConditions(problem *problem.Problem, obj *unstructured.Unstructured,
+	failed func(c ResourceCondition) bool) bool {
+	found := false
+	for _, c := range GetConditions(obj) {
+		if !failed(c) {
+			continue
+		}
+		found = true
+		detail := []string{FoundMsg, "Found Status: " + c.Type + "=" + c.Status + "."}
+		if c.ObservedGeneration > 0 && c.ObservedGeneration < obj.GetGeneration() {
+			detail = append(detail, fmt.Sprintf("The condition is observed at generation %d, the latest is %d.",
+				c.ObservedGeneration, obj.GetGeneration()))
+		}
+		appendDetail(problem, detail, c.Message, c.Reason)
+		if c.Message != "" {
+			setConfidence(problem, ConditionConfidence)
+		}
+	}
+	return found
+}
+
+// GetConditions returns status.conditions of a resource of any kind.
+func GetConditions(obj *unstructured.Unstructured) []ResourceCondition {
+	list, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	conditions := make([]ResourceCondition, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		c := ResourceCondition{}
+		c.Type, _, _ = unstructured.NestedString(m, "type")
+		c.Status, _, _ = unstructured.NestedString(m, "status")
+		c.Reason, _, _ = unstructured.NestedString(m, "reason")
+		c.Message, _, _ = unstructured.NestedString(m, "message")
+		c.ObservedGeneration, _, _ = unstructured.NestedInt64(m, "observedGeneration")
+		conditions = append(conditions, c)
+	}
+	return conditions
+}
+
+// GetCondition returns the status condition of the type, false if not found.
+func GetCondition(obj *unstructured.Unstructured, cType string) (ResourceCondition, bool) {
+	for _, c := range GetConditions(obj) {
+		if c.Type == cType {
+			return c, true
+		}
+	}
+	return ResourceCondition{}, false
+}
+
+func conditionFailed(c ResourceCondition) bool {
+	return (slices.Contains(readyConditions, c.Type) && c.Status == "False") ||
+		(slices.Contains(failedConditions, c.Type) && c.Status == "True")
+}
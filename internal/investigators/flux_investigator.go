@@ -0,0 +1,212 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package investigators
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// A failing source fetch is the likely cause of a not ready HelmRelease or Kustomization.
+const FluxSourceConfidence = 0.8
+
+const (
+	FluxRetriesExhaustedSolution = `
+{{.Action}} of HelmRelease '{{.Name}}' failed {{.Failures}} time(s), the {{.Retries}} retries are exhausted.
+helm-controller won't retry until the spec changes, fix the failure then run 'flux reconcile helmrelease {{.Name}} -n {{.Namespace}} --force'.
+`
+	FluxRetryingSolution = `
+{{.Action}} of HelmRelease '{{.Name}}' failed {{.Failures}} time(s), helm-controller is retrying{{if ge .Retries 0}}, {{.Retries}} retries configured{{end}}.
+`
+	FluxRevisionSolution = `
+Revision {{.Attempted}} failed to apply, {{if .Applied}}{{.Applied}} is the last applied revision{{else}}no revision has been applied{{end}}.
+`
+	FluxSourceSolution = `
+Source {{.Kind}} '{{.Name}}' in namespace {{.Namespace}} is not ready, the artifact can't be fetched.
+`
+	FluxCmd = `
+flux get {{.Get}} {{.Name}} -n {{.Namespace}}
+flux logs --kind={{.Kind}} --name={{.Name}} -n {{.Namespace}}
+flux reconcile {{.Reconcile}} {{.Name}} -n {{.Namespace}}{{if .WithSource}} --with-source{{end}}
+`
+)
+
+// Conditions of a failed Flux object when False.
+var fluxReadyConditions = []string{"Ready", "Released", "TestSuccess", "Healthy", "SourceVerified"}
+
+// Conditions of a failed Flux object when True, Remediated is set after a rollback or uninstall.
+var fluxFailedConditions = []string{"Stalled", "FetchFailed", "StorageOperationFailed", "Remediated"}
+
+// Arguments of the flux commands by kind.
+var fluxCommands = map[string][2]string{
+	problem.FluxHelmRelease:    {"helmreleases", "helmrelease"},
+	problem.FluxKustomization:  {"kustomizations", "kustomization"},
+	problem.FluxGitRepository:  {"sources git", "source git"},
+	problem.FluxHelmRepository: {"sources helm", "source helm"},
+}
+
+type fluxObject struct {
+	Kind       string
+	Name       string
+	Namespace  string
+	Get        string
+	Reconcile  string
+	WithSource bool
+}
+
+type fluxRetries struct {
+	Action    string
+	Name      string
+	Namespace string
+	Failures  int64
+	Retries   int64
+}
+
+type fluxRevision struct {
+	Attempted string
+	Applied   string
+}
+
+func FluxHelmReleaseInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
+	input *problem.DetectorCreationInput) {
+	defer wg.Done()
+
+	obj := problem.AffectedResources.Resource.(*unstructured.Unstructured)
+	loadFluxDetails(ctx, problem, obj)
+	addHelmReleaseRetries(ctx, problem, obj)
+	addFluxRevision(ctx, problem, obj)
+	ref, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "chart", "spec", "sourceRef")
+	if !found {
+		ref, _, _ = unstructured.NestedStringMap(obj.Object, "spec", "chartRef")
+	}
+	addFluxSource(ctx, problem, input.KubeClient, obj, ref)
+	appendFluxCmd(ctx, problem, obj, true)
+}
+
+func FluxKustomizationInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
+	input *problem.DetectorCreationInput) {
+	defer wg.Done()
+
+	obj := problem.AffectedResources.Resource.(*unstructured.Unstructured)
+	loadFluxDetails(ctx, problem, obj)
+	addFluxRevision(ctx, problem, obj)
+	ref, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "sourceRef")
+	addFluxSource(ctx, problem, input.KubeClient, obj, ref)
+	appendFluxCmd(ctx, problem, obj, true)
+}
+
+func FluxSourceInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
+	input *problem.DetectorCreationInput) {
+	defer wg.Done()
+
+	obj := problem.AffectedResources.Resource.(*unstructured.Unstructured)
+	loadFluxDetails(ctx, problem, obj)
+	appendFluxCmd(ctx, problem, obj, false)
+}
+
+func loadFluxDetails(ctx context.Context, problem *problem.Problem, obj *unstructured.Unstructured) {
+	logChecking(ctx, obj.GetKind()+com.Blank+obj.GetName())
+	appendFailedConditions(problem, obj, fluxConditionFailed)
+}
+
+// Reports the install and upgrade failures of the HelmRelease, and if the remediation retries are exhausted.
+func addHelmReleaseRetries(ctx context.Context, problem *problem.Problem, obj *unstructured.Unstructured) {
+	for _, action := range []string{"Install", "Upgrade"} {
+		field := strings.ToLower(action)
+		failures, _, _ := unstructured.NestedInt64(obj.Object, "status", field+"Failures")
+		if failures == 0 {
+			continue
+		}
+		retries, _, _ := unstructured.NestedInt64(obj.Object, "spec", field, "remediation", "retries")
+		r := fluxRetries{
+			Action:    action,
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Failures:  failures,
+			Retries:   retries,
+		}
+		// negative retries means retrying forever
+		template := FluxRetryingSolution
+		if retries >= 0 && failures > retries {
+			template = FluxRetriesExhaustedSolution
+		}
+		solution := GetSolutionsByTemplate(ctx, template, r, true)
+		appendSolution(problem, solution, nil)
+		addStatusEvidence(problem, solution[0])
+	}
+}
+
+func addFluxRevision(ctx context.Context, problem *problem.Problem, obj *unstructured.Unstructured) {
+	r := fluxRevision{}
+	r.Attempted, _, _ = unstructured.NestedString(obj.Object, "status", "lastAttemptedRevision")
+	r.Applied, _, _ = unstructured.NestedString(obj.Object, "status", "lastAppliedRevision")
+	if r.Attempted != "" && r.Attempted != r.Applied {
+		appendSolution(problem, GetSolutionsByTemplate(ctx, FluxRevisionSolution, r, true), nil)
+	}
+}
+
+// Reports the source of the HelmRelease or Kustomization if it's not ready, e.g. the git fetch fails.
+func addFluxSource(ctx context.Context, p *problem.Problem, client *kubeclient.KubeClient,
+	obj *unstructured.Unstructured, ref map[string]string) {
+	if client == nil || ref["kind"] == "" || ref["name"] == "" {
+		return
+	}
+	namespace := ref["namespace"]
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	gvk := schema.GroupVersionKind{Group: problem.FluxSourceGroup, Kind: ref["kind"]}
+	source, err := client.GetUnstructured(ctx, gvk, kubeclient.NamespacedName{Name: ref["name"], Namespace: namespace})
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to get source %s %s of %s %s, error is %s",
+			ref["kind"], ref["name"], obj.GetKind(), obj.GetName(), err)
+		return
+	}
+	ready, _ := GetCondition(source, "Ready")
+	fetch, _ := GetCondition(source, "FetchFailed")
+	if ready.Status != "False" && fetch.Status != "True" {
+		return
+	}
+	c := ready
+	if fetch.Status == "True" {
+		c = fetch
+	}
+	detail := GetSolutionsByTemplate(ctx, FluxSourceSolution,
+		fluxObject{Kind: ref["kind"], Name: ref["name"], Namespace: namespace}, true)
+	appendDetail(p, detail, c.Message, c.Reason)
+	setConfidence(p, FluxSourceConfidence)
+	appendFluxCmd(ctx, p, source, false)
+}
+
+func appendFluxCmd(ctx context.Context, problem *problem.Problem, obj *unstructured.Unstructured, withSource bool) {
+	args, ok := fluxCommands[obj.GetKind()]
+	if !ok {
+		return
+	}
+	f := fluxObject{
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		Get:        args[0],
+		Reconcile:  args[1],
+		WithSource: withSource,
+	}
+	appendSolution(problem, nil, GetSolutionsByTemplate(ctx, FluxCmd, f, true))
+}
+
+func fluxConditionFailed(c ResourceCondition) bool {
+	return (slices.Contains(fluxReadyConditions, c.Type) && c.Status == "False") ||
+		(slices.Contains(fluxFailedConditions, c.Type) && c.Status == "True")
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package investigators
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/observability"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// window before the alert became active
+	metricsLookback = 30 * time.Minute
+	// window if the problem has no active time
+	defaultMetricsWindow = time.Hour
+	maxMetricsWindow     = 24 * time.Hour
+	metricsPoints        = 60
+	minMetricsStep       = 15 * time.Second
+)
+
+var (
+	podCodes  = []string{string(problem.CrashLoopErr), string(problem.OOMKilledErr), string(problem.EvictedErr)}
+	nodeCodes = []string{string(problem.NodeNotReadyErr), string(problem.NodeMemoryPressureErr)}
+)
+
+// Used if no query is configured, .Selector is the label matchers of the pod, container or node.
+var DefaultMetricQueries = []config.MetricQuery{
+	{
+		Name:  "memory_working_set",
+		Query: `max(container_memory_working_set_bytes{ {{.Selector}} })`,
+		Unit:  "bytes",
+		Codes: podCodes,
+	},
+	{
+		Name: "memory_limit_ratio",
+		Query: `max(container_memory_working_set_bytes{ {{.Selector}} }) / ` +
+			`max(kube_pod_container_resource_limits{ {{.Selector}},resource="memory" })`,
+		Unit:  "ratio",
+		Codes: []string{string(problem.CrashLoopErr), string(problem.OOMKilledErr)},
+	},
+	{
+		Name: "cpu_throttling_ratio",
+		Query: `sum(rate(container_cpu_cfs_throttled_periods_total{ {{.Selector}} }[5m])) / ` +
+			`sum(rate(container_cpu_cfs_periods_total{ {{.Selector}} }[5m]))`,
+		Unit:  "ratio",
+		Codes: []string{string(problem.CrashLoopErr), string(problem.PodNotReadyErr)},
+	},
+	{
+		Name:  "restart_rate",
+		Query: `sum(increase(kube_pod_container_status_restarts_total{ {{.Selector}} }[15m]))`,
+		Unit:  "restarts/15m",
+		Codes: []string{string(problem.CrashLoopErr), string(problem.OOMKilledErr), string(problem.PodNotReadyErr)},
+	},
+	{
+		Name:  "node_memory_working_set",
+		Query: `sum(container_memory_working_set_bytes{ {{.Selector}},container!="" })`,
+		Unit:  "bytes",
+		Codes: nodeCodes,
+	},
+	{
+		Name:  "node_container_fs_usage",
+		Query: `sum(container_fs_usage_bytes{ {{.Selector}},container!="" })`,
+		Unit:  "bytes",
+		Codes: []string{string(problem.NodeDiskPressureErr)},
+	},
+	{
+		Name:  "node_evicted_pods",
+		Query: `count(kube_pod_status_reason{reason="Evicted"} > 0 and on(namespace, pod) kube_pod_info{ {{.Selector}} })`,
+		Unit:  "pods",
+		Codes: []string{string(problem.NodeDiskPressureErr), string(problem.NodeMemoryPressureErr)},
+	},
+}
+
+// Data of the query templates, Selector includes the cluster matchers of the endpoint.
+type metricTarget struct {
+	Namespace string
+	Pod       string
+	Container string
+	Node      string
+	Selector  string
+}
+
+func podMetricTarget(pod *v1.Pod, container string) metricTarget {
+	selector := fmt.Sprintf(`namespace="%s",pod="%s"`, pod.Namespace, pod.Name)
+	if container != "" {
+		selector += fmt.Sprintf(`,container="%s"`, container)
+	} else {
+		selector += `,container!="",container!="POD"`
+	}
+	return metricTarget{Namespace: pod.Namespace, Pod: pod.Name, Container: container, Node: pod.Spec.NodeName,
+		Selector: selector}
+}
+
+func nodeMetricTarget(node *v1.Node) metricTarget {
+	return metricTarget{Node: node.Name, Selector: fmt.Sprintf(`node="%s"`, node.Name)}
+}
+
+// Runs the range queries of the problem code over the problem window, attaches the summarized series.
+func addMetrics(ctx context.Context, p *problem.Problem, input *problem.DetectorCreationInput, target metricTarget) {
+	if p.Findings == nil || input.MetricRetriever == nil {
+		return
+	}
+	code := p.Code
+	if code == "" {
+		code = problem.GetErrorCode(p.Name)
+	}
+	queries := DefaultMetricQueries
+	if promcfg := config.GetThelivConfig().Prometheus; promcfg != nil && len(promcfg.Queries) > 0 {
+		queries = promcfg.Queries
+	}
+	if matchers := input.MetricRetriever.ClusterMatchers(); matchers != "" {
+		target.Selector = matchers + "," + target.Selector
+	}
+	end := time.Now()
+	if input.Window != nil {
+		end = input.Window.To
+	}
+	start, end, step := metricsWindow(p.ActiveAt, end)
+	for _, q := range queries {
+		if !slices.Contains(q.Codes, string(code)) {
+			continue
+		}
+		query, err := ExecGoTemplate(ctx, q.Query, target)
+		if err != nil {
+			continue
+		}
+		series, err := input.MetricRetriever.QueryRange(ctx, observability.MetricQueryCriteria{
+			Query:     query,
+			StartTime: start,
+			EndTime:   end,
+			Step:      step,
+		})
+		if err != nil {
+			log.SWithContext(ctx).Warnf("Failed to query metric %s, error is %s", q.Name, err)
+			continue
+		}
+		for _, s := range series {
+			if summary := problem.SummarizeSeries(q.Name, q.Unit, query, s); summary != nil {
+				p.Findings.AddMetrics(summary)
+			}
+		}
+	}
+}
+
+// The window starts before the alert became active, step is chosen to return about metricsPoints samples.
+func metricsWindow(activeAt time.Time, now time.Time) (time.Time, time.Time, time.Duration) {
+	start := now.Add(-defaultMetricsWindow)
+	if !activeAt.IsZero() {
+		start = activeAt.Add(-metricsLookback)
+	}
+	if now.Sub(start) > maxMetricsWindow {
+		start = now.Add(-maxMetricsWindow)
+	}
+	step := now.Sub(start) / metricsPoints
+	if step < minMetricsStep {
+		step = minMetricsStep
+	}
+	return start, now, step.Truncate(time.Second)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package investigators
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestMetricsWindow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// no active time, the default window
+	start, end, step := metricsWindow(time.Time{}, now)
+	assert.Equal(t, now.Add(-time.Hour), start)
+	assert.Equal(t, now, end)
+	assert.Equal(t, time.Minute, step)
+
+	// starts before the alert became active
+	start, _, step = metricsWindow(now.Add(-2*time.Hour), now)
+	assert.Equal(t, now.Add(-150*time.Minute), start)
+	assert.Equal(t, 150*time.Second, step)
+
+	// recent alerts still have the lookback
+	start, _, step = metricsWindow(now.Add(-time.Minute), now)
+	assert.Equal(t, now.Add(-31*time.Minute), start)
+	assert.Equal(t, 31*time.Second, step)
+
+	// long running alerts are limited to the max window
+	start, _, step = metricsWindow(now.Add(-72*time.Hour), now)
+	assert.Equal(t, now.Add(-maxMetricsWindow), start)
+	assert.Equal(t, 24*time.Minute, step)
+}
+
+func TestAddMetrics(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "theliv.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(fmt.Sprintf("port: 8080\nclusterDir: %s\n", dir)), 0600))
+	config.NewFileConfigLoader(file).LoadConfigs()
+
+	var mx sync.Mutex
+	queries := make([]string, 0)
+	ends := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mx.Lock()
+		queries = append(queries, r.FormValue("query"))
+		ends = append(ends, r.FormValue("end"))
+		mx.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"container":"app"},"values":[[1704067200,"1"],[1704067260,"3"]]}]}}`))
+	}))
+	defer server.Close()
+
+	window := &problem.TimeWindow{From: time.Unix(1704063600, 0), To: time.Unix(1704067200, 0)}
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Window:      window,
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL,
+			ClusterLabel: "cluster",
+		},
+	}
+	input.MetricRetriever = prometheus.NewMetricRetriever(input)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "team-a"}}
+	p := &problem.Problem{Name: "ContainerWaitingAsCrashLoopBackoff", Code: problem.CrashLoopErr,
+		ActiveAt: window.To.Add(-10 * time.Minute), Findings: problem.InitFindings()}
+
+	addMetrics(context.Background(), p, input, podMetricTarget(pod, "app"))
+
+	// memory, memory limit, cpu throttling and restarts of the crashing container
+	metrics := p.Findings.GetMetrics()
+	assert.Len(t, metrics, 4)
+	assert.Len(t, queries, 4)
+	for _, q := range queries {
+		assert.True(t, strings.Contains(q, `cluster="c1",namespace="team-a",pod="web-1",container="app"`), q)
+	}
+	// historical detection ends at the window end
+	for _, e := range ends {
+		end, err := strconv.ParseFloat(e, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(window.To.Unix()), end)
+	}
+
+	// problems of other codes have no metrics
+	p = &problem.Problem{Name: "DeploymentNotAvailable", Code: problem.DeploymentNotAvailErr, Findings: problem.InitFindings()}
+	addMetrics(context.Background(), p, input, podMetricTarget(pod, ""))
+	assert.Empty(t, p.Findings.GetMetrics())
+}
@@ -64,36 +64,38 @@ const (
 func NodeNotReadyInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
 	input *problem.DetectorCreationInput) {
 	defer wg.Done()
-	getNodeCommonSolution(ctx, problem, NotReadySolution, KubeletCmd)
+	getNodeCommonSolution(ctx, problem, input, NotReadySolution, KubeletCmd)
 }
 
 func NodeDiskPressureInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
 	input *problem.DetectorCreationInput) {
 	defer wg.Done()
-	getNodeCommonSolution(ctx, problem, DiskPressSolution, "")
+	getNodeCommonSolution(ctx, problem, input, DiskPressSolution, "")
 }
 
 func NodeMemoryPressureInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
 	input *problem.DetectorCreationInput) {
 	defer wg.Done()
-	getNodeCommonSolution(ctx, problem, MemPressSolution, FindPoOnNoCmd)
+	getNodeCommonSolution(ctx, problem, input, MemPressSolution, FindPoOnNoCmd)
 }
 
 func NodePIDPressureInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
 	input *problem.DetectorCreationInput) {
 	defer wg.Done()
-	getNodeCommonSolution(ctx, problem, PidPressSolution, FindPoOnNoCmd)
+	getNodeCommonSolution(ctx, problem, input, PidPressSolution, FindPoOnNoCmd)
 }
 
 func NodeNetworkUnavailableInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *problem.Problem,
 	input *problem.DetectorCreationInput) {
 	defer wg.Done()
-	getNodeCommonSolution(ctx, problem, NetUnAvailableSolution, KubeletCmd)
+	getNodeCommonSolution(ctx, problem, input, NetUnAvailableSolution, KubeletCmd)
 }
 
-func getNodeCommonSolution(ctx context.Context, problem *problem.Problem, template string, cmdTemplate string) {
+func getNodeCommonSolution(ctx context.Context, problem *problem.Problem, input *problem.DetectorCreationInput,
+	template string, cmdTemplate string) {
 	no := *problem.AffectedResources.Resource.(*v1.Node)
 	logChecking(ctx, com.Node+com.Blank+no.Name)
+	addMetrics(ctx, problem, input, nodeMetricTarget(&no))
 	solutions := GetSolutionsByTemplate(ctx, template, no, true)
 	var cmd []string = nil
 	if cmdTemplate != "" {
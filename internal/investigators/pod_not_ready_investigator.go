@@ -52,6 +52,7 @@ func PodNotReadyInvestigator(ctx context.Context, wg *sync.WaitGroup, problem *p
 	defer wg.Done()
 
 	pod := *problem.AffectedResources.Resource.(*v1.Pod)
+	addMetrics(ctx, problem, input, podMetricTarget(&pod, ""))
 	for _, con := range pod.Status.Conditions {
 		if con.Type == "Ready" {
 			var solution []string
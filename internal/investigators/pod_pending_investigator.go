@@ -35,18 +35,18 @@ const (
 
 const (
 	FailedSchedulingMessage          = "%d. Pod failed scheduling, message is: %s."
-	NodeUnavailableSolution          = "%d. No node is available for the Pod, you may need to add new Node. Refer to: https://kubernetes.io/docs/tasks/debug-application-cluster/debug-cluster/"
+	NodeUnavailableSolution          = "%d. No node is available for the Pod, you may need to add new Node."
 	PendingNodeUnschedulableSolution = "%d. Some nodes are unschedulable, try to uncordon these nodes may fix this."
 
-	PendingNodeSelectorSolution = "%d. Some nodes don't match the Pod node-selector/affinity, can check and adjust Pod node-selector/affinity. Refer to: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#node-affinity"
-	PendingNodeTaintSolution    = "%d. Some node(s) had taints, that the pod didn't tolerate. Try to modify the pod to tolerate 1 of them. Refer to: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/"
+	PendingNodeSelectorSolution = "%d. Some nodes don't match the Pod node-selector/affinity, can check and adjust Pod node-selector/affinity."
+	PendingNodeTaintSolution    = "%d. Some node(s) had taints, that the pod didn't tolerate. Try to modify the pod to tolerate 1 of them."
 
 	PendingInsufficientSolution = "%d. Some available node(s) has insufficient resources, check the resources that the pod requests or limits, try to modify them to applicable quota."
 	PendingNoHostPortSolution   = "%d. Available node(s) didn't have free ports for the requested pod ports. Please check the HostPort used in the Pod, change/remove it is suggested."
 	PVCNotFoundSolution         = "2. Pod {{ .ObjectMeta.Name }} is pending, used PVC not found." + KubectlPodAndPVC
 	PVCUnboundSolution          = "2. Pod {{ .ObjectMeta.Name }} is pending, due to use an unbound PVC." + KubectlPodAndPVC
 	KubectlPodAndPVC            = `
-3. Please check PVC used by the pod, create new or choose an existing PVC may solve this problem. Refer to: https://kubernetes.io/docs/concepts/storage/persistent-volumes/`
+3. Please check PVC used by the pod, create new or choose an existing PVC may solve this problem.`
 
 	ContainerFailMount         = "%d. Container failed mount, message is: %s."
 	ContainerFailMountSolution = "%d. Please check your volumes of the Pod, try to change to correct and existing resources may fix this problem."
@@ -13,86 +13,133 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
-	com "github.com/fidelity/theliv/pkg/common"
 	"github.com/fidelity/theliv/pkg/kubeclient"
 	log "github.com/fidelity/theliv/pkg/log"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
-// Aggregate problems into report cards. Problems related to the same resource will be grouped together.
-func Aggregate(ctx context.Context, problems []*Problem, client *kubeclient.KubeClient) (interface{}, error) {
-	var wg = &sync.WaitGroup{}
-	var lock = &sync.Mutex{}
-
-	cards := make([]*ReportCard, 0)
-	for _, val := range buildReportCards(ctx, wg, lock, problems, client) {
-		val.RootCause = rootCause(val.Resources)
-		// set ID
-		val.ID = hashcode(val.TopResourceType + "/" + val.Name)
-		cards = append(cards, val)
+// Aggregate problems into report cards. Problems related to the same resource will be grouped together,
+// the grouping strategies are tried in order, see DefaultGrouping.
+func Aggregate(ctx context.Context, problems []*Problem, client *kubeclient.KubeClient, grouping []string) (interface{}, error) {
+	b := NewCardBuilder(ctx, problems, client, grouping, nil)
+	var wg sync.WaitGroup
+	for _, p := range problems {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Add(ctx, p)
+		}()
 	}
-	log.SWithContext(ctx).Infof("Generated %d report cards", len(cards))
+	wg.Wait()
+	return b.Cards(ctx), nil
+}
 
-	// Sort makes sure the cluster level report card is the first one,
-	// then sort by id
-	sort.Slice(cards, func(i, j int) bool {
-		if cards[i].Level == cards[j].Level {
-			return cards[i].ID < cards[j].ID
-		}
-		return cards[i].Level < cards[j].Level
-	})
-	return cards, nil
+// CardBuilder aggregates the problems into report cards as they are investigated. The group of each problem
+// is determined upfront, so a report card is complete once all the problems of its group are added.
+type CardBuilder struct {
+	mx       sync.Mutex
+	client   *kubeclient.KubeClient
+	problems []*Problem
+	groups   map[*Problem]*group
+	pending  map[string]int
+	cards    map[string]*ReportCard
+	onCard   func(card *ReportCard)
 }
 
-func buildReportCards(ctx context.Context, wg *sync.WaitGroup, lock *sync.Mutex, problems []*Problem, client *kubeclient.KubeClient) map[string]*ReportCard {
-	cards := make(map[string]*ReportCard)
+// NewCardBuilder determines the groups of the problems, onCard receives each report card when it is complete,
+// it may be called concurrently.
+func NewCardBuilder(ctx context.Context, problems []*Problem, client *kubeclient.KubeClient, grouping []string,
+	onCard func(card *ReportCard)) *CardBuilder {
+	b := &CardBuilder{
+		client:   client,
+		problems: problems,
+		groups:   make(map[*Problem]*group),
+		pending:  make(map[string]int),
+		cards:    make(map[string]*ReportCard),
+		onCard:   onCard,
+	}
+	var wg sync.WaitGroup
 	for _, p := range problems {
-
+		mo, ok := p.AffectedResources.Resource.(metav1.Object)
+		if !ok {
+			continue
+		}
 		wg.Add(1)
-		go buildCard(ctx, wg, lock, client, cards, p)
+		go func() {
+			defer wg.Done()
+			// determine the group of the root resource, e.g. an argo instance, flux instance, helm chart, or k8s object
+			g := getGroup(getTopResource(ctx, mo, client), grouping)
+			b.mx.Lock()
+			defer b.mx.Unlock()
+			b.groups[p] = g
+			b.pending[cardKey(g)]++
+		}()
 	}
-
 	wg.Wait()
-	return cards
+	return b
 }
 
-func buildCard(ctx context.Context, wg *sync.WaitGroup, lock *sync.Mutex, client *kubeclient.KubeClient, cards map[string]*ReportCard, p *Problem) {
-	defer wg.Done()
-	switch v := p.AffectedResources.Resource.(type) {
-	case metav1.Object:
-		// determine if root resource is an argo instance, helm chart, or k8s object
-		top, helm, argo := getTopResource(ctx, v, client)
-		cr := getReportCardResource(ctx, p, p.AffectedResources)
-		if argo != nil {
-			appendCards(lock, cards, cr, p, argo.Instance, com.Argo)
-		} else if helm != nil {
-			appendCards(lock, cards, cr, p, helm.toString(), com.Helm)
-		} else {
-			topType := ""
-			if obj, ok := top.(runtime.Object); ok {
-				topType = obj.GetObjectKind().GroupVersionKind().Kind
-			}
-			appendCards(lock, cards, cr, p, top.GetName(), topType)
+// Add the investigated problem to its report card, the card is passed to onCard if it is complete.
+func (b *CardBuilder) Add(ctx context.Context, p *Problem) {
+	b.mx.Lock()
+	g, ok := b.groups[p]
+	b.mx.Unlock()
+	if !ok {
+		return
+	}
+	cr := getReportCardResource(ctx, p, p.AffectedResources)
+	appendCards(&b.mx, b.cards, cr, p, g.name, g.topType, g.top)
+
+	key := cardKey(g)
+	b.mx.Lock()
+	b.pending[key]--
+	card, complete := b.cards[key], b.pending[key] == 0
+	b.mx.Unlock()
+	if complete {
+		card.ID = hashcode(key)
+		if b.onCard != nil {
+			b.onCard(card)
 		}
-	default:
-		// TODO log
 	}
 }
 
-func rootCause(res []*ReportCardResource) *ReportCardIssue {
-	var root *ReportCardIssue
-	rootlevel := 100
-	causelevelmap := make(map[int]*ReportCardResource)
-	for _, r := range res {
-		causelevelmap[r.Issue.CauseLevel] = r
-		if r.Issue.CauseLevel < rootlevel {
-			rootlevel = r.Issue.CauseLevel
-		}
+// Cards returns the report cards after all the problems are added, with root causes and scores set.
+// The most impactful report card is the first one.
+func (b *CardBuilder) Cards(ctx context.Context) []*ReportCard {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	cards := make([]*ReportCard, 0, len(b.cards))
+	for key, card := range b.cards {
+		card.ID = hashcode(key)
+		cards = append(cards, card)
 	}
-	root = causelevelmap[rootlevel].Issue
-	return root
+	log.SWithContext(ctx).Infof("Generated %d report cards", len(cards))
+
+	setRootCauses(cards, BuildDependencyGraph(ctx, b.client, b.problems))
+
+	scoreCards(ctx, cards, b.client, time.Now())
+
+	// Sort by score, the most impactful report card is the first one,
+	// then cluster level first, then sort by id
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Score != cards[j].Score {
+			return cards[i].Score > cards[j].Score
+		}
+		if cards[i].Level == cards[j].Level {
+			return cards[i].ID < cards[j].ID
+		}
+		return cards[i].Level < cards[j].Level
+	})
+	return cards
+}
+
+// groups of different strategies may have the same name
+func cardKey(g *group) string {
+	return g.topType + "/" + g.name
 }
 
 // getHelmChart returns the helm chart info if
@@ -108,32 +155,36 @@ func getHelmChart(meta metav1.Object) *helmChart {
 
 // getTopResource returns the top resource for the specified resource,
 // e.g. Deployment --> ReplicaSet --> Pod, so the top resource for Pod is Deployment
-// if any level of resource has Argo Instance info, then returns Argo Instance.
-// if any level of resource has helm chart info, then returns helm
-func getTopResource(ctx context.Context, mo metav1.Object, client *kubeclient.KubeClient) (metav1.Object, *helmChart, *ArgoInstance) {
-	oref := getControlOwner(mo)
+func getTopResource(ctx context.Context, mo metav1.Object, client *kubeclient.KubeClient) metav1.Object {
+	oref, ns := getControlOwner(mo), mo.GetNamespace()
+	if oref == nil {
+		oref, ns = getClaimRef(mo)
+	}
 	// if there is no parent resource
-	if oref != nil {
-		owner, err := client.GetOwner(ctx, *oref, mo.GetNamespace())
-		if err != nil {
-			fmt.Printf("Failed to get owner resource from owner reference, %v", err)
-			// return the resource itself if cannot get its owner
-			return mo, nil, nil
-		}
-		return getTopResource(ctx, owner, client)
-	} else {
-		argo := getArgoInstance(mo)
-		if argo.Instance != "" {
-			return nil, nil, argo
-		}
-		if argo.RolloutTemplate == "" {
-			chart := getHelmChart(mo)
-			if !chart.isEmpty() {
-				return nil, chart, nil
-			}
+	if oref == nil {
+		return mo
+	}
+	owner, err := client.GetOwner(ctx, *oref, ns)
+	if err != nil {
+		fmt.Printf("Failed to get owner resource from owner reference, %v", err)
+		// placeholders of deleted resources have no uid, they are grouped by the owner recorded in the alert
+		if mo.GetUID() == "" {
+			return ownerPlaceholder(oref, ns)
 		}
-		return mo, nil, nil
+		// return the resource itself if cannot get its owner
+		return mo
 	}
+	return getTopResource(ctx, owner, client)
+}
+
+// The owner with the name only, when it is deleted as well.
+func ownerPlaceholder(oref *metav1.OwnerReference, ns string) metav1.Object {
+	owner := &unstructured.Unstructured{}
+	owner.SetAPIVersion(oref.APIVersion)
+	owner.SetKind(oref.Kind)
+	owner.SetName(oref.Name)
+	owner.SetNamespace(ns)
+	return owner
 }
 
 // Assume only 1 owner which controls the resource
@@ -149,12 +200,30 @@ func getControlOwner(mo metav1.Object) *metav1.OwnerReference {
 	return nil
 }
 
+// Crossplane composite resources refer to their claim with spec.claimRef instead of an owner reference,
+// returns the claim and its namespace.
+func getClaimRef(mo metav1.Object) (*metav1.OwnerReference, string) {
+	u, ok := mo.(*unstructured.Unstructured)
+	if !ok {
+		return nil, ""
+	}
+	ref, found, err := unstructured.NestedStringMap(u.Object, "spec", "claimRef")
+	if err != nil || !found || ref["kind"] == "" || ref["name"] == "" {
+		return nil, ""
+	}
+	return &metav1.OwnerReference{APIVersion: ref["apiVersion"], Kind: ref["kind"], Name: ref["name"]}, ref["namespace"]
+}
+
 func getReportCardResource(ctx context.Context, p *Problem, resource ResourceDetails) *ReportCardResource {
 	cr := createReportCardResource(ctx, p, resource.Resource.(metav1.Object), resource.ResourceKind)
 	cr.Issue.Solutions = append(cr.Issue.Solutions, p.SolutionDetails.GetStore()...)
 	cr.Issue.Commands = append(cr.Issue.Commands, p.UsefulCommands.GetStore()...)
+	if p.Findings != nil {
+		if metrics := p.Findings.GetMetrics(); len(metrics) > 0 {
+			cr.Metrics = metrics
+		}
+	}
 
-	// cr.Issue.Documents = urlToStr(p.Docs)
 	// if resource.Deeplink != nil {
 	// 	links := make(map[string]string)
 	// 	for k, v := range resource.Deeplink {
@@ -166,25 +235,38 @@ func getReportCardResource(ctx context.Context, p *Problem, resource ResourceDet
 }
 
 func createReportCardResource(ctx context.Context, p *Problem, v metav1.Object, kind string) *ReportCardResource {
+	code := p.Code
+	if code == "" {
+		code = GetErrorCode(p.Name)
+	}
+	issueType := GetIssueType(code)
 	issue := ReportCardIssue{
-		Name:        p.Name,
-		Description: p.Description,
-		Tags:        p.Tags,
-		// DomainName:  p.DomainName,
-		CauseLevel:  p.CauseLevel,
-		CreatedTime: v.GetCreationTimestamp().String(),
+		Name:         p.Name,
+		Description:  p.Description,
+		Tags:         p.Tags,
+		DomainName:   issueType.Domain,
+		Severity:     issueType.Severity,
+		Code:         code,
+		Documents:    GetDocuments(code),
+		CreatedTime:  v.GetCreationTimestamp().String(),
+		AlertState:   p.AlertState,
+		SuppressedBy: p.SuppressedBy,
+		Recurrence:   p.Recurrence,
 	}
 	name := v.GetName()
 	if strings.Contains(p.Name, "Container") {
 		name = p.Tags["container"]
 	}
 	return &ReportCardResource{
-		Name:        name,
-		Type:        kind,
-		Labels:      v.GetLabels(),
-		Annotations: v.GetAnnotations(),
-		Metadata:    convertMetadata(ctx, v),
-		Issue:       &issue,
+		Name:           name,
+		Type:           kind,
+		Labels:         v.GetLabels(),
+		Annotations:    v.GetAnnotations(),
+		Metadata:       convertMetadata(ctx, v),
+		Issue:          &issue,
+		Count:          len(p.Replicas),
+		AffectedPods:   p.Replicas,
+		HistoricalNote: p.HistoricalNote,
 	}
 }
 
@@ -247,17 +329,22 @@ func cleanFieldNotRequired(data map[string]interface{}) map[string]interface{} {
 }
 
 // If card exists, append to card.Resources, or append new card into whole cards.
-func appendCards(lock *sync.Mutex, cards map[string]*ReportCard, cr *ReportCardResource, p *Problem, name string, topType string) {
+func appendCards(lock *sync.Mutex, cards map[string]*ReportCard, cr *ReportCardResource, p *Problem, name string, topType string,
+	top metav1.Object) {
 	lock.Lock()
 	defer lock.Unlock()
-	if rd, ok := cards[name]; ok {
+	key := cardKey(&group{name: name, topType: topType})
+	if rd, ok := cards[key]; ok {
 		rd.Resources = append(rd.Resources, cr)
+		rd.problems = append(rd.problems, p)
 	} else {
-		cards[name] = &ReportCard{
+		cards[key] = &ReportCard{
 			Name:            name,
 			Level:           p.Level,
 			Resources:       []*ReportCardResource{cr},
 			TopResourceType: topType,
+			problems:        []*Problem{p},
+			top:             top,
 		}
 	}
 }
@@ -269,3 +356,23 @@ func getArgoInstance(meta metav1.Object) *ArgoInstance {
 		RolloutTemplate: meta.GetLabels()["rollouts-pod-template-hash"],
 	}
 }
+
+// Returns the Flux instance which applied the resource, the HelmRelease before the Kustomization which applied
+// the HelmRelease. A HelmRelease or Kustomization is the Flux instance of itself.
+func getFluxInstance(meta metav1.Object) *FluxInstance {
+	if obj, ok := meta.(runtime.Object); ok {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if (gvk.Group == FluxHelmGroup && gvk.Kind == FluxHelmRelease) ||
+			(gvk.Group == FluxKustomizeGroup && gvk.Kind == FluxKustomization) {
+			return &FluxInstance{Kind: gvk.Kind, Name: meta.GetName(), Namespace: meta.GetNamespace()}
+		}
+	}
+	labels := meta.GetLabels()
+	if name := labels[FluxHelmGroup+"/name"]; name != "" {
+		return &FluxInstance{Kind: FluxHelmRelease, Name: name, Namespace: labels[FluxHelmGroup+"/namespace"]}
+	}
+	if name := labels[FluxKustomizeGroup+"/name"]; name != "" {
+		return &FluxInstance{Kind: FluxKustomization, Name: name, Namespace: labels[FluxKustomizeGroup+"/namespace"]}
+	}
+	return nil
+}
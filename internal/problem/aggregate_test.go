@@ -0,0 +1,105 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"context"
+	"testing"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGetClaimRef(t *testing.T) {
+	composite := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "database.example.org/v1alpha1",
+		"kind":       "XPostgreSQLInstance",
+		"metadata":   map[string]interface{}{"name": "orders-db-x7k2p"},
+		"spec": map[string]interface{}{"claimRef": map[string]interface{}{
+			"apiVersion": "database.example.org/v1alpha1",
+			"kind":       "PostgreSQLInstance",
+			"name":       "orders-db",
+			"namespace":  "orders",
+		}},
+	}}
+	ref, ns := getClaimRef(composite)
+	assert.Equal(t, "PostgreSQLInstance", ref.Kind)
+	assert.Equal(t, "orders-db", ref.Name)
+	assert.Equal(t, "orders", ns)
+
+	ref, _ = getClaimRef(&corev1.Pod{})
+	assert.Nil(t, ref)
+	ref, _ = getClaimRef(&unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}})
+	assert.Nil(t, ref)
+}
+
+func TestGetFluxInstance(t *testing.T) {
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Labels: map[string]string{
+		"helm.toolkit.fluxcd.io/name":           "podinfo",
+		"helm.toolkit.fluxcd.io/namespace":      "apps",
+		"app.kubernetes.io/managed-by":          "Helm",
+		"kustomize.toolkit.fluxcd.io/name":      "ignored",
+		"kustomize.toolkit.fluxcd.io/namespace": "flux-system",
+	}}}
+	assert.Equal(t, &FluxInstance{Kind: FluxHelmRelease, Name: "podinfo", Namespace: "apps"}, getFluxInstance(deploy))
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{
+		"kustomize.toolkit.fluxcd.io/name":      "apps",
+		"kustomize.toolkit.fluxcd.io/namespace": "flux-system",
+	}}}
+	assert.Equal(t, &FluxInstance{Kind: FluxKustomization, Name: "apps", Namespace: "flux-system"}, getFluxInstance(svc))
+
+	// the HelmRelease applied by a Kustomization is the instance of itself
+	release := &unstructured.Unstructured{}
+	release.SetAPIVersion("helm.toolkit.fluxcd.io/v2")
+	release.SetKind(FluxHelmRelease)
+	release.SetName("podinfo")
+	release.SetNamespace("apps")
+	release.SetLabels(map[string]string{"kustomize.toolkit.fluxcd.io/name": "apps"})
+	assert.Equal(t, &FluxInstance{Kind: FluxHelmRelease, Name: "podinfo", Namespace: "apps"}, getFluxInstance(release))
+
+	assert.Nil(t, getFluxInstance(&corev1.Pod{}))
+}
+
+func TestCardBuilder(t *testing.T) {
+	newProblem := func(name string, pod *corev1.Pod) *Problem {
+		return &Problem{
+			Name:              name,
+			Tags:              map[string]string{com.Resourcetype: com.Pod},
+			AffectedResources: ResourceDetails{Resource: pod},
+			SolutionDetails:   com.InitLockedSlice(),
+			UsefulCommands:    com.InitLockedSlice(),
+		}
+	}
+	web := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"}}
+	db := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"}}
+	notReady, pending, dbNotReady := newProblem("PodNotReady", web), newProblem("PodPending", web), newProblem("PodNotReady", db)
+
+	complete := make([]string, 0)
+	ctx := context.Background()
+	b := NewCardBuilder(ctx, []*Problem{notReady, pending, dbNotReady}, nil, nil, func(card *ReportCard) {
+		complete = append(complete, card.Name)
+	})
+
+	// the web card is complete when both its problems are added
+	b.Add(ctx, notReady)
+	assert.Empty(t, complete)
+	b.Add(ctx, dbNotReady)
+	assert.Equal(t, []string{"db"}, complete)
+	b.Add(ctx, pending)
+	assert.Equal(t, []string{"db", "web"}, complete)
+
+	cards := b.Cards(ctx)
+	assert.Len(t, cards, 2)
+	for _, card := range cards {
+		assert.NotEmpty(t, card.ID)
+		assert.NotNil(t, card.RootCause)
+	}
+}
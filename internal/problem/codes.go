@@ -0,0 +1,159 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"sort"
+
+	com "github.com/fidelity/theliv/pkg/common"
+)
+
+// ErrorCode is the stable identifier of a problem type, it doesn't change when an alert is renamed.
+type ErrorCode string
+
+const (
+	ImagePullErr           ErrorCode = "IMAGEPULL_ERR"
+	CrashLoopErr           ErrorCode = "CRASHLOOP_ERR"
+	CreateContainerErr     ErrorCode = "CREATECONTAINER_ERR"
+	OOMKilledErr           ErrorCode = "OOMKILLED_ERR"
+	ContainerTerminatedErr ErrorCode = "CONTAINER_TERMINATED_ERR"
+	DeadlineExceededErr    ErrorCode = "DEADLINE_EXCEEDED_ERR"
+	EvictedErr             ErrorCode = "EVICTED_ERR"
+	PendingPodsErr         ErrorCode = "PENDING_PODS_ERR"
+	PodNotReadyErr         ErrorCode = "POD_NOTREADY_ERR"
+	DeploymentNotAvailErr  ErrorCode = "DEPLOYMENT_NOTAVAILABLE_ERR"
+	DeploymentRolloutErr   ErrorCode = "DEPLOYMENT_ROLLOUT_ERR"
+	DeploymentReplicasErr  ErrorCode = "DEPLOYMENT_REPLICAS_ERR"
+	StatefulsetRolloutErr  ErrorCode = "STATEFULSET_ROLLOUT_ERR"
+	StatefulsetReplicasErr ErrorCode = "STATEFULSET_REPLICAS_ERR"
+	NodeNotReadyErr        ErrorCode = "NODE_NOTREADY_ERR"
+	NodeDiskPressureErr    ErrorCode = "NODE_DISKPRESSURE_ERR"
+	NodeMemoryPressureErr  ErrorCode = "NODE_MEMORYPRESSURE_ERR"
+	NodePIDPressureErr     ErrorCode = "NODE_PIDPRESSURE_ERR"
+	NodeNetworkErr         ErrorCode = "NODE_NETWORK_ERR"
+	EndpointNotAvailErr    ErrorCode = "ENDPOINT_NOTAVAILABLE_ERR"
+	IngressConfigErr       ErrorCode = "INGRESS_CONFIG_ERR"
+	CustomResourceErr      ErrorCode = "CUSTOMRESOURCE_NOTREADY_ERR"
+	FluxReleaseErr         ErrorCode = "FLUX_RELEASE_ERR"
+	FluxKustomizationErr   ErrorCode = "FLUX_KUSTOMIZATION_ERR"
+	FluxSourceErr          ErrorCode = "FLUX_SOURCE_ERR"
+	UnknownErr             ErrorCode = "UNKNOWN_ERR"
+)
+
+// Maps the alert names to error codes, modify this map when adding new alert.
+var alertErrorCodes = map[string]ErrorCode{
+	"ContainerWaitingAsImagePullBackOff":     ImagePullErr,
+	"InitContainerWaitingAsImagePullBackOff": ImagePullErr,
+	"ContainerWaitingAsCrashLoopBackoff":     CrashLoopErr,
+	"InitContainerWaitingAsCrashLoopBackoff": CrashLoopErr,
+	"ContainerWaitingAsCreateContainerError": CreateContainerErr,
+
+	"ContainerTerminatedAsOOMKilled":              OOMKilledErr,
+	"InitContainerTerminatedAsOOMKilled":          OOMKilledErr,
+	"ContainerTerminatedAsError":                  ContainerTerminatedErr,
+	"InitContainerTerminatedAsError":              ContainerTerminatedErr,
+	"ContainerTerminatedAsContainerCannotRun":     ContainerTerminatedErr,
+	"InitContainerTerminatedAsContainerCannotRun": ContainerTerminatedErr,
+	"ContainerTerminatedAsDeadlineExceeded":       DeadlineExceededErr,
+	"InitContainerTerminatedAsDeadlineExceeded":   DeadlineExceededErr,
+	"ContainerTerminatedAsEvicted":                EvictedErr,
+	"InitContainerTerminatedAsEvicted":            EvictedErr,
+
+	"PodNotRunning": PendingPodsErr,
+	"PodNotReady":   PodNotReadyErr,
+
+	"DeploymentNotAvailable":        DeploymentNotAvailErr,
+	"DeploymentGenerationMismatch":  DeploymentRolloutErr,
+	"DeploymentReplicasMismatch":    DeploymentReplicasErr,
+	"StatefulsetGenerationMismatch": StatefulsetRolloutErr,
+	"StatefulsetUpdateNotRolledOut": StatefulsetRolloutErr,
+	"StatefulsetReplicasMismatch":   StatefulsetReplicasErr,
+
+	"NodeNotReady":           NodeNotReadyErr,
+	"NodeDiskPressure":       NodeDiskPressureErr,
+	"NodeMemoryPressure":     NodeMemoryPressureErr,
+	"NodePIDPressure":        NodePIDPressureErr,
+	"NodeNetworkUnavailable": NodeNetworkErr,
+
+	"EndpointAddressNotAvailable": EndpointNotAvailErr,
+
+	com.IngressMisconfigured: IngressConfigErr,
+
+	com.FluxHelmReleaseNotReady:   FluxReleaseErr,
+	com.FluxKustomizationNotReady: FluxKustomizationErr,
+	com.FluxSourceNotReady:        FluxSourceErr,
+}
+
+// IssueType is the taxonomy entry of an error code.
+type IssueType struct {
+	Code     ErrorCode  `json:"code"`
+	Domain   DomainName `json:"domain"`
+	Severity Severity   `json:"severity"`
+}
+
+// Taxonomy of all the problem types Theliv can emit, modify this map when adding new error code.
+var issueTypes = map[ErrorCode]IssueType{
+	ImagePullErr:           {ImagePullErr, DomainWorkload, SeverityHigh},
+	CrashLoopErr:           {CrashLoopErr, DomainWorkload, SeverityHigh},
+	CreateContainerErr:     {CreateContainerErr, DomainConfig, SeverityHigh},
+	OOMKilledErr:           {OOMKilledErr, DomainWorkload, SeverityHigh},
+	ContainerTerminatedErr: {ContainerTerminatedErr, DomainWorkload, SeverityMedium},
+	DeadlineExceededErr:    {DeadlineExceededErr, DomainWorkload, SeverityMedium},
+	EvictedErr:             {EvictedErr, DomainNode, SeverityMedium},
+	PendingPodsErr:         {PendingPodsErr, DomainWorkload, SeverityHigh},
+	PodNotReadyErr:         {PodNotReadyErr, DomainWorkload, SeverityMedium},
+	DeploymentNotAvailErr:  {DeploymentNotAvailErr, DomainWorkload, SeverityCritical},
+	DeploymentRolloutErr:   {DeploymentRolloutErr, DomainWorkload, SeverityMedium},
+	DeploymentReplicasErr:  {DeploymentReplicasErr, DomainWorkload, SeverityMedium},
+	StatefulsetRolloutErr:  {StatefulsetRolloutErr, DomainWorkload, SeverityMedium},
+	StatefulsetReplicasErr: {StatefulsetReplicasErr, DomainWorkload, SeverityMedium},
+	NodeNotReadyErr:        {NodeNotReadyErr, DomainNode, SeverityCritical},
+	NodeDiskPressureErr:    {NodeDiskPressureErr, DomainNode, SeverityHigh},
+	NodeMemoryPressureErr:  {NodeMemoryPressureErr, DomainNode, SeverityHigh},
+	NodePIDPressureErr:     {NodePIDPressureErr, DomainNode, SeverityHigh},
+	NodeNetworkErr:         {NodeNetworkErr, DomainNetwork, SeverityCritical},
+	EndpointNotAvailErr:    {EndpointNotAvailErr, DomainNetwork, SeverityHigh},
+	IngressConfigErr:       {IngressConfigErr, DomainNetwork, SeverityHigh},
+	CustomResourceErr:      {CustomResourceErr, DomainWorkload, SeverityMedium},
+	FluxReleaseErr:         {FluxReleaseErr, DomainConfig, SeverityHigh},
+	FluxKustomizationErr:   {FluxKustomizationErr, DomainConfig, SeverityHigh},
+	FluxSourceErr:          {FluxSourceErr, DomainConfig, SeverityHigh},
+	UnknownErr:             {UnknownErr, DomainWorkload, SeverityLow},
+}
+
+// RegisterIssueType adds the issue type of a custom rule to the taxonomy.
+// It is not safe for concurrent use, only called at startup before any detection.
+func RegisterIssueType(t IssueType) {
+	issueTypes[t.Code] = t
+}
+
+// GetErrorCode returns the error code of the problem name, UNKNOWN_ERR if not defined.
+func GetErrorCode(name string) ErrorCode {
+	if code, ok := alertErrorCodes[name]; ok {
+		return code
+	}
+	return UnknownErr
+}
+
+// GetIssueType returns the taxonomy entry of the error code, UNKNOWN_ERR entry if not defined.
+func GetIssueType(code ErrorCode) IssueType {
+	if t, ok := issueTypes[code]; ok {
+		return t
+	}
+	return issueTypes[UnknownErr]
+}
+
+// ListIssueTypes returns all the issue types sorted by error code.
+func ListIssueTypes() []IssueType {
+	types := make([]IssueType, 0, len(issueTypes))
+	for _, t := range issueTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Code < types[j].Code
+	})
+	return types
+}
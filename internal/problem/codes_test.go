@@ -0,0 +1,31 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetErrorCode(t *testing.T) {
+	assert.Equal(t, ImagePullErr, GetErrorCode("InitContainerWaitingAsImagePullBackOff"))
+	assert.Equal(t, UnknownErr, GetErrorCode("SomeCustomAlert"))
+}
+
+// Every error code an alert maps to must be in the taxonomy, and have default documents.
+func TestTaxonomyComplete(t *testing.T) {
+	for alert, code := range alertErrorCodes {
+		issueType, ok := issueTypes[code]
+		assert.True(t, ok, "%s of alert %s is not in taxonomy", code, alert)
+		assert.Equal(t, code, issueType.Code)
+		assert.NotEmpty(t, issueType.Domain, code)
+		assert.NotEmpty(t, issueType.Severity, code)
+		assert.NotEmpty(t, defaultDocuments[code], code)
+	}
+	assert.Equal(t, UnknownErr, GetIssueType("NOT_DEFINED").Code)
+	assert.Len(t, ListIssueTypes(), len(issueTypes))
+}
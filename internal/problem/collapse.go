@@ -0,0 +1,104 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CollapseReplicas merges the problems with the same signature under the same controller,
+// e.g. the crashlooping pods of a Deployment. Only the representative problem is returned for investigation,
+// all the affected pods are kept in its Replicas.
+func CollapseReplicas(problems []*Problem) []*Problem {
+	groups := make(map[string][]*Problem)
+	// placeholder of each group in results, keeps the original order
+	positions := make(map[string]int)
+	results := make([]*Problem, 0, len(problems))
+	for _, p := range problems {
+		sig := replicaSignature(p)
+		if sig == "" {
+			results = append(results, p)
+			continue
+		}
+		if _, ok := groups[sig]; !ok {
+			positions[sig] = len(results)
+			results = append(results, nil)
+		}
+		groups[sig] = append(groups[sig], p)
+	}
+	for sig, group := range groups {
+		results[positions[sig]] = mergeReplicas(group)
+	}
+	return results
+}
+
+// The representative is the pod with the smallest name, so the result is stable between detections.
+func mergeReplicas(group []*Problem) *Problem {
+	if len(group) == 1 {
+		return group[0]
+	}
+	sort.Slice(group, func(i, j int) bool { return podName(group[i]) < podName(group[j]) })
+	rep := group[0]
+	rep.Replicas = make([]string, 0, len(group))
+	for _, p := range group {
+		rep.Replicas = append(rep.Replicas, podName(p))
+		if !p.ActiveAt.IsZero() && (rep.ActiveAt.IsZero() || p.ActiveAt.Before(rep.ActiveAt)) {
+			rep.ActiveAt = p.ActiveAt
+		}
+	}
+	return rep
+}
+
+// Signature is alert, controller, container and reason, empty if the problem is not on a controlled pod.
+func replicaSignature(p *Problem) string {
+	pod, ok := p.AffectedResources.Resource.(*corev1.Pod)
+	if !ok {
+		return ""
+	}
+	owner := getControlOwner(pod)
+	if owner == nil {
+		return ""
+	}
+	container := p.Tags[com.Container]
+	return strings.Join([]string{p.Name, pod.Namespace, owner.Kind, owner.Name, container, podReason(pod, container)}, "/")
+}
+
+// Reason of the container, waiting reason and last exit code, or the pod reason if no container.
+func podReason(pod *corev1.Pod, container string) string {
+	if container == "" {
+		return pod.Status.Reason
+	}
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, s := range statuses {
+			if s.Name != container {
+				continue
+			}
+			reason := ""
+			if s.State.Waiting != nil {
+				reason = s.State.Waiting.Reason
+			} else if s.State.Terminated != nil {
+				reason = s.State.Terminated.Reason
+			}
+			if s.LastTerminationState.Terminated != nil {
+				reason = fmt.Sprintf("%s:%d", reason, s.LastTerminationState.Terminated.ExitCode)
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+func podName(p *Problem) string {
+	if pod, ok := p.AffectedResources.Resource.(*corev1.Pod); ok {
+		return pod.Name
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"fmt"
+	"testing"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newReplicaProblem(name string, owner string, exitCode int32) *Problem {
+	controller := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "team-a",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: owner, Controller: &controller},
+			},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:                 "app",
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+		}}},
+	}
+	return &Problem{
+		Name:              "ContainerWaitingAsCrashLoopBackoff",
+		Tags:              map[string]string{com.Resourcetype: com.Container, com.Container: "app"},
+		AffectedResources: ResourceDetails{Resource: pod},
+	}
+}
+
+func TestCollapseReplicas(t *testing.T) {
+	problems := make([]*Problem, 0)
+	for i := 50; i > 0; i-- {
+		problems = append(problems, newReplicaProblem(fmt.Sprintf("web-%02d", i), "web-7d9f", 1))
+	}
+	// different exit code and different controller are not merged
+	problems = append(problems, newReplicaProblem("web-99", "web-7d9f", 137))
+	problems = append(problems, newReplicaProblem("api-01", "api-5c6b", 1))
+	node := &Problem{Name: "NodeNotReady", AffectedResources: ResourceDetails{Resource: &corev1.Node{}}}
+	problems = append(problems, node)
+
+	results := CollapseReplicas(problems)
+	assert.Len(t, results, 4)
+	assert.Equal(t, "web-01", podName(results[0]))
+	assert.Len(t, results[0].Replicas, 50)
+	assert.Equal(t, "web-01", results[0].Replicas[0])
+	assert.Equal(t, "web-99", podName(results[1]))
+	assert.Empty(t, results[1].Replicas)
+	assert.Equal(t, "api-01", podName(results[2]))
+	assert.Same(t, node, results[3])
+}
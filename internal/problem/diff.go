@@ -0,0 +1,122 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"sort"
+	"strings"
+
+	com "github.com/fidelity/theliv/pkg/common"
+)
+
+type DiffStatus string
+
+const (
+	DiffNew        DiffStatus = "new"
+	DiffResolved   DiffStatus = "resolved"
+	DiffPersisting DiffStatus = "persisting"
+)
+
+type CardDiff struct {
+	Fingerprint     string       `json:"fingerprint"`
+	Name            string       `json:"name"`
+	TopResourceType string       `json:"topResourceType"`
+	Status          DiffStatus   `json:"status"`
+	Issues          []*IssueDiff `json:"issues"`
+}
+
+type IssueDiff struct {
+	Fingerprint string           `json:"fingerprint"`
+	Status      DiffStatus       `json:"status"`
+	Resource    string           `json:"resource"`
+	Issue       *ReportCardIssue `json:"issue"`
+}
+
+// CardFingerprint is stable between detections, it is the top resource of the card.
+// Unlike ID, it does not change when the card is rebuilt.
+func CardFingerprint(card *ReportCard) string {
+	return card.TopResourceType + "/" + card.Name
+}
+
+// IssueFingerprint is the card fingerprint, issue code and container, pods recreated by
+// the controller have new names, so the pod name is not part of the fingerprint.
+func IssueFingerprint(card *ReportCard, res *ReportCardResource) string {
+	code := string(res.Issue.Code)
+	if code == "" {
+		code = res.Issue.Name
+	}
+	return strings.Join([]string{CardFingerprint(card), code, res.Issue.Tags[com.Container]}, "/")
+}
+
+// DiffCards compares the report cards of two detections, base is the earlier one.
+// Cards and issues are matched by fingerprint, classified as new, resolved or persisting.
+func DiffCards(base []*ReportCard, target []*ReportCard) []*CardDiff {
+	baseCards := indexCards(base)
+	targetCards := indexCards(target)
+
+	diffs := make([]*CardDiff, 0)
+	for fp, card := range targetCards {
+		diff := &CardDiff{Fingerprint: fp, Name: card.Name, TopResourceType: card.TopResourceType, Status: DiffNew}
+		var baseIssues map[string]*ReportCardResource
+		if b, ok := baseCards[fp]; ok {
+			diff.Status = DiffPersisting
+			baseIssues = indexIssues(b)
+		}
+		targetIssues := indexIssues(card)
+		for ifp, res := range targetIssues {
+			status := DiffNew
+			if _, ok := baseIssues[ifp]; ok {
+				status = DiffPersisting
+			}
+			diff.Issues = append(diff.Issues, &IssueDiff{Fingerprint: ifp, Status: status, Resource: res.Name, Issue: res.Issue})
+		}
+		for ifp, res := range baseIssues {
+			if _, ok := targetIssues[ifp]; !ok {
+				diff.Issues = append(diff.Issues, &IssueDiff{Fingerprint: ifp, Status: DiffResolved, Resource: res.Name, Issue: res.Issue})
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	for fp, card := range baseCards {
+		if _, ok := targetCards[fp]; ok {
+			continue
+		}
+		diff := &CardDiff{Fingerprint: fp, Name: card.Name, TopResourceType: card.TopResourceType, Status: DiffResolved}
+		for ifp, res := range indexIssues(card) {
+			diff.Issues = append(diff.Issues, &IssueDiff{Fingerprint: ifp, Status: DiffResolved, Resource: res.Name, Issue: res.Issue})
+		}
+		diffs = append(diffs, diff)
+	}
+
+	for _, d := range diffs {
+		sort.Slice(d.Issues, func(i, j int) bool { return d.Issues[i].Fingerprint < d.Issues[j].Fingerprint })
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Fingerprint < diffs[j].Fingerprint })
+	return diffs
+}
+
+func indexCards(cards []*ReportCard) map[string]*ReportCard {
+	result := make(map[string]*ReportCard)
+	for _, c := range cards {
+		result[CardFingerprint(c)] = c
+	}
+	return result
+}
+
+// Issues with the same fingerprint, e.g. the same issue on several pods, are counted once.
+func indexIssues(card *ReportCard) map[string]*ReportCardResource {
+	result := make(map[string]*ReportCardResource)
+	for _, r := range card.Resources {
+		if r.Issue == nil {
+			continue
+		}
+		fp := IssueFingerprint(card, r)
+		if _, ok := result[fp]; !ok {
+			result[fp] = r
+		}
+	}
+	return result
+}
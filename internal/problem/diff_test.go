@@ -0,0 +1,59 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"testing"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDiffCard(id string, name string, resources ...*ReportCardResource) *ReportCard {
+	return &ReportCard{ID: id, Name: name, TopResourceType: "Deployment", Resources: resources}
+}
+
+func newDiffResource(pod string, code ErrorCode, container string) *ReportCardResource {
+	return &ReportCardResource{Name: pod, Issue: &ReportCardIssue{Code: code, Tags: map[string]string{com.Container: container}}}
+}
+
+func TestDiffCards(t *testing.T) {
+	base := []*ReportCard{
+		newDiffCard("1", "web",
+			newDiffResource("web-1", CrashLoopErr, "app"),
+			newDiffResource("web-1", ImagePullErr, "sidecar")),
+		newDiffCard("2", "api", newDiffResource("api-1", PodNotReadyErr, "")),
+	}
+	// pods are recreated with new names and the card ID changed, fingerprints are the same
+	target := []*ReportCard{
+		newDiffCard("3", "web",
+			newDiffResource("web-2", CrashLoopErr, "app"),
+			newDiffResource("web-2", OOMKilledErr, "app")),
+		newDiffCard("4", "worker", newDiffResource("worker-1", PendingPodsErr, "")),
+	}
+
+	diffs := DiffCards(base, target)
+	assert.Len(t, diffs, 3)
+
+	assert.Equal(t, "Deployment/api", diffs[0].Fingerprint)
+	assert.Equal(t, DiffResolved, diffs[0].Status)
+	assert.Equal(t, DiffResolved, diffs[0].Issues[0].Status)
+
+	assert.Equal(t, "Deployment/web", diffs[1].Fingerprint)
+	assert.Equal(t, DiffPersisting, diffs[1].Status)
+	statuses := map[string]DiffStatus{}
+	for _, i := range diffs[1].Issues {
+		statuses[i.Fingerprint] = i.Status
+	}
+	assert.Equal(t, map[string]DiffStatus{
+		"Deployment/web/CRASHLOOP_ERR/app":     DiffPersisting,
+		"Deployment/web/OOMKILLED_ERR/app":     DiffNew,
+		"Deployment/web/IMAGEPULL_ERR/sidecar": DiffResolved,
+	}, statuses)
+
+	assert.Equal(t, "Deployment/worker", diffs[2].Fingerprint)
+	assert.Equal(t, DiffNew, diffs[2].Status)
+}
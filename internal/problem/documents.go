@@ -0,0 +1,131 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"github.com/fidelity/theliv/pkg/config"
+)
+
+const (
+	DocSourceKubernetes         = "kubernetes"
+	DocSourceOrganization       = "organization"
+	DocSourceContainerSolutions = "containersolutions"
+	DocSourceTencentCloud       = "tencentcloud"
+	DocSourceFlux               = "flux"
+)
+
+type Document struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Source string `json:"source,omitempty"`
+}
+
+var (
+	debugPodsDoc     = k8sDoc("Debug Pods", "https://kubernetes.io/docs/tasks/debug/debug-application/debug-pods/")
+	podFailureDoc    = k8sDoc("Determine the Reason for Pod Failure", "https://kubernetes.io/docs/tasks/debug/debug-application/determine-reason-pod-failure/")
+	deployStatusDoc  = k8sDoc("Deployment Status", "https://kubernetes.io/docs/concepts/workloads/controllers/deployment/#deployment-status")
+	statefulsetDoc   = k8sDoc("StatefulSets", "https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/")
+	nodeConditionDoc = k8sDoc("Node Conditions", "https://kubernetes.io/docs/concepts/architecture/nodes/#condition")
+	nodePressureDoc  = k8sDoc("Node-pressure Eviction", "https://kubernetes.io/docs/concepts/scheduling-eviction/node-pressure-eviction/")
+	fluxTroubleDoc   = doc("Flux troubleshooting cheatsheet", "https://fluxcd.io/flux/cheatsheets/troubleshooting/", DocSourceFlux)
+)
+
+// Default upstream documents of each error code.
+var defaultDocuments = map[ErrorCode][]Document{
+	ImagePullErr: {
+		k8sDoc("Images", "https://kubernetes.io/docs/concepts/containers/images/"),
+		k8sDoc("Pull an Image from a Private Registry", "https://kubernetes.io/docs/tasks/configure-pod-container/pull-image-private-registry/"),
+	},
+	CrashLoopErr: {
+		debugPodsDoc,
+		doc("CrashLoopBackOff runbook", "https://containersolutions.github.io/runbooks/posts/kubernetes/crashloopbackoff",
+			DocSourceContainerSolutions),
+		doc("Container exit codes", "https://intl.cloud.tencent.com/document/product/457/35758", DocSourceTencentCloud),
+	},
+	CreateContainerErr: {
+		k8sDoc("ConfigMaps", "https://kubernetes.io/docs/concepts/configuration/configmap/"),
+		k8sDoc("Secrets", "https://kubernetes.io/docs/concepts/configuration/secret/"),
+	},
+	OOMKilledErr: {
+		k8sDoc("Assign Memory Resources to Containers and Pods", "https://kubernetes.io/docs/tasks/configure-pod-container/assign-memory-resource/"),
+	},
+	ContainerTerminatedErr: {podFailureDoc, debugPodsDoc},
+	DeadlineExceededErr: {
+		k8sDoc("Job termination and cleanup", "https://kubernetes.io/docs/concepts/workloads/controllers/job/#job-termination-and-cleanup"),
+	},
+	EvictedErr: {nodePressureDoc},
+	PendingPodsErr: {
+		k8sDoc("Troubleshooting Clusters", "https://kubernetes.io/docs/tasks/debug-application-cluster/debug-cluster/"),
+		k8sDoc("Node affinity", "https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#node-affinity"),
+		k8sDoc("Taints and Tolerations", "https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/"),
+		k8sDoc("Persistent Volumes", "https://kubernetes.io/docs/concepts/storage/persistent-volumes/"),
+	},
+	PodNotReadyErr: {
+		k8sDoc("Configure Liveness, Readiness and Startup Probes", "https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-startup-probes/"),
+	},
+	DeploymentNotAvailErr: {
+		deployStatusDoc,
+		k8sDoc("Resource Quotas", "https://kubernetes.io/docs/concepts/policy/resource-quotas/"),
+	},
+	DeploymentRolloutErr:   {deployStatusDoc},
+	DeploymentReplicasErr:  {deployStatusDoc},
+	StatefulsetRolloutErr:  {statefulsetDoc},
+	StatefulsetReplicasErr: {statefulsetDoc},
+	NodeNotReadyErr:        {nodeConditionDoc},
+	NodeDiskPressureErr:    {nodeConditionDoc, nodePressureDoc},
+	NodeMemoryPressureErr:  {nodeConditionDoc, nodePressureDoc},
+	NodePIDPressureErr:     {nodeConditionDoc, nodePressureDoc},
+	NodeNetworkErr:         {nodeConditionDoc},
+	EndpointNotAvailErr: {
+		k8sDoc("Debug Services", "https://kubernetes.io/docs/tasks/debug/debug-application/debug-service/"),
+	},
+	IngressConfigErr: {
+		k8sDoc("Ingress", "https://kubernetes.io/docs/concepts/services-networking/ingress/"),
+		k8sDoc("AWS Load Balancer Controller Ingress annotations", "https://kubernetes-sigs.github.io/aws-load-balancer-controller/latest/guide/ingress/annotations/"),
+	},
+	CustomResourceErr: {
+		k8sDoc("Custom Resources", "https://kubernetes.io/docs/concepts/extend-kubernetes/api-extension/custom-resources/"),
+	},
+	FluxReleaseErr: {
+		doc("Flux HelmReleases", "https://fluxcd.io/flux/components/helm/helmreleases/", DocSourceFlux),
+		fluxTroubleDoc,
+	},
+	FluxKustomizationErr: {
+		doc("Flux Kustomizations", "https://fluxcd.io/flux/components/kustomize/kustomizations/", DocSourceFlux),
+		fluxTroubleDoc,
+	},
+	FluxSourceErr: {
+		doc("Flux GitRepositories", "https://fluxcd.io/flux/components/source/gitrepositories/", DocSourceFlux),
+		doc("Flux HelmRepositories", "https://fluxcd.io/flux/components/source/helmrepositories/", DocSourceFlux),
+		fluxTroubleDoc,
+	},
+}
+
+// GetDocuments returns the documents of the error code, the default upstream documents first,
+// then the organization documents configured in ThelivConfig.Documents.
+// If ReplaceDefaults is configured, only the organization documents are returned when present.
+func GetDocuments(code ErrorCode) []Document {
+	docs := make([]Document, 0)
+	var orgDocs []Document
+	if cfg := config.GetThelivConfig(); cfg != nil && cfg.Documents != nil {
+		for _, link := range cfg.Documents.Links[string(code)] {
+			orgDocs = append(orgDocs, Document{Title: link.Title, URL: link.URL, Source: DocSourceOrganization})
+		}
+		if cfg.Documents.ReplaceDefaults && len(orgDocs) > 0 {
+			return orgDocs
+		}
+	}
+	docs = append(docs, defaultDocuments[code]...)
+	return append(docs, orgDocs...)
+}
+
+func k8sDoc(title string, url string) Document {
+	return doc(title, url, DocSourceKubernetes)
+}
+
+func doc(title string, url string, source string) Document {
+	return Document{Title: title, URL: url, Source: source}
+}
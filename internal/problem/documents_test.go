@@ -0,0 +1,60 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const documentsConfig = `
+port: 8080
+clusterDir: %s
+documents:
+  replaceDefaults: %v
+  links:
+    IMAGEPULL_ERR:
+    - title: Registry onboarding
+      url: https://wiki.example.com/registry
+`
+
+func loadDocumentsConfig(t *testing.T, replace bool) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "theliv.yaml")
+	content := []byte(fmt.Sprintf(documentsConfig, dir, replace))
+	assert.NoError(t, os.WriteFile(file, content, 0600))
+	config.NewFileConfigLoader(file).LoadConfigs()
+}
+
+func TestGetDocuments(t *testing.T) {
+	loadDocumentsConfig(t, false)
+
+	docs := GetDocuments(ImagePullErr)
+	assert.Len(t, docs, 3)
+	assert.Equal(t, DocSourceKubernetes, docs[0].Source)
+	assert.Equal(t, "https://wiki.example.com/registry", docs[2].URL)
+	assert.Equal(t, DocSourceOrganization, docs[2].Source)
+
+	// codes without organization documents keep the defaults
+	assert.Equal(t, defaultDocuments[CrashLoopErr], GetDocuments(CrashLoopErr))
+	assert.Equal(t, DocSourceContainerSolutions, GetDocuments(CrashLoopErr)[1].Source)
+	assert.Equal(t, DocSourceFlux, GetDocuments(FluxReleaseErr)[0].Source)
+	assert.Empty(t, GetDocuments(UnknownErr))
+}
+
+func TestGetDocumentsReplaceDefaults(t *testing.T) {
+	loadDocumentsConfig(t, true)
+
+	docs := GetDocuments(ImagePullErr)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, DocSourceOrganization, docs[0].Source)
+	assert.Equal(t, defaultDocuments[CrashLoopErr], GetDocuments(CrashLoopErr))
+}
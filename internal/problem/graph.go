@@ -0,0 +1,523 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Scheduler reports a resource no node has enough of as e.g. "Insufficient cpu".
+const insufficientResource = "Insufficient"
+
+// Node problems which reduce the cluster-wide capacity.
+var nodeCapacityCodes = map[ErrorCode]bool{
+	NodeMemoryPressureErr: true,
+	NodeDiskPressureErr:   true,
+	NodePIDPressureErr:    true,
+}
+
+// ResourceKey identifies a resource in the dependency graph, in format Kind/Namespace/Name,
+// a container is identified by Pod/Namespace/Name/Container.
+type ResourceKey string
+
+// DependencyGraph is built per detection, an edge points from a resource to the resource it depends on,
+// e.g. Ingress -> Service -> EndpointSlice -> Pod -> Node.
+type DependencyGraph struct {
+	edges map[ResourceKey]map[ResourceKey]bool
+}
+
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{edges: make(map[ResourceKey]map[ResourceKey]bool)}
+}
+
+func (g *DependencyGraph) AddEdge(from ResourceKey, to ResourceKey) {
+	if from == "" || to == "" || from == to {
+		return
+	}
+	if _, ok := g.edges[from]; !ok {
+		g.edges[from] = make(map[ResourceKey]bool)
+	}
+	g.edges[from][to] = true
+}
+
+// Dependencies returns the direct dependencies of the resource, sorted.
+func (g *DependencyGraph) Dependencies(key ResourceKey) []ResourceKey {
+	deps := make([]ResourceKey, 0, len(g.edges[key]))
+	for k := range g.edges[key] {
+		deps = append(deps, k)
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i] < deps[j] })
+	return deps
+}
+
+// Reachable returns all the resources the resource depends on, directly or transitively.
+func (g *DependencyGraph) Reachable(key ResourceKey) map[ResourceKey]bool {
+	visited := map[ResourceKey]bool{key: true}
+	queue := []ResourceKey{key}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for next := range g.edges[current] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	delete(visited, key)
+	return visited
+}
+
+func NewResourceKey(kind string, namespace string, name string) ResourceKey {
+	return ResourceKey(kind + "/" + namespace + "/" + name)
+}
+
+func containerKey(podKey ResourceKey, container string) ResourceKey {
+	return ResourceKey(string(podKey) + "/" + container)
+}
+
+// Returns the key of the problem affected resource, container problems use the container key.
+func problemKey(p *Problem) ResourceKey {
+	key := ObjectKey(p.AffectedResources.Resource)
+	switch p.Tags[com.Resourcetype] {
+	case com.Container, com.Initcontainer:
+		if c := p.Tags[com.Container]; c != "" && key != "" {
+			return containerKey(key, c)
+		}
+	}
+	return key
+}
+
+// ObjectKey returns the key of the object in the dependency graph, empty if obj is not a metav1.Object.
+func ObjectKey(obj runtime.Object) ResourceKey {
+	mo, ok := obj.(metav1.Object)
+	if !ok {
+		return ""
+	}
+	return NewResourceKey(objectKind(obj), mo.GetNamespace(), mo.GetName())
+}
+
+func objectKind(obj runtime.Object) string {
+	switch obj.(type) {
+	case *corev1.Pod:
+		return "Pod"
+	case *corev1.Node:
+		return "Node"
+	case *corev1.Service:
+		return "Service"
+	case *corev1.Endpoints:
+		return "Endpoints"
+	case *discoveryv1.EndpointSlice:
+		return "EndpointSlice"
+	case *networkv1.Ingress:
+		return "Ingress"
+	case *appsv1.Deployment:
+		return "Deployment"
+	case *appsv1.ReplicaSet:
+		return "ReplicaSet"
+	case *appsv1.StatefulSet:
+		return "StatefulSet"
+	case *appsv1.DaemonSet:
+		return "DaemonSet"
+	case *batchv1.Job:
+		return "Job"
+	case *batchv1.CronJob:
+		return "CronJob"
+	}
+	return obj.GetObjectKind().GroupVersionKind().Kind
+}
+
+// BuildDependencyGraph lists Ingresses, Services, EndpointSlices, ReplicaSets, Jobs and Pods in the namespaces of the problems,
+// and discovers the edges from selectors, backends, endpoints, owner references and Pod spec references.
+func BuildDependencyGraph(ctx context.Context, client *kubeclient.KubeClient, problems []*Problem) *DependencyGraph {
+	g := NewDependencyGraph()
+
+	objects := make([]runtime.Object, 0)
+	namespaces := make(map[string]bool)
+	for _, p := range problems {
+		if p.AffectedResources.Resource == nil {
+			continue
+		}
+		objects = append(objects, p.AffectedResources.Resource)
+		if mo, ok := p.AffectedResources.Resource.(metav1.Object); ok && mo.GetNamespace() != "" {
+			namespaces[mo.GetNamespace()] = true
+		}
+	}
+	for ns := range namespaces {
+		objects = append(objects, listGraphObjects(ctx, client, ns)...)
+	}
+
+	pods := make([]*corev1.Pod, 0)
+	for _, obj := range objects {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	for _, obj := range objects {
+		addObjectEdges(g, obj, pods)
+	}
+
+	// Pending pods not scheduled to any node may be caused by the node problems the scheduler reports
+	for _, p := range problems {
+		node, ok := p.AffectedResources.Resource.(*corev1.Node)
+		if !ok {
+			continue
+		}
+		for _, pod := range pods {
+			if pod.Spec.NodeName == "" && pod.Status.Phase == corev1.PodPending && unschedulableBy(pod, node, p.Code) {
+				g.AddEdge(ObjectKey(pod), ObjectKey(node))
+			}
+		}
+	}
+	return g
+}
+
+// The scheduling failure of the pod references the node or its taints, or the node problem is
+// a capacity problem and the scheduler reports insufficient resources, which don't name the nodes.
+func unschedulableBy(pod *corev1.Pod, node *corev1.Node, code ErrorCode) bool {
+	msg := unschedulableMessage(pod)
+	if msg == "" {
+		return false
+	}
+	if strings.Contains(msg, node.Name) {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if strings.Contains(msg, taint.Key) {
+			return true
+		}
+	}
+	return nodeCapacityCodes[code] && strings.Contains(msg, insufficientResource)
+}
+
+func unschedulableMessage(pod *corev1.Pod) string {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable {
+			return c.Message
+		}
+	}
+	return ""
+}
+
+func listGraphObjects(ctx context.Context, client *kubeclient.KubeClient, ns string) []runtime.Object {
+	objects := make([]runtime.Object, 0)
+	if client == nil {
+		return objects
+	}
+	namespace := kubeclient.NamespacedName{Namespace: ns}
+	l := log.SWithContext(ctx)
+
+	ingresses := &networkv1.IngressList{}
+	if err := client.List(ctx, ingresses, namespace, metav1.ListOptions{}); err == nil {
+		for i := range ingresses.Items {
+			objects = append(objects, &ingresses.Items[i])
+		}
+	} else {
+		l.Warnf("Failed to list ingresses for dependency graph, error is %s", err)
+	}
+	services := &corev1.ServiceList{}
+	if err := client.List(ctx, services, namespace, metav1.ListOptions{}); err == nil {
+		for i := range services.Items {
+			objects = append(objects, &services.Items[i])
+		}
+	} else {
+		l.Warnf("Failed to list services for dependency graph, error is %s", err)
+	}
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := client.List(ctx, slices, namespace, metav1.ListOptions{}); err == nil {
+		for i := range slices.Items {
+			objects = append(objects, &slices.Items[i])
+		}
+	} else {
+		l.Warnf("Failed to list endpointslices for dependency graph, error is %s", err)
+	}
+	replicasets := &appsv1.ReplicaSetList{}
+	if err := client.List(ctx, replicasets, namespace, metav1.ListOptions{}); err == nil {
+		for i := range replicasets.Items {
+			objects = append(objects, &replicasets.Items[i])
+		}
+	} else {
+		l.Warnf("Failed to list replicasets for dependency graph, error is %s", err)
+	}
+	jobs := &batchv1.JobList{}
+	if err := client.List(ctx, jobs, namespace, metav1.ListOptions{}); err == nil {
+		for i := range jobs.Items {
+			objects = append(objects, &jobs.Items[i])
+		}
+	} else {
+		l.Warnf("Failed to list jobs for dependency graph, error is %s", err)
+	}
+	pods := &corev1.PodList{}
+	if err := client.List(ctx, pods, namespace, metav1.ListOptions{}); err == nil {
+		for i := range pods.Items {
+			objects = append(objects, &pods.Items[i])
+		}
+	} else {
+		l.Warnf("Failed to list pods for dependency graph, error is %s", err)
+	}
+	return objects
+}
+
+func addObjectEdges(g *DependencyGraph, obj runtime.Object, pods []*corev1.Pod) {
+	key := ObjectKey(obj)
+	if key == "" {
+		return
+	}
+	mo := obj.(metav1.Object)
+	ns := mo.GetNamespace()
+
+	// owner depends on the owned resource, e.g. Deployment -> ReplicaSet -> Pod
+	if owner := getControlOwner(mo); owner != nil {
+		g.AddEdge(NewResourceKey(owner.Kind, ns, owner.Name), key)
+	}
+
+	switch v := obj.(type) {
+	case *networkv1.Ingress:
+		if v.Spec.DefaultBackend != nil && v.Spec.DefaultBackend.Service != nil {
+			g.AddEdge(key, NewResourceKey("Service", ns, v.Spec.DefaultBackend.Service.Name))
+		}
+		for _, rule := range v.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service != nil {
+					g.AddEdge(key, NewResourceKey("Service", ns, path.Backend.Service.Name))
+				}
+			}
+		}
+	case *corev1.Service:
+		g.AddEdge(key, NewResourceKey("Endpoints", ns, v.Name))
+		if len(v.Spec.Selector) > 0 {
+			selector := labels.SelectorFromSet(v.Spec.Selector)
+			for _, pod := range pods {
+				if pod.Namespace == ns && selector.Matches(labels.Set(pod.Labels)) {
+					g.AddEdge(key, ObjectKey(pod))
+				}
+			}
+		}
+	case *discoveryv1.EndpointSlice:
+		if svc := v.Labels[discoveryv1.LabelServiceName]; svc != "" {
+			g.AddEdge(NewResourceKey("Service", ns, svc), key)
+		}
+		for _, ep := range v.Endpoints {
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				g.AddEdge(key, NewResourceKey("Pod", ns, ep.TargetRef.Name))
+			}
+		}
+	case *corev1.Endpoints:
+		for _, subset := range v.Subsets {
+			for _, addr := range append(append([]corev1.EndpointAddress{}, subset.Addresses...), subset.NotReadyAddresses...) {
+				if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+					g.AddEdge(key, NewResourceKey("Pod", ns, addr.TargetRef.Name))
+				}
+			}
+		}
+	case *corev1.Pod:
+		addPodEdges(g, key, v)
+	}
+}
+
+// Pod depends on its containers, Node, PVCs, ConfigMaps and Secrets.
+func addPodEdges(g *DependencyGraph, key ResourceKey, pod *corev1.Pod) {
+	ns := pod.Namespace
+	if pod.Spec.NodeName != "" {
+		g.AddEdge(key, NewResourceKey("Node", "", pod.Spec.NodeName))
+	}
+	for _, c := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		g.AddEdge(key, containerKey(key, c.Name))
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil {
+				g.AddEdge(key, NewResourceKey("ConfigMap", ns, from.ConfigMapRef.Name))
+			}
+			if from.SecretRef != nil {
+				g.AddEdge(key, NewResourceKey("Secret", ns, from.SecretRef.Name))
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				g.AddEdge(key, NewResourceKey("ConfigMap", ns, env.ValueFrom.ConfigMapKeyRef.Name))
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				g.AddEdge(key, NewResourceKey("Secret", ns, env.ValueFrom.SecretKeyRef.Name))
+			}
+		}
+	}
+	for _, vol := range pod.Spec.Volumes {
+		switch {
+		case vol.PersistentVolumeClaim != nil:
+			g.AddEdge(key, NewResourceKey("PersistentVolumeClaim", ns, vol.PersistentVolumeClaim.ClaimName))
+		case vol.ConfigMap != nil:
+			g.AddEdge(key, NewResourceKey("ConfigMap", ns, vol.ConfigMap.Name))
+		case vol.Secret != nil:
+			g.AddEdge(key, NewResourceKey("Secret", ns, vol.Secret.SecretName))
+		}
+	}
+	for _, secret := range pod.Spec.ImagePullSecrets {
+		g.AddEdge(key, NewResourceKey("Secret", ns, secret.Name))
+	}
+}
+
+type graphFinding struct {
+	key     ResourceKey
+	card    *ReportCard
+	res     *ReportCardResource
+	problem *Problem
+}
+
+// Set the hypotheses, root cause and contributing findings of the report cards. Findings reachable from the card
+// resources are contributing findings, each of them is a hypothesis, the deepest ones, which have no other findings
+// downstream, get higher confidence. The root cause is the first hypothesis, it may come from another report card,
+// e.g. a Node.
+func setRootCauses(cards []*ReportCard, g *DependencyGraph) {
+	findings := make(map[ResourceKey][]graphFinding)
+	for _, card := range cards {
+		for i, p := range card.problems {
+			if i >= len(card.Resources) {
+				break
+			}
+			key := problemKey(p)
+			if key == "" {
+				continue
+			}
+			findings[key] = append(findings[key], graphFinding{key: key, card: card, res: card.Resources[i], problem: p})
+		}
+	}
+	levels := causeLevels(g, findings)
+	for key, fs := range findings {
+		for _, f := range fs {
+			f.res.Issue.CauseLevel = levels[key]
+		}
+	}
+
+	for _, card := range cards {
+		contributing := make([]graphFinding, 0)
+		seen := make(map[ResourceKey]bool)
+		for _, p := range card.problems {
+			key := problemKey(p)
+			if key == "" {
+				continue
+			}
+			keys := g.Reachable(key)
+			keys[key] = true
+			for k := range keys {
+				if !seen[k] {
+					seen[k] = true
+					contributing = append(contributing, findings[k]...)
+				}
+			}
+		}
+		sort.SliceStable(contributing, func(i, j int) bool { return lessFinding(card, contributing[i], contributing[j]) })
+
+		card.ContributingFindings = make([]*Finding, 0, len(contributing))
+		for _, f := range contributing {
+			card.ContributingFindings = append(card.ContributingFindings, &Finding{
+				CardID:   f.card.ID,
+				Resource: string(f.key),
+				Issue:    f.res.Issue.Name,
+				Code:     f.res.Issue.Code,
+			})
+		}
+
+		deepest := make(map[ResourceKey]bool)
+		for _, f := range contributing {
+			if !hasDownstreamFinding(g, f.key, findings) {
+				deepest[f.key] = true
+			}
+		}
+		// cyclic dependencies, all the findings are the deepest
+		cyclic := len(deepest) == 0
+
+		card.Hypotheses = make([]*Hypothesis, 0, len(contributing))
+		for _, f := range contributing {
+			card.Hypotheses = append(card.Hypotheses, newHypothesis(f, cyclic || deepest[f.key]))
+		}
+		// contributing findings are sorted already, stable sort keeps the order for same confidence
+		sort.SliceStable(card.Hypotheses, func(i, j int) bool {
+			return card.Hypotheses[i].Confidence > card.Hypotheses[j].Confidence
+		})
+		if len(card.Hypotheses) > 0 {
+			card.RootCause = card.Hypotheses[0].Issue
+		} else if len(card.Resources) > 0 {
+			card.RootCause = card.Resources[0].Issue
+		}
+	}
+}
+
+// Cause level of a finding is the number of findings on the longest path downstream, the deepest is level 0.
+func causeLevels(g *DependencyGraph, findings map[ResourceKey][]graphFinding) map[ResourceKey]int {
+	downstream := make(map[ResourceKey][]ResourceKey, len(findings))
+	for key := range findings {
+		for k := range g.Reachable(key) {
+			if len(findings[k]) > 0 {
+				downstream[key] = append(downstream[key], k)
+			}
+		}
+	}
+	levels := make(map[ResourceKey]int, len(findings))
+	visiting := make(map[ResourceKey]bool)
+	var level func(key ResourceKey) int
+	level = func(key ResourceKey) int {
+		if l, ok := levels[key]; ok {
+			return l
+		}
+		// cyclic dependencies
+		if visiting[key] {
+			return 0
+		}
+		visiting[key] = true
+		l := 0
+		for _, k := range downstream[key] {
+			l = max(l, level(k)+1)
+		}
+		levels[key] = l
+		return l
+	}
+	for key := range findings {
+		level(key)
+	}
+	return levels
+}
+
+func hasDownstreamFinding(g *DependencyGraph, key ResourceKey, findings map[ResourceKey][]graphFinding) bool {
+	for k := range g.Reachable(key) {
+		if len(findings[k]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Higher severity first, then findings of the card itself, then by resource and issue name.
+func lessFinding(card *ReportCard, a graphFinding, b graphFinding) bool {
+	if sa, sb := severityWeights[a.res.Issue.Severity], severityWeights[b.res.Issue.Severity]; sa != sb {
+		return sa > sb
+	}
+	if (a.card == card) != (b.card == card) {
+		return a.card == card
+	}
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	return a.res.Issue.Name < b.res.Issue.Name
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newGraphProblem(name string, code ErrorCode, resourceType string, obj runtime.Object) *Problem {
+	return &Problem{
+		Name:              name,
+		Code:              code,
+		Tags:              map[string]string{com.Resourcetype: resourceType},
+		AffectedResources: ResourceDetails{Resource: obj},
+	}
+}
+
+func TestRootCauseFromDependencyGraph(t *testing.T) {
+	pathType := networkv1.PathTypePrefix
+	ingress := &networkv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+		Spec: networkv1.IngressSpec{Rules: []networkv1.IngressRule{{
+			IngressRuleValue: networkv1.IngressRuleValue{HTTP: &networkv1.HTTPIngressRuleValue{
+				Paths: []networkv1.HTTPIngressPath{{
+					Path:     "/",
+					PathType: &pathType,
+					Backend:  networkv1.IngressBackend{Service: &networkv1.IngressServiceBackend{Name: "web-svc"}},
+				}},
+			}},
+		}}},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-svc", Namespace: "team-a"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "team-a", Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{{Name: "app"}}},
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+
+	ingressProblem := newGraphProblem("IngressBackendNotAvailable", IngressConfigErr, com.Ingress, ingress)
+	podProblem := newGraphProblem("PodNotReady", PodNotReadyErr, com.Pod, pod)
+	serviceProblem := newGraphProblem("ServiceNotAvailable", EndpointNotAvailErr, com.Service, service)
+	nodeProblem := newGraphProblem("NodeNotReady", NodeNotReadyErr, com.Node, node)
+	problems := []*Problem{ingressProblem, serviceProblem, podProblem, nodeProblem}
+
+	g := BuildDependencyGraph(context.Background(), nil, problems)
+	assert.Equal(t, []ResourceKey{"Service/team-a/web-svc"}, g.Dependencies("Ingress/team-a/web"))
+	assert.Contains(t, g.Dependencies("Service/team-a/web-svc"), ResourceKey("Pod/team-a/web-1"))
+	assert.Contains(t, g.Dependencies("Pod/team-a/web-1"), ResourceKey("Node//node-1"))
+	assert.Contains(t, g.Dependencies("Pod/team-a/web-1"), ResourceKey("Pod/team-a/web-1/app"))
+
+	webCard := newGraphCard("1", ingressProblem, serviceProblem, podProblem)
+	nodeCard := newGraphCard("2", nodeProblem)
+	setRootCauses([]*ReportCard{webCard, nodeCard}, g)
+
+	// the node is the deepest finding, even it belongs to another card
+	assert.Equal(t, "NodeNotReady", webCard.RootCause.Name)
+	assert.Len(t, webCard.ContributingFindings, 4)
+	assert.Equal(t, "NodeNotReady", nodeCard.RootCause.Name)
+	assert.Len(t, nodeCard.ContributingFindings, 1)
+	// ingress -> service -> pod -> node
+	assert.Equal(t, 3, webCard.Resources[0].Issue.CauseLevel)
+	assert.Equal(t, 2, webCard.Resources[1].Issue.CauseLevel)
+	assert.Equal(t, 1, webCard.Resources[2].Issue.CauseLevel)
+	assert.Equal(t, 0, nodeCard.Resources[0].Issue.CauseLevel)
+}
+
+func TestPendingPodNodeEdges(t *testing.T) {
+	pending := func(name string, msg string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+			Status: corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Reason:  corev1.PodReasonUnschedulable,
+				Message: msg,
+			}}},
+		}
+	}
+	tainted := pending("tainted", "0/3 nodes are available: 1 node(s) had untolerated taint {node.kubernetes.io/not-ready: }.")
+	insufficient := pending("insufficient", "0/3 nodes are available: 3 Insufficient memory.")
+	affinity := pending("affinity", "0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector.")
+
+	notReady := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "node.kubernetes.io/not-ready"}}},
+	}
+	pressure := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}}
+	problems := []*Problem{
+		newGraphProblem("PodPending", PendingPodsErr, com.Pod, tainted),
+		newGraphProblem("PodPending", PendingPodsErr, com.Pod, insufficient),
+		newGraphProblem("PodPending", PendingPodsErr, com.Pod, affinity),
+		newGraphProblem("NodeNotReady", NodeNotReadyErr, com.Node, notReady),
+		newGraphProblem("NodeMemoryPressure", NodeMemoryPressureErr, com.Node, pressure),
+	}
+
+	g := BuildDependencyGraph(context.Background(), nil, problems)
+	assert.Equal(t, []ResourceKey{"Node//node-1"}, g.Dependencies("Pod/team-a/tainted"))
+	assert.Equal(t, []ResourceKey{"Node//node-2"}, g.Dependencies("Pod/team-a/insufficient"))
+	assert.Empty(t, g.Dependencies("Pod/team-a/affinity"))
+}
+
+func newGraphCard(id string, problems ...*Problem) *ReportCard {
+	card := &ReportCard{ID: id}
+	for _, p := range problems {
+		issueType := GetIssueType(p.Code)
+		card.problems = append(card.problems, p)
+		card.Resources = append(card.Resources, &ReportCardResource{
+			Issue: &ReportCardIssue{Name: p.Name, Code: p.Code, Severity: issueType.Severity},
+		})
+	}
+	return card
+}
+
+func TestHypothesesRankedByConfidence(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "team-a"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+	}
+	app := newGraphProblem("ContainerWaitingAsCrashLoopBackoff", CrashLoopErr, com.Container, pod)
+	app.Tags[com.Container] = "app"
+	app.Findings = InitFindings()
+	app.Findings.AddEvidence(EvidenceLog, "panic: runtime error", time.Time{})
+	app.Findings.SetConfidence(0.9)
+	sidecar := newGraphProblem("ContainerWaitingAsCrashLoopBackoff", CrashLoopErr, com.Container, pod)
+	sidecar.Tags[com.Container] = "sidecar"
+	notReady := newGraphProblem("PodNotReady", PodNotReadyErr, com.Pod, pod)
+
+	g := BuildDependencyGraph(context.Background(), nil, []*Problem{notReady, sidecar, app})
+	card := newGraphCard("1", notReady, sidecar, app)
+	setRootCauses([]*ReportCard{card}, g)
+
+	assert.Len(t, card.Hypotheses, 3)
+	assert.Equal(t, "Pod/team-a/web-1/app", card.Hypotheses[0].Resource)
+	assert.Equal(t, []Evidence{{Source: EvidenceLog, Message: "panic: runtime error"}}, card.Hypotheses[0].Evidence)
+	assert.Equal(t, "Pod/team-a/web-1/sidecar", card.Hypotheses[1].Resource)
+	// the pod has findings downstream, it is the least likely
+	assert.Equal(t, "Pod/team-a/web-1", card.Hypotheses[2].Resource)
+	assert.Same(t, card.Hypotheses[0].Issue, card.RootCause)
+	assert.Greater(t, card.Hypotheses[0].Confidence, card.Hypotheses[1].Confidence)
+}
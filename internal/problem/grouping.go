@@ -0,0 +1,120 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"fmt"
+	"strings"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Strategies to group the problems into report cards, by the top resource of the owner chain.
+const (
+	GroupByArgo  = "argo"
+	GroupByFlux  = "flux"
+	GroupByHelm  = "helm"
+	GroupByOwner = "owner"
+	// label:<key> groups by the label value, e.g. label:app.kubernetes.io/part-of
+	GroupByLabelPrefix = "label:"
+)
+
+// DefaultGrouping groups by Argo instance, then Flux instance, then Helm chart, then the top owner.
+var DefaultGrouping = []string{GroupByArgo, GroupByFlux, GroupByHelm, GroupByOwner}
+
+// Group of a report card, the name and TopResourceType of the card.
+type group struct {
+	name    string
+	topType string
+	// the top resource if grouped by owner
+	top metav1.Object
+}
+
+type groupFunc func(top metav1.Object) *group
+
+var groupFuncs = map[string]groupFunc{
+	GroupByArgo:  groupByArgo,
+	GroupByFlux:  groupByFlux,
+	GroupByHelm:  groupByHelm,
+	GroupByOwner: groupByOwner,
+}
+
+// ValidateGrouping returns error if any strategy is unknown or a label key is empty.
+func ValidateGrouping(strategies []string) error {
+	for _, s := range strategies {
+		if key, ok := strings.CutPrefix(s, GroupByLabelPrefix); ok {
+			if key == "" {
+				return fmt.Errorf("label key of grouping strategy %s is empty", s)
+			}
+		} else if _, ok := groupFuncs[s]; !ok {
+			return fmt.Errorf("unknown grouping strategy %s, should be %s, %s, %s, %s or %s<key>",
+				s, GroupByArgo, GroupByFlux, GroupByHelm, GroupByOwner, GroupByLabelPrefix)
+		}
+	}
+	return nil
+}
+
+// Returns the group of the first strategy matching the top resource, the owner is the fallback.
+func getGroup(top metav1.Object, strategies []string) *group {
+	for _, s := range strategies {
+		fc, ok := groupFuncs[s]
+		if key, isLabel := strings.CutPrefix(s, GroupByLabelPrefix); isLabel {
+			fc, ok = groupByLabel(key), true
+		}
+		if !ok {
+			continue
+		}
+		if g := fc(top); g != nil {
+			return g
+		}
+	}
+	return groupByOwner(top)
+}
+
+func groupByArgo(top metav1.Object) *group {
+	if argo := getArgoInstance(top); argo.Instance != "" {
+		return &group{name: argo.Instance, topType: com.Argo}
+	}
+	return nil
+}
+
+func groupByFlux(top metav1.Object) *group {
+	if flux := getFluxInstance(top); flux != nil {
+		return &group{name: flux.Name, topType: flux.Kind}
+	}
+	return nil
+}
+
+// Argo rollouts are not grouped by helm chart.
+func groupByHelm(top metav1.Object) *group {
+	if getArgoInstance(top).RolloutTemplate != "" {
+		return nil
+	}
+	if chart := getHelmChart(top); !chart.isEmpty() {
+		return &group{name: chart.toString(), topType: com.Helm}
+	}
+	return nil
+}
+
+func groupByOwner(top metav1.Object) *group {
+	topType := ""
+	if obj, ok := top.(runtime.Object); ok {
+		topType = obj.GetObjectKind().GroupVersionKind().Kind
+	}
+	return &group{name: top.GetName(), topType: topType, top: top}
+}
+
+// The label key is the TopResourceType of the card.
+func groupByLabel(key string) groupFunc {
+	return func(top metav1.Object) *group {
+		if v := top.GetLabels()[key]; v != "" {
+			return &group{name: v, topType: key}
+		}
+		return nil
+	}
+}
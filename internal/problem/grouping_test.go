@@ -0,0 +1,67 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"testing"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateGrouping(t *testing.T) {
+	assert.Nil(t, ValidateGrouping(nil))
+	assert.Nil(t, ValidateGrouping(DefaultGrouping))
+	assert.Nil(t, ValidateGrouping([]string{"label:app.kubernetes.io/part-of", "label:team", GroupByOwner}))
+	assert.NotNil(t, ValidateGrouping([]string{"label:"}))
+	assert.NotNil(t, ValidateGrouping([]string{"namespace"}))
+}
+
+func TestGetGroup(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout-api", Labels: map[string]string{
+			"app.kubernetes.io/part-of":   "checkout",
+			"helm.sh/chart":               "checkout-api-1.2.0",
+			"argocd.argoproj.io/instance": "checkout-prod",
+		}},
+	}
+
+	g := getGroup(deploy, DefaultGrouping)
+	assert.Equal(t, "checkout-prod", g.name)
+	assert.Equal(t, com.Argo, g.topType)
+	assert.Nil(t, g.top)
+
+	g = getGroup(deploy, []string{"label:team", "label:app.kubernetes.io/part-of", GroupByHelm})
+	assert.Equal(t, "checkout", g.name)
+	assert.Equal(t, "app.kubernetes.io/part-of", g.topType)
+
+	g = getGroup(deploy, []string{GroupByHelm, GroupByArgo})
+	assert.Equal(t, "checkout-api-1.2.0", g.name)
+	assert.Equal(t, com.Helm, g.topType)
+
+	// the owner is the fallback
+	g = getGroup(deploy, []string{"label:team", GroupByFlux})
+	assert.Equal(t, "checkout-api", g.name)
+	assert.Equal(t, "Deployment", g.topType)
+	assert.Equal(t, deploy, g.top)
+
+	// argo rollouts are not grouped by helm chart
+	deploy.Labels["rollouts-pod-template-hash"] = "5d8f7"
+	g = getGroup(deploy, []string{GroupByHelm})
+	assert.Equal(t, "checkout-api", g.name)
+}
+
+func TestGetGroupOfDeletedOwner(t *testing.T) {
+	controller := true
+	oref := &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d8f7", Controller: &controller}
+	g := getGroup(ownerPlaceholder(oref, "ns1"), DefaultGrouping)
+	assert.Equal(t, "web-5d8f7", g.name)
+	assert.Equal(t, "ReplicaSet", g.topType)
+	assert.Equal(t, "ns1", g.top.GetNamespace())
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"sync"
+	"time"
+)
+
+type EvidenceSource string
+
+const (
+	EvidenceEvent  EvidenceSource = "event"
+	EvidenceStatus EvidenceSource = "status"
+	EvidenceLog    EvidenceSource = "log"
+)
+
+// Weights of the hypothesis confidence factors, the sum is 1.
+const (
+	graphWeight        = 0.5
+	investigatorWeight = 0.3
+	severityWeight     = 0.2
+
+	// graph factor of findings which have other findings downstream
+	upstreamGraphFactor = 0.4
+	// investigator factor when investigator found evidence but did not set confidence
+	evidenceConfidence = 0.5
+	// investigator factor when there is no evidence at all
+	noEvidenceConfidence = 0.3
+)
+
+// Evidence supports a hypothesis, e.g. a Kubernetes event, a status field or a log line.
+type Evidence struct {
+	Source    EvidenceSource `json:"source"`
+	Message   string         `json:"message"`
+	Timestamp string         `json:"timestamp,omitempty"`
+}
+
+// Hypothesis is a possible root cause of a report card, hypotheses are sorted by confidence descending.
+type Hypothesis struct {
+	Issue      *ReportCardIssue `json:"issue"`
+	Resource   string           `json:"resource"`
+	CardID     string           `json:"cardId"`
+	Confidence float64          `json:"confidence"`
+	Evidence   []Evidence       `json:"evidence,omitempty"`
+}
+
+// Findings collects the evidence and confidence contributed by investigators,
+// investigators of a problem run concurrently.
+type Findings struct {
+	mx         *sync.Mutex
+	evidence   []Evidence
+	confidence float64
+	metrics    []*MetricSummary
+}
+
+func InitFindings() *Findings {
+	return &Findings{
+		mx:       &sync.Mutex{},
+		evidence: make([]Evidence, 0),
+	}
+}
+
+func (f *Findings) AddEvidence(source EvidenceSource, message string, timestamp time.Time) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	e := Evidence{Source: source, Message: message}
+	if !timestamp.IsZero() {
+		e.Timestamp = timestamp.UTC().Format(time.RFC3339)
+	}
+	f.evidence = append(f.evidence, e)
+}
+
+// SetConfidence sets how confident the investigator is that the problem is the root cause, between 0 and 1.
+// The highest confidence set by the investigators is kept.
+func (f *Findings) SetConfidence(confidence float64) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	if confidence > f.confidence {
+		f.confidence = min(confidence, 1)
+	}
+}
+
+func (f *Findings) GetEvidence() []Evidence {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return append([]Evidence{}, f.evidence...)
+}
+
+func (f *Findings) GetConfidence() float64 {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return f.confidence
+}
+
+func (f *Findings) AddMetrics(metrics ...*MetricSummary) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.metrics = append(f.metrics, metrics...)
+}
+
+func (f *Findings) GetMetrics() []*MetricSummary {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return append([]*MetricSummary{}, f.metrics...)
+}
+
+// Confidence of the finding as root cause, combines the position in dependency graph,
+// the investigator confidence and the issue severity.
+func hypothesisConfidence(f graphFinding, deepest bool) float64 {
+	graph := upstreamGraphFactor
+	if deepest {
+		graph = 1
+	}
+	investigator := noEvidenceConfidence
+	if f.problem != nil && f.problem.Findings != nil {
+		if c := f.problem.Findings.GetConfidence(); c > 0 {
+			investigator = c
+		} else if len(f.problem.Findings.GetEvidence()) > 0 {
+			investigator = evidenceConfidence
+		}
+	}
+	return round(graphWeight*graph + investigatorWeight*investigator + severityWeight*severityWeights[f.res.Issue.Severity])
+}
+
+func newHypothesis(f graphFinding, deepest bool) *Hypothesis {
+	h := &Hypothesis{
+		Issue:      f.res.Issue,
+		Resource:   string(f.key),
+		CardID:     f.card.ID,
+		Confidence: hypothesisConfidence(f, deepest),
+	}
+	if f.problem != nil && f.problem.Findings != nil {
+		h.Evidence = f.problem.Findings.GetEvidence()
+	}
+	return h
+}
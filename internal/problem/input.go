@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/fidelity/theliv/pkg/config"
 	"github.com/fidelity/theliv/pkg/kubeclient"
 	"github.com/fidelity/theliv/pkg/observability"
 	"k8s.io/client-go/rest"
@@ -19,6 +20,12 @@ type TimeSpan struct {
 	TimespanType time.Duration
 }
 
+// TimeWindow of a historical detection, the alerts firing in the window are detected.
+type TimeWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
 type DetectorCreationInput struct {
 	Kubeconfig     *rest.Config
 	Namespace      string
@@ -28,4 +35,15 @@ type DetectorCreationInput struct {
 	AwsConfig      aws.Config
 	KubeClient     *kubeclient.KubeClient
 	EventRetriever observability.EventRetriever
+	LogRetriever   observability.LogRetriever
+	// range queries of resource usage, nil if prometheus is not available
+	MetricRetriever observability.MetricRetriever
+	// prometheus endpoint of the cluster, the default endpoint is used if nil
+	Prometheus *config.PrometheusEndpoint
+	// historical detection, nil detects the current alerts
+	Window *TimeWindow
+	// report card grouping strategies of the request, the strategies in config are used if empty
+	Grouping []string
+	// re-run of a watched namespace, the recurrence is kept from the first detection instead of queried again
+	Refresh bool
 }
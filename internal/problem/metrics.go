@@ -0,0 +1,57 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"math"
+	"sort"
+
+	"github.com/fidelity/theliv/pkg/observability"
+)
+
+// MetricSummary summarizes a series of a range query over the problem window.
+type MetricSummary struct {
+	Name    string            `json:"name"`
+	Unit    string            `json:"unit,omitempty"`
+	Query   string            `json:"query"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Min     float64           `json:"min"`
+	Max     float64           `json:"max"`
+	P95     float64           `json:"p95"`
+	Last    float64           `json:"last"`
+	Samples int               `json:"samples"`
+}
+
+// SummarizeSeries returns min, max, p95 and last value of the series, NaN and Inf are skipped.
+// Returns nil if there is no valid sample.
+func SummarizeSeries(name string, unit string, query string, series observability.MetricSeries) *MetricSummary {
+	values := make([]float64, 0, len(series.Samples))
+	last := 0.0
+	for _, s := range series.Samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		values = append(values, s.Value)
+		last = s.Value
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	// nearest rank
+	rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+	return &MetricSummary{
+		Name:    name,
+		Unit:    unit,
+		Query:   query,
+		Labels:  series.Labels,
+		Min:     round(values[0]),
+		Max:     round(values[len(values)-1]),
+		P95:     round(values[rank]),
+		Last:    round(last),
+		Samples: len(values),
+	}
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/pkg/observability"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeSeries(t *testing.T) {
+	now := time.Now()
+	series := observability.MetricSeries{Labels: map[string]string{"container": "app"}}
+	for i := 1; i <= 20; i++ {
+		series.Samples = append(series.Samples, observability.MetricSample{
+			Timestamp: now.Add(time.Duration(i) * time.Minute), Value: float64(i)})
+	}
+	// invalid samples are skipped
+	series.Samples = append(series.Samples, observability.MetricSample{Timestamp: now, Value: math.NaN()})
+	series.Samples = append(series.Samples, observability.MetricSample{Timestamp: now, Value: 7})
+
+	s := SummarizeSeries("memory", "bytes", "q", series)
+	assert.Equal(t, 1.0, s.Min)
+	assert.Equal(t, 20.0, s.Max)
+	assert.Equal(t, 19.0, s.P95)
+	assert.Equal(t, 7.0, s.Last)
+	assert.Equal(t, 21, s.Samples)
+	assert.Equal(t, "app", s.Labels["container"])
+
+	assert.Nil(t, SummarizeSeries("memory", "bytes", "q", observability.MetricSeries{}))
+}
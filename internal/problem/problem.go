@@ -6,12 +6,37 @@
 package problem
 
 import (
+	"time"
+
 	"github.com/fidelity/theliv/pkg/common"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // Custom type for holding the Problem domains.
-type DomainName int
+type DomainName string
+
+const (
+	DomainWorkload DomainName = "workload"
+	DomainNetwork  DomainName = "network"
+	DomainStorage  DomainName = "storage"
+	DomainNode     DomainName = "node"
+	DomainConfig   DomainName = "config"
+)
+
+// Domains of the issue taxonomy.
+var Domains = []DomainName{DomainWorkload, DomainNetwork, DomainStorage, DomainNode, DomainConfig}
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+)
+
+// Severities of the issue taxonomy, from the most severe.
+var Severities = []Severity{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow}
 
 type DetectorName string
 type DeeplinkType string
@@ -33,12 +58,19 @@ const (
 // Problem struct is for Prometheus alerts feature. It is the input and output struct for detectors.
 type Problem struct {
 	Name              string
+	Code              ErrorCode
 	Description       string
 	Tags              map[string]string
 	Level             ProblemLevel
-	CauseLevel        int
+	ActiveAt          time.Time           // when the alert became active, zero if unknown
+	AlertState        string              // firing or pending, empty if not built from an alert
+	SuppressedBy      []string            // silences and inhibiting alerts in alertmanager, if flagged not dropped
+	HistoricalNote    string              // historical detection only, the resource changed or deleted since the window
+	Recurrence        *Recurrence         // how often the alert fired in the recurrence window, nil if unknown
+	Replicas          []string            // pods merged into this problem by CollapseReplicas, empty if not merged
 	SolutionDetails   *common.LockedSlice // output field after detetor. It contains solutions details to show in UI.
 	UsefulCommands    *common.LockedSlice // output field after detetor. It contains solutions details to show in UI.
+	Findings          *Findings           // output field after detetor. It contains evidence and confidence as root cause.
 	AffectedResources ResourceDetails     // output field after detetor. It contains the resources affected by this problem that to show in UI.
 }
 
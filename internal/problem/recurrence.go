@@ -0,0 +1,213 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	com "github.com/fidelity/theliv/pkg/common"
+)
+
+// Defaults of the recurrence config.
+const (
+	DefaultRecurrenceWindowHours  = 7 * 24
+	DefaultMinOccurrences         = 3
+	DefaultFlappingSeconds        = 600
+	DefaultRecurrenceCacheSeconds = 300
+)
+
+const (
+	// gaps between the starts within this ratio of the mean are regular
+	periodTolerance = 0.2
+	// starts within this time of the day are daily
+	dailyTolerance = 30 * time.Minute
+	day            = 24 * time.Hour
+	week           = 7 * day
+)
+
+// Interval is a period the issue was firing.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MergeIntervals sorts the intervals and merges the overlapping ones.
+func MergeIntervals(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+	merged := make([]Interval, 0, len(intervals))
+	for _, in := range intervals {
+		if n := len(merged); n > 0 && !in.Start.After(merged[n-1].End) {
+			if in.End.After(merged[n-1].End) {
+				merged[n-1].End = in.End
+			}
+			continue
+		}
+		merged = append(merged, in)
+	}
+	return merged
+}
+
+// Recurrence of a finding over the recurrence window, built from the ALERTS history or stored detections.
+type Recurrence struct {
+	// number of times the issue fired in the window, including the current one
+	Occurrences int `json:"occurrences"`
+	// total firing duration in the window
+	DurationSeconds int64     `json:"durationSeconds"`
+	FirstSeen       time.Time `json:"firstSeen"`
+	LastSeen        time.Time `json:"lastSeen"`
+	WindowHours     int       `json:"windowHours"`
+	// periodicity hint, e.g. daily around 02:00 UTC, empty if not periodic
+	Period    string `json:"period,omitempty"`
+	Recurring bool   `json:"recurring,omitempty"`
+	// recurring and each occurrence is short
+	Flapping bool `json:"flapping,omitempty"`
+}
+
+// Pod names generated by the controllers, deployment pods have replicaset hash and random suffix,
+// daemonset and job pods have random suffix, statefulset pods have ordinal.
+var (
+	replicaSetPod = regexp.MustCompile(`^(.+)-[a-z0-9]{8,10}-[a-z0-9]{5}$`)
+	generatedPod  = regexp.MustCompile(`^(.+)-[a-z0-9]{5}$`)
+	orderedPod    = regexp.MustCompile(`^(.+)-[0-9]+$`)
+)
+
+// RecurrenceKey is stable between the pods recreated by the controller, it is the alert, namespace,
+// workload name guessed from the pod name, container and node.
+func RecurrenceKey(tags map[string]string) string {
+	return strings.Join([]string{tags["alertname"], tags[com.Namespace], workloadName(tags[com.Pod]),
+		tags[com.Container], tags[com.Node]}, "/")
+}
+
+func workloadName(pod string) string {
+	for _, re := range []*regexp.Regexp{replicaSetPod, generatedPod, orderedPod} {
+		if m := re.FindStringSubmatch(pod); m != nil {
+			return m[1]
+		}
+	}
+	return pod
+}
+
+// ComputeRecurrence summarizes the firing intervals, it is recurring if fired at least minOccurrences times,
+// flapping if also the mean duration is shorter than flapping. Returns nil if no interval.
+func ComputeRecurrence(intervals []Interval, windowHours int, minOccurrences int, flapping time.Duration) *Recurrence {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+	var total time.Duration
+	r := &Recurrence{
+		Occurrences: len(intervals),
+		FirstSeen:   intervals[0].Start.UTC(),
+		WindowHours: windowHours,
+	}
+	for _, i := range intervals {
+		total += i.End.Sub(i.Start)
+		if i.End.After(r.LastSeen) {
+			r.LastSeen = i.End.UTC()
+		}
+	}
+	r.DurationSeconds = int64(total.Seconds())
+	r.Recurring = r.Occurrences >= minOccurrences
+	r.Flapping = r.Recurring && total/time.Duration(r.Occurrences) < flapping
+	r.Period = periodHint(intervals)
+	return r
+}
+
+// Periodicity of the starts, needs at least 3 occurrences.
+func periodHint(intervals []Interval) string {
+	if len(intervals) < 3 {
+		return ""
+	}
+	starts := make([]time.Time, len(intervals))
+	for i, in := range intervals {
+		starts[i] = in.Start.UTC()
+	}
+	if at, ok := dailyAt(starts); ok {
+		return "daily around " + at
+	}
+	gaps := make([]time.Duration, len(starts)-1)
+	var sum time.Duration
+	for i := 1; i < len(starts); i++ {
+		gaps[i-1] = starts[i].Sub(starts[i-1])
+		sum += gaps[i-1]
+	}
+	mean := sum / time.Duration(len(gaps))
+	for _, g := range gaps {
+		if math.Abs(float64(g-mean)) > periodTolerance*float64(mean) {
+			return ""
+		}
+	}
+	switch {
+	case math.Abs(float64(mean-week)) <= periodTolerance*float64(week):
+		return "weekly around " + starts[0].Weekday().String() + " " + starts[0].Format("15:04") + " UTC"
+	case mean >= time.Hour:
+		return fmt.Sprintf("every %.0f hours", mean.Hours())
+	}
+	return fmt.Sprintf("every %.0f minutes", math.Max(1, math.Round(mean.Minutes())))
+}
+
+// Starts on different days, at the same time of the day within dailyTolerance.
+func dailyAt(starts []time.Time) (string, bool) {
+	if starts[len(starts)-1].Sub(starts[0]) < 2*day-dailyTolerance {
+		return "", false
+	}
+	days := make(map[string]bool)
+	// mean time of the day on the circle, handles the starts around midnight
+	var x, y float64
+	for _, s := range starts {
+		days[s.Format(time.DateOnly)] = true
+		angle := 2 * math.Pi * float64(timeOfDay(s)) / float64(day)
+		x += math.Cos(angle)
+		y += math.Sin(angle)
+	}
+	if len(days) < len(starts) {
+		return "", false
+	}
+	angle := math.Atan2(y, x)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	mean := time.Duration(angle / (2 * math.Pi) * float64(day))
+	for _, s := range starts {
+		diff := (timeOfDay(s) - mean + day) % day
+		if diff > day/2 {
+			diff = day - diff
+		}
+		if diff > dailyTolerance {
+			return "", false
+		}
+	}
+	mean = mean.Round(time.Minute)
+	return fmt.Sprintf("%02d:%02d UTC", int(mean.Hours())%24, int(mean.Minutes())%60), true
+}
+
+func timeOfDay(t time.Time) time.Duration {
+	return t.Sub(t.Truncate(day))
+}
+
+// RecurrenceSolution is the solution hint of the recurring or flapping issue, empty if not recurring.
+func RecurrenceSolution(r *Recurrence) string {
+	switch {
+	case r == nil || !r.Recurring:
+		return ""
+	case r.Flapping:
+		return fmt.Sprintf("The issue is flapping, it fired %d times in the last %d hours, %s each time on average. "+
+			"Check the probe thresholds, resource limits and the dependencies which are intermittently unavailable.",
+			r.Occurrences, r.WindowHours, time.Duration(r.DurationSeconds/int64(r.Occurrences))*time.Second)
+	case r.Period != "":
+		return fmt.Sprintf("The issue recurs %s, it fired %d times in the last %d hours. "+
+			"Check the cron jobs, backups, batch workloads or traffic peaks at that time.",
+			r.Period, r.Occurrences, r.WindowHours)
+	}
+	return fmt.Sprintf("The issue is recurring, it fired %d times in the last %d hours, %s in total. "+
+		"Fixing the current occurrence may not prevent the next one, look for the common cause.",
+		r.Occurrences, r.WindowHours, time.Duration(r.DurationSeconds)*time.Second)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurrenceKey(t *testing.T) {
+	key := RecurrenceKey(map[string]string{"alertname": "ContainerWaitingAsCrashLoopBackoff",
+		"namespace": "ns1", "pod": "web-7d9f8b6c5d-x2k4p", "container": "app"})
+	assert.Equal(t, "ContainerWaitingAsCrashLoopBackoff/ns1/web/app/", key)
+	assert.Equal(t, key, RecurrenceKey(map[string]string{"alertname": "ContainerWaitingAsCrashLoopBackoff",
+		"namespace": "ns1", "pod": "web-7d9f8b6c5d-q8z2m", "container": "app"}))
+	assert.Equal(t, "db", workloadName("db-0"))
+	assert.Equal(t, "agent", workloadName("agent-h7x2k"))
+}
+
+func TestComputeRecurrence(t *testing.T) {
+	assert.Nil(t, ComputeRecurrence(nil, 168, 3, 10*time.Minute))
+
+	// crashes every night at 2am for 5 minutes
+	start := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	intervals := make([]Interval, 0)
+	for i := 0; i < 4; i++ {
+		s := start.Add(time.Duration(i)*24*time.Hour + time.Duration(2*i)*time.Minute)
+		intervals = append(intervals, Interval{Start: s, End: s.Add(5 * time.Minute)})
+	}
+	r := ComputeRecurrence(intervals, 168, 3, 10*time.Minute)
+	assert.Equal(t, 4, r.Occurrences)
+	assert.Equal(t, int64(1200), r.DurationSeconds)
+	assert.True(t, r.Recurring)
+	assert.True(t, r.Flapping)
+	assert.Equal(t, "daily around 02:03 UTC", r.Period)
+	assert.Contains(t, RecurrenceSolution(r), "flapping")
+
+	// every 2 hours for an hour
+	intervals = intervals[:0]
+	for i := 0; i < 3; i++ {
+		s := start.Add(time.Duration(i) * 2 * time.Hour)
+		intervals = append(intervals, Interval{Start: s, End: s.Add(time.Hour)})
+	}
+	r = ComputeRecurrence(intervals, 168, 3, 10*time.Minute)
+	assert.False(t, r.Flapping)
+	assert.Equal(t, "every 2 hours", r.Period)
+	assert.Contains(t, RecurrenceSolution(r), "recurs every 2 hours")
+
+	r = ComputeRecurrence(intervals[:2], 168, 3, 10*time.Minute)
+	assert.False(t, r.Recurring)
+	assert.Empty(t, r.Period)
+	assert.Empty(t, RecurrenceSolution(r))
+}
+
+func TestMergeIntervals(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	merged := MergeIntervals([]Interval{
+		{Start: t0.Add(time.Hour), End: t0.Add(2 * time.Hour)},
+		{Start: t0, End: t0.Add(90 * time.Minute)},
+		{Start: t0.Add(3 * time.Hour), End: t0.Add(3 * time.Hour)},
+	})
+	assert.Equal(t, []Interval{
+		{Start: t0, End: t0.Add(2 * time.Hour)},
+		{Start: t0.Add(3 * time.Hour), End: t0.Add(3 * time.Hour)},
+	}, merged)
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Max points of each factor, the score of a report card is between 0 and 100.
+const (
+	severityPoints    = 40.0
+	unavailablePoints = 25.0
+	durationPoints    = 15.0
+	restartPoints     = 10.0
+	trafficPoints     = 10.0
+
+	// problems active longer than this get the full duration points
+	fullDuration = 24 * time.Hour
+	// restarts more than this get the full restart points
+	fullRestarts = 10
+)
+
+var severityWeights = map[Severity]float64{
+	SeverityCritical: 1,
+	SeverityHigh:     0.75,
+	SeverityMedium:   0.5,
+	SeverityLow:      0.25,
+}
+
+// ScoreBreakdown shows the points of each factor, the sum is the card score.
+type ScoreBreakdown struct {
+	Severity    float64 `json:"severity"`
+	Unavailable float64 `json:"unavailable"`
+	Duration    float64 `json:"duration"`
+	Restarts    float64 `json:"restarts"`
+	Traffic     float64 `json:"traffic"`
+}
+
+func (b ScoreBreakdown) total() float64 {
+	return round(b.Severity + b.Unavailable + b.Duration + b.Restarts + b.Traffic)
+}
+
+// Set score and breakdown of the report cards, services are listed once per namespace
+// to check if the card serves traffic.
+func scoreCards(ctx context.Context, cards []*ReportCard, client *kubeclient.KubeClient, now time.Time) {
+	services := make(map[string][]corev1.Service)
+	for _, card := range cards {
+		for _, ns := range cardNamespaces(card) {
+			if _, ok := services[ns]; !ok {
+				services[ns] = listServices(ctx, client, ns)
+			}
+		}
+	}
+	for _, card := range cards {
+		var svcs []corev1.Service
+		for _, ns := range cardNamespaces(card) {
+			svcs = append(svcs, services[ns]...)
+		}
+		breakdown := scoreCard(card, svcs, now)
+		card.ScoreBreakdown = &breakdown
+		card.Score = breakdown.total()
+	}
+}
+
+func scoreCard(card *ReportCard, services []corev1.Service, now time.Time) ScoreBreakdown {
+	breakdown := ScoreBreakdown{}
+
+	severity := 0.0
+	for _, r := range card.Resources {
+		if w := severityWeights[r.Issue.Severity]; w > severity {
+			severity = w
+		}
+	}
+	breakdown.Severity = round(severity * severityPoints)
+
+	objects := cardObjects(card)
+
+	unavailable := 0.0
+	for _, obj := range objects {
+		if desired, available, ok := replicaStatus(obj); ok && desired > 0 {
+			if f := float64(desired-available) / float64(desired); f > unavailable {
+				unavailable = math.Min(f, 1)
+			}
+		}
+	}
+	breakdown.Unavailable = round(unavailable * unavailablePoints)
+
+	var activeAt time.Time
+	for _, p := range card.problems {
+		if !p.ActiveAt.IsZero() && (activeAt.IsZero() || p.ActiveAt.Before(activeAt)) {
+			activeAt = p.ActiveAt
+		}
+	}
+	if !activeAt.IsZero() && now.After(activeAt) {
+		breakdown.Duration = round(math.Min(float64(now.Sub(activeAt))/float64(fullDuration), 1) * durationPoints)
+	}
+
+	var restarts int32
+	for _, obj := range objects {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			if r := podRestarts(pod); r > restarts {
+				restarts = r
+			}
+		}
+	}
+	breakdown.Restarts = round(math.Min(float64(restarts)/fullRestarts, 1) * restartPoints)
+
+	if servesTraffic(objects, services) {
+		breakdown.Traffic = trafficPoints
+	}
+	return breakdown
+}
+
+// Objects of the card, the affected resources and the top resource.
+func cardObjects(card *ReportCard) []interface{} {
+	objects := make([]interface{}, 0, len(card.problems)+1)
+	for _, p := range card.problems {
+		if p.AffectedResources.Resource != nil {
+			objects = append(objects, p.AffectedResources.Resource)
+		}
+	}
+	if card.top != nil {
+		objects = append(objects, card.top)
+	}
+	return objects
+}
+
+func cardNamespaces(card *ReportCard) []string {
+	namespaces := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, p := range card.problems {
+		if obj, ok := p.AffectedResources.Resource.(metav1.Object); ok {
+			if ns := obj.GetNamespace(); ns != "" && !seen[ns] {
+				seen[ns] = true
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	return namespaces
+}
+
+func listServices(ctx context.Context, client *kubeclient.KubeClient, ns string) []corev1.Service {
+	if client == nil {
+		return nil
+	}
+	list := &corev1.ServiceList{}
+	if err := client.List(ctx, list, kubeclient.NamespacedName{Namespace: ns}, metav1.ListOptions{}); err != nil {
+		log.SWithContext(ctx).Warnf("Failed to list services in namespace %s, error is %s", ns, err)
+		return nil
+	}
+	return list.Items
+}
+
+// Returns desired and available replicas of workload resources, ok is false for other resources.
+func replicaStatus(obj interface{}) (desired int32, available int32, ok bool) {
+	if u, isUnstructured := obj.(*unstructured.Unstructured); isUnstructured {
+		obj = toTyped(u)
+	}
+	switch v := obj.(type) {
+	case *appsv1.Deployment:
+		return replicas(v.Spec.Replicas), v.Status.AvailableReplicas, true
+	case *appsv1.StatefulSet:
+		return replicas(v.Spec.Replicas), v.Status.ReadyReplicas, true
+	case *appsv1.ReplicaSet:
+		return replicas(v.Spec.Replicas), v.Status.AvailableReplicas, true
+	case *appsv1.DaemonSet:
+		return v.Status.DesiredNumberScheduled, v.Status.NumberAvailable, true
+	}
+	return 0, 0, false
+}
+
+// Convert the owner resources loaded by dynamic client to typed workload objects.
+func toTyped(u *unstructured.Unstructured) interface{} {
+	var obj interface{}
+	switch u.GetKind() {
+	case "Deployment":
+		obj = &appsv1.Deployment{}
+	case "StatefulSet":
+		obj = &appsv1.StatefulSet{}
+	case "ReplicaSet":
+		obj = &appsv1.ReplicaSet{}
+	case "DaemonSet":
+		obj = &appsv1.DaemonSet{}
+	default:
+		return u
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+		return u
+	}
+	return obj
+}
+
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+func podRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, s := range statuses {
+			if s.RestartCount > restarts {
+				restarts = s.RestartCount
+			}
+		}
+	}
+	return restarts
+}
+
+// Check if the card contains traffic-serving resources, or pods selected by a Service.
+func servesTraffic(objects []interface{}, services []corev1.Service) bool {
+	podLabels := make([]labels.Set, 0)
+	for _, obj := range objects {
+		switch v := obj.(type) {
+		case *corev1.Service, *corev1.Endpoints, *networkv1.Ingress:
+			return true
+		case *corev1.Pod:
+			podLabels = append(podLabels, v.Labels)
+		default:
+			if tpl := podTemplateLabels(v); tpl != nil {
+				podLabels = append(podLabels, tpl)
+			}
+		}
+	}
+	for _, svc := range services {
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		for _, l := range podLabels {
+			if selector.Matches(l) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func podTemplateLabels(obj interface{}) labels.Set {
+	if u, isUnstructured := obj.(*unstructured.Unstructured); isUnstructured {
+		obj = toTyped(u)
+	}
+	switch v := obj.(type) {
+	case *appsv1.Deployment:
+		return v.Spec.Template.Labels
+	case *appsv1.StatefulSet:
+		return v.Spec.Template.Labels
+	case *appsv1.ReplicaSet:
+		return v.Spec.Template.Labels
+	case *appsv1.DaemonSet:
+		return v.Spec.Template.Labels
+	}
+	return nil
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
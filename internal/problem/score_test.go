@@ -0,0 +1,68 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package problem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestScoreCard(t *testing.T) {
+	now := time.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "team-a", Labels: map[string]string{"app": "web"}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "app", RestartCount: 5},
+		}},
+	}
+	replicas := int32(4)
+	deploy := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deploy)
+	assert.NoError(t, err)
+
+	card := &ReportCard{
+		Resources: []*ReportCardResource{{Issue: &ReportCardIssue{Severity: SeverityHigh}}},
+		problems: []*Problem{{
+			ActiveAt:          now.Add(-12 * time.Hour),
+			AffectedResources: ResourceDetails{Resource: pod},
+		}},
+		top: &unstructured.Unstructured{Object: content},
+	}
+	services := []corev1.Service{{Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"}}}}
+
+	breakdown := scoreCard(card, services, now)
+	assert.Equal(t, 30.0, breakdown.Severity)
+	assert.Equal(t, 18.75, breakdown.Unavailable)
+	assert.Equal(t, 7.5, breakdown.Duration)
+	assert.Equal(t, 5.0, breakdown.Restarts)
+	assert.Equal(t, 10.0, breakdown.Traffic)
+	assert.Equal(t, 71.25, breakdown.total())
+
+	// no matching service, no traffic points
+	services[0].Spec.Selector = map[string]string{"app": "api"}
+	assert.Equal(t, 0.0, scoreCard(card, services, now).Traffic)
+}
+
+func TestScoreCardSeverityOrder(t *testing.T) {
+	low := &ReportCard{Resources: []*ReportCardResource{{Issue: &ReportCardIssue{Severity: SeverityLow}}}}
+	critical := &ReportCard{Resources: []*ReportCardResource{
+		{Issue: &ReportCardIssue{Severity: SeverityLow}},
+		{Issue: &ReportCardIssue{Severity: SeverityCritical}},
+	}}
+	assert.Less(t, scoreCard(low, nil, time.Now()).total(), scoreCard(critical, nil, time.Now()).total())
+}
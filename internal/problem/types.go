@@ -5,6 +5,8 @@
  */
 package problem
 
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 type ReportCard struct {
 	Name            string                `json:"name"`
 	RootCause       *ReportCardIssue      `json:"rootCause"`
@@ -12,6 +14,17 @@ type ReportCard struct {
 	TopResourceType string                `json:"topResourceType"`
 	Level           ProblemLevel          `json:"level"`
 	ID              string                `json:"id"`
+	Score           float64               `json:"score"`
+	ScoreBreakdown  *ScoreBreakdown       `json:"scoreBreakdown,omitempty"`
+	// root cause hypotheses sorted by confidence, RootCause is the first one
+	Hypotheses []*Hypothesis `json:"hypotheses,omitempty"`
+	// findings the root cause is derived from, may belong to other report cards
+	ContributingFindings []*Finding `json:"contributingFindings,omitempty"`
+	// window of the historical detection, nil if detected from the current alerts
+	Historical *TimeWindow `json:"historical,omitempty"`
+
+	problems []*Problem
+	top      metav1.Object
 }
 
 type ReportCardIssue struct {
@@ -22,10 +35,26 @@ type ReportCardIssue struct {
 	CreatedTime string            `json:"createdTime,omitempty"`
 	Tags        map[string]string `json:"tags,omitempty"`
 	DomainName  DomainName        `json:"domainName,omitempty"`
-	// Documents   []string   `json:"documents,omitempty"`
+	Severity    Severity          `json:"severity,omitempty"`
+	Code        ErrorCode         `json:"code,omitempty"`
+	Documents   []Document        `json:"documents,omitempty"`
+	AlertState  string            `json:"alertState,omitempty"`
+	// silences and inhibiting alerts, the issue is suppressed in alertmanager
+	SuppressedBy []string `json:"suppressedBy,omitempty"`
+	// how often the issue fired in the recurrence window
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// depth of the finding in the dependency graph, the deepest finding is level 0 and the likely root cause
 	CauseLevel int `json:"causelevel,omitempty"`
 }
 
+// Finding is an issue found on a resource of the dependency graph.
+type Finding struct {
+	CardID   string    `json:"cardId"`
+	Resource string    `json:"resource"`
+	Issue    string    `json:"issue"`
+	Code     ErrorCode `json:"code,omitempty"`
+}
+
 type ReportCardResource struct {
 	Name        string                 `json:"name"`
 	Type        string                 `json:"type"`
@@ -34,7 +63,14 @@ type ReportCardResource struct {
 	Annotations map[string]string      `json:"annotations,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	Issue       *ReportCardIssue       `json:"issue,omitempty"`
-	Deeplink    map[string]string      `json:"deeplink,omitempty"`
+	// number and names of the pods with the same issue under the same controller
+	Count        int               `json:"count,omitempty"`
+	AffectedPods []string          `json:"affectedPods,omitempty"`
+	Deeplink     map[string]string `json:"deeplink,omitempty"`
+	// resource usage over the problem window, summarized from prometheus range queries
+	Metrics []*MetricSummary `json:"metrics,omitempty"`
+	// resources are loaded as they are now, noted if changed or deleted since the historical window
+	HistoricalNote string `json:"historicalNote,omitempty"`
 }
 
 type helmChart struct {
@@ -48,3 +84,21 @@ type ArgoInstance struct {
 	Instance        string
 	RolloutTemplate string
 }
+
+// API groups and kinds of Flux, the groups are also the prefixes of the labels on the applied resources.
+const (
+	FluxHelmGroup      = "helm.toolkit.fluxcd.io"
+	FluxKustomizeGroup = "kustomize.toolkit.fluxcd.io"
+	FluxSourceGroup    = "source.toolkit.fluxcd.io"
+	FluxHelmRelease    = "HelmRelease"
+	FluxKustomization  = "Kustomization"
+	FluxGitRepository  = "GitRepository"
+	FluxHelmRepository = "HelmRepository"
+)
+
+// FluxInstance is the Flux HelmRelease or Kustomization which applied the resource.
+type FluxInstance struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
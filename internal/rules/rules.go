@@ -0,0 +1,187 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fidelity/theliv/internal/investigators"
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Variables of the rule expressions.
+const (
+	VarObject    = "object"
+	VarNamespace = "ns"
+	VarCluster   = "cluster"
+	VarRelated   = "related"
+)
+
+const (
+	defaultSeverity = problem.SeverityMedium
+	defaultDomain   = problem.DomainConfig
+)
+
+// Rule is a compiled custom rule.
+type Rule struct {
+	Name     string
+	Code     problem.ErrorCode
+	Severity problem.Severity
+	Domain   problem.DomainName
+	Target   schema.GroupVersionKind
+	Related  []schema.GroupVersionKind
+	conf     config.CustomRule
+	program  cel.Program
+}
+
+// TemplateData is the data of the description, solution and command templates.
+type TemplateData struct {
+	Object    map[string]interface{}
+	Namespace string
+	Cluster   string
+}
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(
+		cel.Variable(VarObject, cel.DynType),
+		cel.Variable(VarNamespace, cel.StringType),
+		cel.Variable(VarCluster, cel.StringType),
+		cel.Variable(VarRelated, cel.MapType(cel.StringType, cel.ListType(cel.DynType))),
+		ext.Strings(),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Compile checks the rule config and compiles the expression, the expression must return bool.
+func Compile(conf config.CustomRule) (*Rule, error) {
+	if conf.Name == "" || conf.APIVersion == "" || conf.Kind == "" || conf.Expression == "" {
+		return nil, errors.New("name, apiVersion, kind and expression are required")
+	}
+	ast, issues := env.Compile(conf.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression should return bool, not %s", ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	rule := &Rule{
+		Name:     conf.Name,
+		Code:     problem.ErrorCode(conf.Code),
+		Severity: problem.Severity(conf.Severity),
+		Domain:   problem.DomainName(conf.Domain),
+		Target:   schema.FromAPIVersionAndKind(conf.APIVersion, conf.Kind),
+		conf:     conf,
+		program:  program,
+	}
+	if rule.Code == "" {
+		rule.Code = problem.ErrorCode(strings.ToUpper(conf.Name))
+	}
+	if rule.Severity == "" {
+		rule.Severity = defaultSeverity
+	} else if !slices.Contains(problem.Severities, rule.Severity) {
+		return nil, fmt.Errorf("unknown severity %s, should be one of %v", conf.Severity, problem.Severities)
+	}
+	if rule.Domain == "" {
+		rule.Domain = defaultDomain
+	} else if !slices.Contains(problem.Domains, rule.Domain) {
+		return nil, fmt.Errorf("unknown domain %s, should be one of %v", conf.Domain, problem.Domains)
+	}
+	for _, r := range conf.Related {
+		rule.Related = append(rule.Related, schema.FromAPIVersionAndKind(r.APIVersion, r.Kind))
+	}
+	return rule, nil
+}
+
+// IssueType of the rule, registered in the taxonomy.
+func (r *Rule) IssueType() problem.IssueType {
+	return problem.IssueType{Code: r.Code, Domain: r.Domain, Severity: r.Severity}
+}
+
+// Violated evaluates the expression against the object, related objects are keyed by kind.
+func (r *Rule) Violated(obj *unstructured.Unstructured, cluster string, related map[string][]interface{}) (bool, error) {
+	out, _, err := r.program.Eval(map[string]interface{}{
+		VarObject:    obj.Object,
+		VarNamespace: obj.GetNamespace(),
+		VarCluster:   cluster,
+		VarRelated:   related,
+	})
+	if err != nil {
+		return false, err
+	}
+	violated, ok := out.Value().(bool)
+	return ok && violated, nil
+}
+
+// NewProblem builds the problem of the object violating the rule, the templates are rendered with the object.
+func (r *Rule) NewProblem(ctx context.Context, obj *unstructured.Unstructured, cluster string) *problem.Problem {
+	data := TemplateData{Object: obj.Object, Namespace: obj.GetNamespace(), Cluster: cluster}
+	kind := strings.ToLower(obj.GetKind())
+	level := problem.UserNamespace
+	if obj.GetNamespace() == "" {
+		level = problem.Cluster
+	}
+	return &problem.Problem{
+		Name:        r.Name,
+		Code:        r.Code,
+		Description: render(ctx, r.conf.Description, data),
+		Tags: map[string]string{
+			"alertname":      r.Name,
+			com.Namespace:    obj.GetNamespace(),
+			com.Resourcetype: kind,
+			kind:             obj.GetName(),
+		},
+		Level:           level,
+		SolutionDetails: renderAll(ctx, r.conf.Solutions, data),
+		UsefulCommands:  renderAll(ctx, r.conf.Commands, data),
+		Findings:        problem.InitFindings(),
+		AffectedResources: problem.ResourceDetails{
+			ResourceKind: kind,
+			ResourceName: obj.GetName(),
+			Resource:     obj,
+		},
+	}
+}
+
+// The template text is kept if it can't be executed.
+func render(ctx context.Context, text string, data TemplateData) string {
+	if text == "" {
+		return ""
+	}
+	s, err := investigators.ExecGoTemplate(ctx, text, data)
+	if err != nil {
+		return text
+	}
+	return strings.TrimSpace(s)
+}
+
+func renderAll(ctx context.Context, texts []string, data TemplateData) *com.LockedSlice {
+	slice := com.InitLockedSlice()
+	for _, t := range texts {
+		if s := render(ctx, t, data); s != "" {
+			slice.Append(s)
+		}
+	}
+	return slice
+}
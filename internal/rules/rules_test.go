@@ -0,0 +1,115 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deployment(name string, image string, labels map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "prod-web"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": labels},
+			"spec": map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": image},
+			}},
+		}},
+	}}
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile(config.CustomRule{Name: "NoKind", Expression: "true"})
+	assert.NotNil(t, err)
+	_, err = Compile(config.CustomRule{Name: "NotBool", APIVersion: "v1", Kind: "Pod", Expression: "'a'"})
+	assert.NotNil(t, err)
+	_, err = Compile(config.CustomRule{Name: "Invalid", APIVersion: "v1", Kind: "Pod", Expression: "object.("})
+	assert.NotNil(t, err)
+
+	rule, err := Compile(config.CustomRule{Name: "PodRule", APIVersion: "v1", Kind: "Pod", Expression: "true"})
+	assert.Nil(t, err)
+	assert.Equal(t, problem.IssueType{Code: "PODRULE", Domain: problem.DomainConfig, Severity: problem.SeverityMedium},
+		rule.IssueType())
+	assert.Equal(t, "Pod", rule.Target.Kind)
+
+	rule, err = Compile(config.CustomRule{Name: "PvcRule", APIVersion: "v1", Kind: "PersistentVolumeClaim",
+		Expression: "true", Severity: "high", Domain: "storage"})
+	assert.Nil(t, err)
+	assert.Equal(t, problem.DomainStorage, rule.Domain)
+	assert.Equal(t, problem.SeverityHigh, rule.Severity)
+
+	// unknown severity and domain are not added to the taxonomy
+	_, err = Compile(config.CustomRule{Name: "PvcRule", APIVersion: "v1", Kind: "PersistentVolumeClaim",
+		Expression: "true", Domain: "storag"})
+	assert.NotNil(t, err)
+	_, err = Compile(config.CustomRule{Name: "PvcRule", APIVersion: "v1", Kind: "PersistentVolumeClaim",
+		Expression: "true", Severity: "urgent"})
+	assert.NotNil(t, err)
+}
+
+func TestLatestImageRule(t *testing.T) {
+	rule, err := Compile(config.CustomRule{
+		Name:        "DeploymentLatestImage",
+		Code:        "LATEST_IMAGE_ERR",
+		APIVersion:  "apps/v1",
+		Kind:        "Deployment",
+		Expression:  `object.spec.template.spec.containers.exists(c, c.image.endsWith(":latest") || !c.image.contains(":"))`,
+		Severity:    "high",
+		Description: "Deployment {{.Object.metadata.name}} in {{.Namespace}} uses the latest tag.",
+		Solutions:   []string{"Pin the image of deployment {{.Object.metadata.name}} to a version."},
+		Commands:    []string{"kubectl get deployment {{.Object.metadata.name}} -n {{.Namespace}} -o yaml"},
+	})
+	assert.Nil(t, err)
+
+	violated, err := rule.Violated(deployment("web", "nginx:latest", nil), "c1", nil)
+	assert.Nil(t, err)
+	assert.True(t, violated)
+	violated, err = rule.Violated(deployment("web", "nginx:1.25", nil), "c1", nil)
+	assert.Nil(t, err)
+	assert.False(t, violated)
+
+	p := rule.NewProblem(context.Background(), deployment("web", "nginx", nil), "c1")
+	assert.Equal(t, problem.ErrorCode("LATEST_IMAGE_ERR"), p.Code)
+	assert.Equal(t, "Deployment web in prod-web uses the latest tag.", p.Description)
+	assert.Equal(t, []string{"Pin the image of deployment web to a version."}, p.SolutionDetails.GetStore())
+	assert.Equal(t, []string{"kubectl get deployment web -n prod-web -o yaml"}, p.UsefulCommands.GetStore())
+	assert.Equal(t, "deployment", p.Tags["resourcetype"])
+	assert.Equal(t, "web", p.Tags["deployment"])
+	assert.Equal(t, problem.UserNamespace, p.Level)
+	assert.NotNil(t, p.AffectedResources.Resource)
+}
+
+func TestRelatedRule(t *testing.T) {
+	rule, err := Compile(config.CustomRule{
+		Name:       "DeploymentWithoutPDB",
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Related:    []config.RuleTarget{{APIVersion: "policy/v1", Kind: "PodDisruptionBudget"}},
+		Expression: `ns.startsWith("prod") && !related.PodDisruptionBudget.exists(p,
+			p.spec.selector.matchLabels.all(k, k in object.spec.template.metadata.labels &&
+				object.spec.template.metadata.labels[k] == p.spec.selector.matchLabels[k]))`,
+	})
+	assert.Nil(t, err)
+	pdb := map[string]interface{}{"spec": map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+	}}
+	related := map[string][]interface{}{"PodDisruptionBudget": {pdb}}
+
+	violated, err := rule.Violated(deployment("web", "nginx:1.25", map[string]interface{}{"app": "web"}), "c1", related)
+	assert.Nil(t, err)
+	assert.False(t, violated)
+	violated, err = rule.Violated(deployment("api", "nginx:1.25", map[string]interface{}{"app": "api"}), "c1", related)
+	assert.Nil(t, err)
+	assert.True(t, violated)
+}
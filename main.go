@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 
@@ -15,7 +16,9 @@ import (
 	"github.com/fidelity/theliv/pkg/auth/oidcmethod"
 	"github.com/fidelity/theliv/pkg/config"
 	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/notification"
 	"github.com/fidelity/theliv/pkg/router"
+	"github.com/fidelity/theliv/pkg/service"
 )
 
 func main() {
@@ -50,6 +53,13 @@ func main() {
 	// init default logger
 	log.NewDefaultLogger(log.DefaultLogConfig(theliv.LogLevel))
 
+	// org specific checks, only if configured
+	service.LoadCustomRules()
+	// notify the findings of watch mode, only if configured
+	notification.Start()
+	// background watch mode, only if configured
+	service.StartWatch(context.Background())
+
 	err := http.ListenAndServe(fmt.Sprintf(":%v", theliv.Port), r)
 	if err != nil {
 		log.S().Fatalf("Failed to start server, %v", err)
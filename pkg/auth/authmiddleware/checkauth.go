@@ -18,6 +18,7 @@ import (
 const UIDPrefix string = "/theliv/uids/"
 const RolePrefix string = "/theliv/roles/"
 const URLPrefix string = "/theliv-api/v1"
+const DetectionsPath string = "/detections/"
 
 func getRole(ctx context.Context, UID string) ([]string, error) {
 	UID = UIDPrefix + UID
@@ -42,12 +43,21 @@ func getPath(ctx context.Context, role string) ([]string, error) {
 }
 
 func checkRBAC(r *http.Request) (bool, error) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	// stored detections are checked by the handler, with the detector path of the detection
+	if strings.HasPrefix(path, URLPrefix+DetectionsPath) {
+		_, err := GetUser(r, false)
+		return err == nil, err
+	}
+	return CheckPath(r, path)
+}
+
+// CheckPath checks if the user of the request is granted the path, e.g. /theliv-api/v1/detector/c1/ns1/detect.
+func CheckPath(r *http.Request, path string) (bool, error) {
 	user, err := GetUser(r, true)
 	if err != nil {
 		return false, err
 	}
-	path := r.URL.Path
-	path = strings.TrimSuffix(path, "/")
 	skip, err := checkPattern([]string{path}, config.GetThelivConfig().Auth.WhitelistPath[:])
 	if err != nil {
 		return false, err
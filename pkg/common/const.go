@@ -33,9 +33,16 @@ const (
 	PrometheusNotAvailable = "prometheus agent is either uninstalled or currently shut down by FinOps,"
 	LoadResourceFailed     = "failed to load affected resources,"
 	LoadEventsFailed       = "failed to load Kubernetes events in the namespace,"
+	DetectionNotFound      = "detection not found, it may be expired,"
+	LoadDetectionFailed    = "failed to load the detection,"
+	DetectionGroupingDiffs = "report cards of the detections are grouped differently, run the detection again with the same grouping,"
 	UncaughtApiErr         = "error occurred in Theliv API, we will track and fix it soon," + Thanks
 	Contact                = " please contact %s for help." + Thanks
 	Thanks                 = " Thanks for using Theliv!!"
 
 	IngressMisconfigured = "IngressConfigurationError"
+
+	FluxHelmReleaseNotReady   = "FluxHelmReleaseNotReady"
+	FluxKustomizationNotReady = "FluxKustomizationNotReady"
+	FluxSourceNotReady        = "FluxSourceNotReady"
 )
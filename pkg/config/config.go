@@ -22,6 +22,7 @@ type LogDriverType string
 
 const (
 	DriverDatadog       LogDriverType = "datadog"
+	DriverElasticsearch LogDriverType = "elasticsearch"
 	DriverDefaultDriver LogDriverType = "k8s"
 )
 
@@ -56,12 +57,20 @@ type ThelivConfig struct {
 	QPS      float32 `json:"qps,omitempty"`
 	Burst    int     `json:"burst,omitempty"`
 	// Only for file configs
-	ClusterDir          string              `json:"clusterDir,omitempty"`
-	Datadog             *DatadogConfig      `json:"datadog,omitempty"`
-	Auth                *AuthConfig         `json:"auth,omitempty"`
-	Oidc                *OidcConfig         `json:"oidc,omitempty"`
-	Prometheus          *PrometheusConfig   `json:"prometheus,omitempty"`
-	ProblemLevel        *ProblemLevelConfig `json:"problemlevel,omitempty"`
+	ClusterDir          string               `json:"clusterDir,omitempty"`
+	Datadog             *DatadogConfig       `json:"datadog,omitempty"`
+	Elasticsearch       *ElasticsearchConfig `json:"elasticsearch,omitempty"`
+	Auth                *AuthConfig          `json:"auth,omitempty"`
+	Oidc                *OidcConfig          `json:"oidc,omitempty"`
+	Prometheus          *PrometheusConfig    `json:"prometheus,omitempty"`
+	ProblemLevel        *ProblemLevelConfig  `json:"problemlevel,omitempty"`
+	Documents           *DocumentConfig      `json:"documents,omitempty"`
+	Detection           *DetectionConfig     `json:"detection,omitempty"`
+	Watch               *WatchConfig         `json:"watch,omitempty"`
+	Notification        *NotificationConfig  `json:"notification,omitempty"`
+	Alertmanager        *AlertmanagerConfig  `json:"alertmanager,omitempty"`
+	CustomRules         *CustomRulesConfig   `json:"customRules,omitempty"`
+	Grouping            *GroupingConfig      `json:"grouping,omitempty"`
 	Ldap                *LdapConfig
 	LogDriver           LogDriverType `json:"logDriver,omitempty"`
 	EventDriver         LogDriverType `json:"eventDriver,omitempty"`
@@ -93,6 +102,38 @@ func (c *DatadogConfig) ToMaskString() string {
 		c.Index, c.MaxRecords, c.Debug, c.DatadogHost)
 }
 
+// ElasticsearchConfig is used by the elasticsearch event and log driver, works with OpenSearch as well.
+// Either Username/Password or ApiKey can be used for authentication.
+type ElasticsearchConfig struct {
+	Address     string              `json:"address"`
+	Username    string              `json:"username,omitempty"`
+	Password    string              `json:"password,omitempty"`
+	ApiKey      string              `json:"apiKey,omitempty"`
+	EventIndex  string              `json:"eventIndex"`
+	LogIndex    string              `json:"logIndex"`
+	MaxRecords  int                 `json:"maxRecords"`
+	EventFields ElasticsearchFields `json:"eventFields,omitempty"`
+	LogFields   ElasticsearchFields `json:"logFields,omitempty"`
+}
+
+// ElasticsearchFields maps the document fields, nested fields are separated by dot,
+// e.g. kubernetes.namespace_name. Empty fields use the driver defaults.
+type ElasticsearchFields struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Container string `json:"container,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+func (c *ElasticsearchConfig) ToMaskString() string {
+	return fmt.Sprintf("Elasticsearch config: \n Address: %v\n Username: %v\n Password: ***\n ApiKey: ***\n EventIndex: %v\n LogIndex: %v\n MaxRecords: %v\n",
+		c.Address, c.Username, c.EventIndex, c.LogIndex, c.MaxRecords)
+}
+
 type AuthConfig struct {
 	CertPath        string   `json:"certPath"`
 	Cert            []byte   `json:"cert"`
@@ -139,21 +180,279 @@ func (c *AuthConfig) ToMaskString() string {
 		len(c.IDPMetadata), c.IDPMetadataURL, c.MetadataURL, c.AcrURL, c.SloURL, c.EntityID, c.WhitelistPath)
 }
 
+type PrometheusMode string
+
+const (
+	// Kubernetes API server service proxy, with the kubeconfig credentials
+	PrometheusServiceProxy PrometheusMode = "proxy"
+	// Address of Prometheus, Thanos Query or Mimir, with PrometheusAuth
+	PrometheusDirect PrometheusMode = "direct"
+)
+
+// PrometheusConfig is the default endpoint of the clusters without their own endpoint.
 type PrometheusConfig struct {
-	Address   string `json:"address"`
+	PrometheusEndpoint
+	// Range queries attached to report cards, the default queries are used if empty
+	Queries []MetricQuery `json:"queries,omitempty"`
+	// Endpoints by cluster name, for the file config loader. Etcd stores the endpoint with the cluster.
+	Clusters    map[string]*PrometheusEndpoint `json:"clusters,omitempty"`
+	AlertFilter *AlertFilterConfig             `json:"alertFilter,omitempty"`
+}
+
+// AlertFilterConfig drops or flags the alerts before building problems. By default, only the alerts
+// silenced or inhibited in alertmanager are dropped, if alertmanager of the cluster is configured.
+type AlertFilterConfig struct {
+	// Drop pending alerts, otherwise they are flagged with their state
+	DropPending bool `json:"dropPending,omitempty"`
+	// Drop the alerts active for a shorter time
+	MinActiveSeconds int `json:"minActiveSeconds,omitempty"`
+	// Flag the alerts silenced or inhibited in alertmanager, otherwise they are dropped
+	FlagSuppressed bool `json:"flagSuppressed,omitempty"`
+}
+
+type PrometheusEndpoint struct {
+	// Default is proxy
+	Mode    PrometheusMode `json:"mode,omitempty"`
+	Address string         `json:"address"`
+	// Service of the proxy mode
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
 	Port      string `json:"port"`
+	// Scheme of the service, http or https, default is https
+	Scheme string `json:"scheme,omitempty"`
+	// Tenant of Thanos or Mimir, sent in TenantHeader
+	Tenant string `json:"tenant,omitempty"`
+	// Default is X-Scope-OrgID
+	TenantHeader string `json:"tenantHeader,omitempty"`
+	// Label selecting the cluster if the store has several clusters, e.g. cluster
+	ClusterLabel string `json:"clusterLabel,omitempty"`
+	// Value of ClusterLabel, default is the cluster name
+	ClusterLabelValue string          `json:"clusterLabelValue,omitempty"`
+	Auth              *PrometheusAuth `json:"auth,omitempty"`
+	// Alertmanager of the cluster for silences and inhibitions, supports the same modes as Prometheus
+	Alertmanager *PrometheusEndpoint `json:"alertmanager,omitempty"`
+	// Maps the alert labels of rules not following the kube-state-metrics label conventions
+	LabelMapping *LabelMapping `json:"labelMapping,omitempty"`
+}
+
+// LabelMapping renames the alert labels to the labels Theliv uses, e.g. namespace, pod, container and resourcetype,
+// so the existing alert rules can be used unchanged. Alertmanager matching uses the original labels,
+// and the alerts of the cluster are selected by ClusterLabel, e.g. k8s_cluster.
+type LabelMapping struct {
+	// Theliv label to the alert label, e.g. namespace: exported_namespace, pod: kubernetes_pod_name.
+	// The alert label replaces the Theliv label if both are set.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Go templates of the labels not set by the alert, executed with the mapped labels,
+	// e.g. resourcetype: '{{if hasPrefix "Node" .alertname}}node{{end}}'.
+	// Functions are hasPrefix, hasSuffix, contains, match, lower, upper, trimPrefix and trimSuffix.
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// PrometheusAuth is used by the direct mode, independent of the kubeconfig credentials.
+// Bearer token and basic auth are exclusive, bearer token is used if both set.
+type PrometheusAuth struct {
+	BearerToken string `json:"bearerToken,omitempty"`
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	// PEM of the CA, and the client certificate and key for mTLS
+	CA                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+func (e *PrometheusEndpoint) configured() bool {
+	return e.Mode != "" || e.Address != "" || e.Name != ""
+}
+
+// MetricQuery is a PromQL go template, executed with the namespace, pod, container, node and selector of the problem.
+type MetricQuery struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	Unit  string `json:"unit,omitempty"`
+	// Issue codes the query applies to
+	Codes []string `json:"codes"`
 }
 
 type ProblemLevelConfig struct {
 	ManagedNamespaces []string `json:"managednamespaces"`
 }
 
+// DocumentConfig holds the organization documents, e.g. confluence pages or runbooks,
+// keyed by problem error code like IMAGEPULL_ERR.
+type DocumentConfig struct {
+	Links map[string][]DocumentLink `json:"links,omitempty"`
+	// If true, organization documents replace the default kubernetes documents of the same error code
+	ReplaceDefaults bool `json:"replaceDefaults,omitempty"`
+}
+
+type DocumentLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// DetectionConfig controls how long the detection results are kept for sharing.
+type DetectionConfig struct {
+	// Hours to keep the detection results, default is 168 (7 days)
+	RetentionHours int `json:"retentionHours,omitempty"`
+	// If true, detection results are not stored
+	Disabled   bool              `json:"disabled,omitempty"`
+	Recurrence *RecurrenceConfig `json:"recurrence,omitempty"`
+}
+
+type RecurrenceSource string
+
+const (
+	RecurrencePrometheus RecurrenceSource = "prometheus"
+	RecurrenceDetections RecurrenceSource = "detections"
+)
+
+// RecurrenceConfig computes how often each finding fired, to flag the recurring and flapping issues.
+type RecurrenceConfig struct {
+	// If true, recurrence is not computed
+	Disabled bool `json:"disabled,omitempty"`
+	// ALERTS history of prometheus or the stored detections, default is prometheus
+	Source RecurrenceSource `json:"source,omitempty"`
+	// Hours to look back, default is 168 (7 days)
+	WindowHours int `json:"windowHours,omitempty"`
+	// Recurring if fired at least this many times, default is 3
+	MinOccurrences int `json:"minOccurrences,omitempty"`
+	// Recurring issues are flapping if the mean firing duration is shorter, default is 600 seconds
+	FlappingSeconds int `json:"flappingSeconds,omitempty"`
+	// Seconds to reuse the ALERTS history of a namespace, default is 300
+	CacheSeconds int `json:"cacheSeconds,omitempty"`
+}
+
+// WatchConfig enables the background watch mode for the namespaces of registered clusters, and the detect API
+// reads the findings from memory. Detection is re-run when the alerts or node conditions change, changes of the
+// watched objects re-run only the investigators of their problems.
+type WatchConfig struct {
+	Clusters []WatchCluster `json:"clusters,omitempty"`
+	// Informer resync period, default is 600 seconds
+	ResyncSeconds int `json:"resyncSeconds,omitempty"`
+	// Interval to poll Prometheus alerts, default is 60 seconds
+	AlertPollSeconds int `json:"alertPollSeconds,omitempty"`
+	// Detection waits until no change within this period, at most 6 periods, default is 10 seconds
+	DebounceSeconds int `json:"debounceSeconds,omitempty"`
+}
+
+type WatchCluster struct {
+	Name       string   `json:"name"`
+	Namespaces []string `json:"namespaces"`
+}
+
+type NotificationType string
+
+const (
+	NotificationSlack   NotificationType = "slack"
+	NotificationTeams   NotificationType = "teams"
+	NotificationWebhook NotificationType = "webhook"
+	NotificationEmail   NotificationType = "email"
+)
+
+// NotificationConfig pushes the findings of watch mode to the channels matched by the routes.
+type NotificationConfig struct {
+	// Base URL of Theliv UI, used for the links in messages
+	UIAddress string                `json:"uiAddress,omitempty"`
+	Channels  []NotificationChannel `json:"channels,omitempty"`
+	Routes    []NotificationRoute   `json:"routes,omitempty"`
+	// Delivery attempts of a message, default is 3
+	Retries int `json:"retries,omitempty"`
+}
+
+type NotificationChannel struct {
+	Name string           `json:"name"`
+	Type NotificationType `json:"type"`
+	// Incoming webhook URL of slack, teams and generic webhook
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Only for email
+	Smtp *SmtpConfig `json:"smtp,omitempty"`
+	To   []string    `json:"to,omitempty"`
+}
+
+type SmtpConfig struct {
+	Address  string `json:"address"` // host:port
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
+}
+
+// NotificationRoute matches findings by cluster, namespace and severity, empty or "*" matches any.
+type NotificationRoute struct {
+	Cluster    string   `json:"cluster,omitempty"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Severities []string `json:"severities,omitempty"`
+	Channels   []string `json:"channels"`
+}
+
+// CustomRulesConfig holds the checks of the organization, evaluated with CEL against the Kubernetes objects
+// of the detected namespace, e.g. deployments must not use the latest tag.
+type CustomRulesConfig struct {
+	Rules []CustomRule `json:"rules,omitempty"`
+}
+
+// CustomRule reports a problem for each object of the target kind the expression returns true for.
+type CustomRule struct {
+	// Problem name, e.g. DeploymentLatestImage
+	Name string `json:"name"`
+	// Error code of the problem, default is the upper case name
+	Code string `json:"code,omitempty"`
+	// Target kind, e.g. apps/v1 Deployment
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Kinds listed in the namespace for the expression, e.g. policy/v1 PodDisruptionBudget
+	Related []RuleTarget `json:"related,omitempty"`
+	// CEL expression, variables are object, ns (namespace is reserved), cluster and related, related is keyed by kind, e.g.
+	// object.spec.template.spec.containers.exists(c, c.image.endsWith(':latest'))
+	Expression string `json:"expression"`
+	// critical, high, medium or low, default is medium
+	Severity string `json:"severity,omitempty"`
+	// workload, network, storage, node or config, default is config
+	Domain string `json:"domain,omitempty"`
+	// Go templates executed with Object, Namespace and Cluster
+	Description string   `json:"description,omitempty"`
+	Solutions   []string `json:"solutions,omitempty"`
+	Commands    []string `json:"commands,omitempty"`
+}
+
+type RuleTarget struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+}
+
+// AlertmanagerConfig maps the alerts of alertmanager webhook to cluster and namespace.
+type AlertmanagerConfig struct {
+	// Alert label of the cluster name, default is "cluster"
+	ClusterLabel string `json:"clusterLabel,omitempty"`
+	// Alert label of the namespace, default is "namespace"
+	NamespaceLabel string `json:"namespaceLabel,omitempty"`
+	// Cluster of the alerts without cluster label
+	DefaultCluster string `json:"defaultCluster,omitempty"`
+	// Send the detection results to the notification channels
+	Notify bool `json:"notify,omitempty"`
+	// Alerts of a namespace detected within this period are skipped, alertmanager re-sends the firing groups
+	// on every group_interval, default is 300 seconds
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"`
+	// Detections running at the same time, default is 4
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+}
+
+// GroupingConfig selects the strategies to group the problems into report cards, tried in order:
+// argo, flux, helm, owner, or label:<key> to group by the label value, e.g. label:app.kubernetes.io/part-of.
+// The owner chain is the fallback, the grouping parameter of the detect API overrides the config.
+type GroupingConfig struct {
+	// Default is argo, flux, helm, owner
+	Strategies []string `json:"strategies,omitempty"`
+	// Strategies by cluster name
+	Clusters map[string][]string `json:"clusters,omitempty"`
+}
+
 type KubernetesCluster struct {
-	Basic    ClusterBasicInfo `json:"basic"`
-	KubeConf []byte           `json:"kubeconf"`
-	AwsConf  []byte           `json:"awsconf"`
+	Basic      ClusterBasicInfo   `json:"basic"`
+	KubeConf   []byte             `json:"kubeconf"`
+	AwsConf    []byte             `json:"awsconf"`
+	Prometheus PrometheusEndpoint `json:"prometheus"`
 }
 
 // Kubernetes cluster basic information
@@ -175,6 +474,33 @@ func (conf *KubernetesCluster) GetKubeConfig(ctx context.Context) (*restclient.C
 	return client, nil
 }
 
+// GetPrometheusEndpoint returns the prometheus endpoint of the cluster,
+// falls back to the endpoint in prometheus config by cluster name, then the default endpoint.
+func (conf *KubernetesCluster) GetPrometheusEndpoint() *PrometheusEndpoint {
+	if conf.Prometheus.configured() {
+		return &conf.Prometheus
+	}
+	endpoint := conf.sharedPrometheusEndpoint()
+	// the cluster may only set the label mapping of the shared endpoint
+	if conf.Prometheus.LabelMapping != nil {
+		e := *endpoint
+		e.LabelMapping = conf.Prometheus.LabelMapping
+		return &e
+	}
+	return endpoint
+}
+
+func (conf *KubernetesCluster) sharedPrometheusEndpoint() *PrometheusEndpoint {
+	promcfg := thelivConfig.Prometheus
+	if promcfg == nil {
+		return &PrometheusEndpoint{}
+	}
+	if e, ok := promcfg.Clusters[conf.Basic.Name]; ok && e != nil {
+		return e
+	}
+	return &promcfg.PrometheusEndpoint
+}
+
 func (conf *KubernetesCluster) GetAwsConfig(ctx context.Context) *aws.Config {
 	awsconf := &AwsConfig{}
 	err := json.Unmarshal(conf.AwsConf, awsconf)
@@ -35,6 +35,10 @@ func (ecl *EtcdConfigLoader) LoadConfigs() {
 		log.S().Errorf("Failed to load datadog config, error is %v\n", err)
 	}
 
+	if err := ecl.loadElasticsearchConfig(); err != nil {
+		log.S().Errorf("Failed to load elasticsearch config, error is %v\n", err)
+	}
+
 	if err := ecl.loadAuthConfig(); err != nil {
 		log.S().Errorf("Failed to load auth config, error is %v\n", err)
 	}
@@ -54,6 +58,28 @@ func (ecl *EtcdConfigLoader) LoadConfigs() {
 	if err := ecl.loadLdapConfig(); err != nil {
 		log.S().Errorf("Failed to load ldap config, error is %v\n", err)
 	}
+
+	if err := ecl.loadDocumentConfig(); err != nil {
+		log.S().Errorf("Failed to load document config, error is %v\n", err)
+	}
+	if err := ecl.loadDetectionConfig(); err != nil {
+		log.S().Errorf("Failed to load detection config, error is %v\n", err)
+	}
+	if err := ecl.loadWatchConfig(); err != nil {
+		log.S().Errorf("Failed to load watch config, error is %v\n", err)
+	}
+	if err := ecl.loadNotificationConfig(); err != nil {
+		log.S().Errorf("Failed to load notification config, error is %v\n", err)
+	}
+	if err := ecl.loadAlertmanagerConfig(); err != nil {
+		log.S().Errorf("Failed to load alertmanager config, error is %v\n", err)
+	}
+	if err := ecl.loadCustomRulesConfig(); err != nil {
+		log.S().Errorf("Failed to load custom rules config, error is %v\n", err)
+	}
+	if err := ecl.loadGroupingConfig(); err != nil {
+		log.S().Errorf("Failed to load grouping config, error is %v\n", err)
+	}
 }
 
 func (ecl *EtcdConfigLoader) GetKubernetesConfig(ctx context.Context, name string) (*KubernetesCluster, error) {
@@ -142,6 +168,17 @@ func (ecl *EtcdConfigLoader) loadDatadogConfig() error {
 	return nil
 }
 
+func (ecl *EtcdConfigLoader) loadElasticsearchConfig() error {
+	conf := &ElasticsearchConfig{}
+	err := driver.GetObject(driver.ELASTICSEARCH_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Elasticsearch = conf
+	log.S().Infof("Successfully load Elasticsearch config %v\n", conf.ToMaskString())
+	return nil
+}
+
 func (ecl *EtcdConfigLoader) loadOidcConfig() error {
 	conf := &OidcConfig{}
 	err := driver.GetObjectWithSub(context.Background(), driver.OIDC_KEY, conf)
@@ -201,3 +238,80 @@ func (ecl *EtcdConfigLoader) loadLdapConfig() error {
 	log.S().Infof("Successfully load ldap config")
 	return nil
 }
+
+func (ecl *EtcdConfigLoader) loadDocumentConfig() error {
+	conf := &DocumentConfig{}
+	err := driver.GetObject(driver.DOCUMENTS_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Documents = conf
+	log.S().Infof("Successfully load document config, %d error codes configured", len(conf.Links))
+	return nil
+}
+
+func (ecl *EtcdConfigLoader) loadDetectionConfig() error {
+	conf := &DetectionConfig{}
+	err := driver.GetObject(driver.DETECTION_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Detection = conf
+	log.S().Infof("Successfully load detection config, retention is %d hours", conf.RetentionHours)
+	return nil
+}
+
+func (ecl *EtcdConfigLoader) loadWatchConfig() error {
+	conf := &WatchConfig{}
+	err := driver.GetObject(driver.WATCH_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Watch = conf
+	log.S().Infof("Successfully load watch config, %d clusters watched", len(conf.Clusters))
+	return nil
+}
+
+func (ecl *EtcdConfigLoader) loadNotificationConfig() error {
+	conf := &NotificationConfig{}
+	err := driver.GetObject(driver.NOTIFICATION_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Notification = conf
+	log.S().Infof("Successfully load notification config, %d channels and %d routes", len(conf.Channels), len(conf.Routes))
+	return nil
+}
+
+func (ecl *EtcdConfigLoader) loadAlertmanagerConfig() error {
+	conf := &AlertmanagerConfig{}
+	err := driver.GetObject(driver.ALERTMANAGER_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Alertmanager = conf
+	log.S().Info("Successfully load alertmanager config")
+	return nil
+}
+
+func (ecl *EtcdConfigLoader) loadCustomRulesConfig() error {
+	conf := &CustomRulesConfig{}
+	err := driver.GetObject(driver.CUSTOM_RULES_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.CustomRules = conf
+	log.S().Infof("Successfully load custom rules config, %d rules", len(conf.Rules))
+	return nil
+}
+
+func (ecl *EtcdConfigLoader) loadGroupingConfig() error {
+	conf := &GroupingConfig{}
+	err := driver.GetObject(driver.GROUPING_CONFIG_KEY, conf)
+	if err != nil {
+		return err
+	}
+	thelivConfig.Grouping = conf
+	log.S().Infof("Successfully load grouping config")
+	return nil
+}
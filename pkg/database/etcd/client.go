@@ -29,12 +29,22 @@ var (
 const (
 	THELIV_CONFIG_KEY            string = "/theliv/config"
 	DATADOG_CONFIG_KEY           string = "/theliv/config/datadog"
+	ELASTICSEARCH_CONFIG_KEY     string = "/theliv/config/elasticsearch"
 	THELIV_AUTH_KEY              string = "/theliv/config/authconf"
 	OIDC_KEY                     string = "/theliv/config/oidc"
 	CLUSTERS_KEY                 string = "/theliv/clusters"
 	PROMETHEUS_GLOBAL_CONFIG_KEY string = "/theliv/config/prometheus"
 	THELIV_LEVEL_CONFIG_KEY      string = "/theliv/config/levelconf"
 	LDAP_CONFIG_KEY              string = "/theliv/config/ldap"
+	DOCUMENTS_CONFIG_KEY         string = "/theliv/config/documents"
+	DETECTION_CONFIG_KEY         string = "/theliv/config/detection"
+	WATCH_CONFIG_KEY             string = "/theliv/config/watch"
+	NOTIFICATION_CONFIG_KEY      string = "/theliv/config/notification"
+	ALERTMANAGER_CONFIG_KEY      string = "/theliv/config/alertmanager"
+	CUSTOM_RULES_CONFIG_KEY      string = "/theliv/config/customrules"
+	GROUPING_CONFIG_KEY          string = "/theliv/config/grouping"
+	DETECTIONS_KEY               string = "/theliv/detections"
+	DETECTION_INDEX_KEY          string = "/theliv/detectionindex"
 )
 
 // Init client config, could be called only once, before any other functions
@@ -95,6 +105,22 @@ func Put(key string, value interface{}) error {
 	return PutStr(key, string(c))
 }
 
+// Marshall the value (struct) and put to etcd, the key is deleted by etcd after ttl
+func PutWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	c, err := json.Marshal(value)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to marshall %v\n", value)
+		return err
+	}
+	lease, err := client.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to grant lease for %v, error is %v\n", key, err)
+		return err
+	}
+	_, err = client.Put(ctx, key, string(c), clientv3.WithLease(lease.ID))
+	return err
+}
+
 // Get keys only with prefix
 func GetKeys(ctx context.Context, prefix string) ([]string, error) {
 	// client := newClient()
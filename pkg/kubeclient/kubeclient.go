@@ -15,6 +15,7 @@ import (
 	log "github.com/fidelity/theliv/pkg/log"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
@@ -47,7 +48,7 @@ var _ reader = (*KubeClient)(nil)
 type KubeClient struct {
 	dynamicCli  dynamic.Interface
 	discoverCli *discovery.DiscoveryClient
-	mapper      *restmapper.DeferredDiscoveryRESTMapper
+	mapper      meta.RESTMapper
 	scheme      *runtime.Scheme
 }
 
@@ -103,6 +104,15 @@ func NewKubeClient(ctx context.Context, cfg *restclient.Config, opts ...func(*Ku
 
 }
 
+// NewKubeClientFor creates the client of the dynamic client and the REST mapper, e.g. the fakes in tests.
+func NewKubeClientFor(dynamicCli dynamic.Interface, mapper meta.RESTMapper) (*KubeClient, error) {
+	kc := &KubeClient{dynamicCli: dynamicCli, mapper: mapper, scheme: runtime.NewScheme()}
+	if err := clientgoscheme.AddToScheme(kc.scheme); err != nil {
+		return nil, fmt.Errorf("error while trying to add default client-go schemes: %w", err)
+	}
+	return kc, nil
+}
+
 func (kc *KubeClient) Scheme() *runtime.Scheme {
 	return kc.scheme
 }
@@ -132,6 +142,53 @@ func (kc *KubeClient) List(ctx context.Context, obj runtime.Object, resName Name
 	return kc.getResource(ctx, obj, resName, &listOps, GetListResourceName, GetListResource)
 }
 
+// ListUnstructured lists the resources of the kind in the namespace, or all namespaces if namespace is empty.
+// It is used for the custom resources not in the scheme, the error is a meta.IsNoMatchError if the CRD is not installed.
+func (kc *KubeClient) ListUnstructured(ctx context.Context, gvk schema.GroupVersionKind, namespace string,
+	listOps metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	mapping, err := kc.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("unable retrieve the restmapping from mapper: %w", err)
+	}
+	var dr dynamic.ResourceInterface = kc.dynamicCli.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace != "" {
+		dr = kc.dynamicCli.Resource(mapping.Resource).Namespace(namespace)
+	}
+	list, err := dr.List(ctx, listOps)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", RetrieveErrorMessage, err)
+	}
+	return list, nil
+}
+
+// GetUnstructured gets the resource of any kind, the namespace is ignored for the cluster-wide resources.
+func (kc *KubeClient) GetUnstructured(ctx context.Context, gvk schema.GroupVersionKind,
+	resName NamespacedName) (*unstructured.Unstructured, error) {
+	mapping, err := kc.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("unable retrieve the restmapping from mapper: %w", err)
+	}
+	var dr dynamic.ResourceInterface = kc.dynamicCli.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		dr = kc.dynamicCli.Resource(mapping.Resource).Namespace(resName.Namespace)
+	}
+	obj, err := dr.Get(ctx, resName.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", RetrieveErrorMessage, err)
+	}
+	return obj, nil
+}
+
+// KindFor discovers the kind of a resource, singular, plural or qualified with the group,
+// e.g. postgresql, postgresqls or postgresqls.acid.zalan.do.
+func (kc *KubeClient) KindFor(resource string) (schema.GroupVersionKind, error) {
+	gvk, err := kc.mapper.KindFor(schema.ParseGroupResource(strings.ToLower(resource)).WithVersion(""))
+	if err != nil {
+		return gvk, fmt.Errorf("unable retrieve the kind from mapper: %w", err)
+	}
+	return gvk, nil
+}
+
 type GetResourceName func(gvk *schema.GroupVersionKind)
 
 // Return original gvk for single resource.
@@ -51,6 +51,19 @@ var httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 	Buckets: []float64{1, 5, 10, 30, 60},
 }, []string{"method", "path"})
 
+var detectedIssues = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "theliv_detected_issues_total",
+		Help: "Number of issues detected, by error code.",
+	},
+	[]string{"code", "domain", "severity"},
+)
+
+// RecordIssue counts a detected issue, labeled by its stable error code instead of alert name.
+func RecordIssue(code string, domain string, severity string) {
+	detectedIssues.WithLabelValues(code, domain, severity).Inc()
+}
+
 func PrometheusMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -82,4 +95,5 @@ func init() {
 	prometheus.Register(totalRequests)
 	prometheus.Register(responseStatus)
 	prometheus.Register(httpDuration)
+	prometheus.Register(detectedIssues)
 }
@@ -0,0 +1,204 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/watch"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+const smtpTimeout = 30 * time.Second
+
+// slackSender posts to a slack incoming webhook.
+type slackSender struct {
+	url     string
+	headers map[string]string
+}
+
+func (s *slackSender) Send(ctx context.Context, msg *Message) error {
+	text := "*" + msg.Title() + "*\n" + strings.Join(msg.Lines(), "\n")
+	if msg.Link != "" {
+		text += fmt.Sprintf("\n<%s|Open in Theliv>", msg.Link)
+	}
+	return postJSON(ctx, s.url, s.headers, map[string]string{"text": text})
+}
+
+// teamsSender posts a MessageCard to a teams incoming webhook.
+type teamsSender struct {
+	url     string
+	headers map[string]string
+}
+
+type teamsCard struct {
+	Type       string        `json:"@type"`
+	Context    string        `json:"@context"`
+	ThemeColor string        `json:"themeColor"`
+	Summary    string        `json:"summary"`
+	Title      string        `json:"title"`
+	Text       string        `json:"text"`
+	Actions    []teamsAction `json:"potentialAction,omitempty"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+func (s *teamsSender) Send(ctx context.Context, msg *Message) error {
+	card := teamsCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: "D9534F",
+		Summary:    msg.Title(),
+		Title:      msg.Title(),
+		Text:       strings.Join(msg.Lines(), "\n\n"),
+	}
+	if msg.Type == watch.FindingResolved {
+		card.ThemeColor = "5CB85C"
+	}
+	if msg.Link != "" {
+		card.Actions = []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "Open in Theliv",
+			Targets: []teamsTarget{{OS: "default", URI: msg.Link}},
+		}}
+	}
+	return postJSON(ctx, s.url, s.headers, card)
+}
+
+// webhookSender posts the message as JSON.
+type webhookSender struct {
+	url     string
+	headers map[string]string
+}
+
+func (s *webhookSender) Send(ctx context.Context, msg *Message) error {
+	return postJSON(ctx, s.url, s.headers, msg)
+}
+
+// emailSender sends plain text email by SMTP, authenticates if username is configured.
+type emailSender struct {
+	smtp *config.SmtpConfig
+	to   []string
+}
+
+func (s *emailSender) Send(ctx context.Context, msg *Message) error {
+	host := s.smtp.Address
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	var auth smtp.Auth
+	if s.smtp.Username != "" {
+		auth = smtp.PlainAuth("", s.smtp.Username, s.smtp.Password, host)
+	}
+	body := strings.Join(msg.Lines(), "\r\n")
+	if msg.Link != "" {
+		body += "\r\n\r\n" + msg.Link
+	}
+	// cluster, namespace and issue names may be non-ASCII, which is not allowed in headers
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.smtp.From, strings.Join(s.to, ", "), mime.QEncoding.Encode("utf-8", msg.Title()), body)
+	return s.sendMail(ctx, host, auth, []byte(content))
+}
+
+// Same as smtp.SendMail, the connection is closed when ctx is done, and times out if ctx has no deadline.
+func (s *emailSender) sendMail(ctx context.Context, host string, auth smtp.Auth, content []byte) error {
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.smtp.Address)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(s.smtp.From); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(content); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return nil
+}
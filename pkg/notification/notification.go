@@ -0,0 +1,343 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/watch"
+)
+
+const defaultRetries = 3
+
+// Detected is the message type of a detection triggered outside watch mode, e.g. by alertmanager.
+const Detected watch.ChangeType = "detected"
+
+var defaultNotifier *Notifier
+
+// Backoff before the first retry, doubled for each following retry.
+var retryBackoff = time.Second
+
+// Message is the summary of a finding change, rendered by each channel in its own format.
+type Message struct {
+	Type        watch.ChangeType `json:"type"`
+	Cluster     string           `json:"cluster"`
+	Namespace   string           `json:"namespace"`
+	Card        string           `json:"card"`
+	CardType    string           `json:"cardType"`
+	Resource    string           `json:"resource"`
+	Issue       string           `json:"issue"`
+	Code        string           `json:"code,omitempty"`
+	Severity    string           `json:"severity,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Link        string           `json:"link,omitempty"`
+	// Stored detection, only for detected messages
+	DetectionLink string    `json:"detectionLink,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Sender delivers the message to a channel.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Notifier subscribes the finding changes of watch mode, routes them to the configured channels.
+// A finding is sent once when it appears, and once when it resolves.
+type Notifier struct {
+	conf    *config.NotificationConfig
+	senders map[string]Sender
+	mx      sync.Mutex
+	sent    map[string]bool
+	wg      sync.WaitGroup
+}
+
+// Start subscribes the notifier to the watch store, does nothing if notification is not configured.
+func Start() {
+	conf := config.GetThelivConfig().Notification
+	if conf == nil || len(conf.Channels) == 0 {
+		return
+	}
+	n, err := NewNotifier(conf)
+	if err != nil {
+		log.S().Errorf("Failed to start notification, error is %s", err)
+		return
+	}
+	defaultNotifier = n
+	watch.GetStore().Subscribe(n)
+	log.S().Infof("Notification started with %d channels", len(n.senders))
+}
+
+func NewNotifier(conf *config.NotificationConfig) (*Notifier, error) {
+	senders := make(map[string]Sender)
+	for _, ch := range conf.Channels {
+		s, err := NewSender(ch)
+		if err != nil {
+			return nil, err
+		}
+		senders[ch.Name] = s
+	}
+	return &Notifier{conf: conf, senders: senders, sent: make(map[string]bool)}, nil
+}
+
+// NewSender creates the sender of the channel type.
+func NewSender(ch config.NotificationChannel) (Sender, error) {
+	switch ch.Type {
+	case config.NotificationSlack:
+		return &slackSender{url: ch.URL, headers: ch.Headers}, nil
+	case config.NotificationTeams:
+		return &teamsSender{url: ch.URL, headers: ch.Headers}, nil
+	case config.NotificationWebhook:
+		return &webhookSender{url: ch.URL, headers: ch.Headers}, nil
+	case config.NotificationEmail:
+		if ch.Smtp == nil || len(ch.To) == 0 {
+			return nil, fmt.Errorf("smtp and recipients are required by email channel %s", ch.Name)
+		}
+		return &emailSender{smtp: ch.Smtp, to: ch.To}, nil
+	}
+	return nil, fmt.Errorf("unknown type %s of notification channel %s", ch.Type, ch.Name)
+}
+
+func (n *Notifier) OnFindingChange(ctx context.Context, change watch.FindingChange) {
+	msg := n.newMessage(change)
+	for _, name := range n.route(change.Cluster, change.Namespace, msg.Severity) {
+		sender, ok := n.senders[name]
+		if !ok {
+			log.SWithContext(ctx).Warnf("Notification channel %s not found", name)
+			continue
+		}
+		key := strings.Join([]string{name, change.Cluster, change.Namespace, change.Finding.Fingerprint}, "/")
+		if !n.mark(key, change.Type) {
+			continue
+		}
+		n.wg.Add(1)
+		go n.deliver(ctx, name, key, sender, msg)
+	}
+}
+
+// NotifyDetection sends a message for each report card of the detection, does nothing if notification is not
+// started. A card is sent once while it is in the detections of the namespace, it is sent again if it disappears
+// from a detection then comes back.
+func NotifyDetection(ctx context.Context, cluster string, namespace string, detectionID string,
+	cards []*problem.ReportCard) {
+	if defaultNotifier != nil {
+		defaultNotifier.NotifyDetection(ctx, cluster, namespace, detectionID, cards)
+	}
+}
+
+func (n *Notifier) NotifyDetection(ctx context.Context, cluster string, namespace string, detectionID string,
+	cards []*problem.ReportCard) {
+	current := make(map[string]bool)
+	for _, card := range cards {
+		fingerprint := problem.CardFingerprint(card)
+		current[fingerprint] = true
+		msg := &Message{
+			Type:      Detected,
+			Cluster:   cluster,
+			Namespace: namespace,
+			Card:      card.Name,
+			CardType:  card.TopResourceType,
+			Resource:  card.Name,
+			Link:      Link(n.conf.UIAddress, cluster, namespace),
+			Time:      time.Now(),
+		}
+		if detectionID != "" && n.conf.UIAddress != "" {
+			msg.DetectionLink = strings.TrimSuffix(n.conf.UIAddress, "/") + "/theliv-api/v1/detections/" + detectionID
+		}
+		if issue := card.RootCause; issue != nil {
+			msg.Issue = issue.Name
+			msg.Code = string(issue.Code)
+			msg.Severity = string(issue.Severity)
+			msg.Description = issue.Description
+		}
+		for _, name := range n.route(cluster, namespace, msg.Severity) {
+			sender, ok := n.senders[name]
+			if !ok {
+				continue
+			}
+			key := detectedKey(cluster, namespace, fingerprint, name)
+			if !n.mark(key, Detected) {
+				continue
+			}
+			n.wg.Add(1)
+			go n.deliver(ctx, name, key, sender, msg)
+		}
+	}
+	n.forgetDetected(cluster, namespace, current)
+}
+
+// Key of a sent card, the card fingerprint may contain "/", the channel name is the last part.
+func detectedKey(cluster string, namespace string, fingerprint string, channel string) string {
+	return strings.Join([]string{string(Detected), cluster, namespace, fingerprint, channel}, "/")
+}
+
+// Forgets the sent cards of the namespace which are not in the current detection.
+func (n *Notifier) forgetDetected(cluster string, namespace string, current map[string]bool) {
+	prefix := strings.Join([]string{string(Detected), cluster, namespace}, "/") + "/"
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	for key := range n.sent {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if i := strings.LastIndex(rest, "/"); i >= 0 && !current[rest[:i]] {
+			delete(n.sent, key)
+		}
+	}
+}
+
+func (n *Notifier) Wait() {
+	n.wg.Wait()
+}
+
+// Channels of all the routes matching the cluster, namespace and severity, without duplicates.
+func (n *Notifier) route(cluster string, namespace string, severity string) []string {
+	channels := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, r := range n.conf.Routes {
+		if !matches(r.Cluster, cluster) || !matches(r.Namespace, namespace) || !matchesSeverity(r.Severities, severity) {
+			continue
+		}
+		for _, ch := range r.Channels {
+			if !seen[ch] {
+				seen[ch] = true
+				channels = append(channels, ch)
+			}
+		}
+	}
+	return channels
+}
+
+// Returns false if the change should not be sent, i.e. the finding is already sent and still persists,
+// or a resolved finding was never sent.
+func (n *Notifier) mark(key string, t watch.ChangeType) bool {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if t == watch.FindingResolved {
+		if !n.sent[key] {
+			return false
+		}
+		delete(n.sent, key)
+		return true
+	}
+	if n.sent[key] {
+		return false
+	}
+	n.sent[key] = true
+	return true
+}
+
+func (n *Notifier) deliver(ctx context.Context, name string, key string, sender Sender, msg *Message) {
+	defer n.wg.Done()
+	retries := n.conf.Retries
+	if retries <= 0 {
+		retries = defaultRetries
+	}
+	backoff := retryBackoff
+	var err error
+	for attempt := 1; attempt <= retries; attempt++ {
+		if err = sender.Send(ctx, msg); err == nil {
+			return
+		}
+		log.SWithContext(ctx).Warnf("Failed to send notification to %s, attempt %d, error is %s", name, attempt, err)
+		if attempt == retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			attempt = retries
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+	log.SWithContext(ctx).Errorf("Gave up sending notification to %s, error is %s", name, err)
+	// not sent, the finding can be sent again
+	if msg.Type == watch.FindingAppeared || msg.Type == Detected {
+		n.mx.Lock()
+		delete(n.sent, key)
+		n.mx.Unlock()
+	}
+}
+
+func (n *Notifier) newMessage(change watch.FindingChange) *Message {
+	msg := &Message{
+		Type:      change.Type,
+		Cluster:   change.Cluster,
+		Namespace: change.Namespace,
+		Card:      change.Card.Name,
+		CardType:  change.Card.TopResourceType,
+		Resource:  change.Finding.Resource,
+		Link:      Link(n.conf.UIAddress, change.Cluster, change.Namespace),
+		Time:      change.Time,
+	}
+	if issue := change.Finding.Issue; issue != nil {
+		msg.Issue = issue.Name
+		msg.Code = string(issue.Code)
+		msg.Severity = string(issue.Severity)
+		msg.Description = issue.Description
+	}
+	return msg
+}
+
+// Link is the UI page of the namespace, empty if the UI address is not configured.
+func Link(uiAddress string, cluster string, namespace string) string {
+	if uiAddress == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/#/kubernetes?cluster=%s&namespace=%s", strings.TrimSuffix(uiAddress, "/"),
+		url.QueryEscape(cluster), url.QueryEscape(namespace))
+}
+
+// Title is the one line summary of the message.
+func (m *Message) Title() string {
+	state := "New finding"
+	switch m.Type {
+	case watch.FindingResolved:
+		state = "Resolved"
+	case Detected:
+		state = "Detected"
+	}
+	return fmt.Sprintf("[Theliv] %s: %s in %s/%s", state, m.Issue, m.Cluster, m.Namespace)
+}
+
+// Lines are the details of the message, without title.
+func (m *Message) Lines() []string {
+	lines := []string{fmt.Sprintf("%s %s, resource %s", m.CardType, m.Card, m.Resource)}
+	if m.Severity != "" || m.Code != "" {
+		lines = append(lines, fmt.Sprintf("Severity: %s, code: %s", m.Severity, m.Code))
+	}
+	if m.Description != "" {
+		lines = append(lines, m.Description)
+	}
+	if m.DetectionLink != "" {
+		lines = append(lines, "Detection: "+m.DetectionLink)
+	}
+	return lines
+}
+
+func matches(pattern string, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
+
+func matchesSeverity(severities []string, severity string) bool {
+	if len(severities) == 0 {
+		return true
+	}
+	for _, s := range severities {
+		if s == "*" || strings.EqualFold(s, severity) {
+			return true
+		}
+	}
+	return false
+}
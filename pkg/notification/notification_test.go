@@ -0,0 +1,262 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package notification
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/watch"
+	"github.com/stretchr/testify/assert"
+)
+
+func newChange(t watch.ChangeType, severity problem.Severity) watch.FindingChange {
+	return watch.FindingChange{
+		Type:      t,
+		Cluster:   "c1",
+		Namespace: "ns1",
+		Card:      &problem.CardDiff{Name: "web", TopResourceType: "Deployment"},
+		Finding: &problem.IssueDiff{Fingerprint: "Deployment/web/CrashLoopError/app", Resource: "web-1",
+			Issue: &problem.ReportCardIssue{Name: "CrashLoopBackOff", Code: problem.CrashLoopErr, Severity: severity}},
+		Time: time.Now(),
+	}
+}
+
+func TestNotifierDedupAndRetry(t *testing.T) {
+	retryBackoff = time.Millisecond
+	var mx sync.Mutex
+	received := make([]Message, 0)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		calls++
+		// the first attempt fails
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		msg := Message{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&msg))
+		assert.Equal(t, "token", r.Header.Get("X-Token"))
+		received = append(received, msg)
+	}))
+	defer server.Close()
+
+	n, err := NewNotifier(&config.NotificationConfig{
+		UIAddress: "https://theliv.example.com/",
+		Channels: []config.NotificationChannel{{Name: "hook", Type: config.NotificationWebhook, URL: server.URL,
+			Headers: map[string]string{"X-Token": "token"}}},
+		Routes: []config.NotificationRoute{
+			{Cluster: "c1", Namespace: "*", Severities: []string{"critical"}, Channels: []string{"hook"}},
+			{Cluster: "c2", Channels: []string{"hook"}},
+		},
+	})
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	n.OnFindingChange(ctx, newChange(watch.FindingAppeared, problem.SeverityCritical))
+	n.Wait()
+	// persisting finding is not sent again
+	n.OnFindingChange(ctx, newChange(watch.FindingAppeared, problem.SeverityCritical))
+	n.Wait()
+	// not routed
+	low := newChange(watch.FindingAppeared, problem.SeverityLow)
+	low.Finding.Fingerprint = "Deployment/web/OOMKilledError/app"
+	n.OnFindingChange(ctx, low)
+	n.OnFindingChange(ctx, newChange(watch.FindingResolved, problem.SeverityCritical))
+	n.Wait()
+
+	assert.Equal(t, 3, calls)
+	assert.Len(t, received, 2)
+	assert.Equal(t, watch.FindingAppeared, received[0].Type)
+	assert.Equal(t, "https://theliv.example.com/#/kubernetes?cluster=c1&namespace=ns1", received[0].Link)
+	assert.Equal(t, watch.FindingResolved, received[1].Type)
+}
+
+func TestSlackAndTeams(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+	}))
+	defer server.Close()
+	msg := &Message{Type: watch.FindingAppeared, Cluster: "c1", Namespace: "ns1", Issue: "CrashLoopBackOff",
+		Link: "https://theliv/#/kubernetes?cluster=c1&namespace=ns1"}
+
+	for _, typ := range []config.NotificationType{config.NotificationSlack, config.NotificationTeams} {
+		s, err := NewSender(config.NotificationChannel{Name: string(typ), Type: typ, URL: server.URL})
+		assert.Nil(t, err)
+		assert.Nil(t, s.Send(context.Background(), msg))
+	}
+	slack := <-bodies
+	assert.Contains(t, slack["text"], "[Theliv] New finding: CrashLoopBackOff in c1/ns1")
+	assert.Contains(t, slack["text"], "|Open in Theliv>")
+	teams := <-bodies
+	assert.Equal(t, "MessageCard", teams["@type"])
+	assert.Len(t, teams["potentialAction"], 1)
+}
+
+func TestEmail(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	data := make(chan string, 1)
+	go serveSMTP(listener, data)
+
+	s, err := NewSender(config.NotificationChannel{Name: "mail", Type: config.NotificationEmail,
+		Smtp: &config.SmtpConfig{Address: listener.Addr().String(), From: "theliv@example.com"},
+		To:   []string{"oncall@example.com"}})
+	assert.Nil(t, err)
+	msg := &Message{Type: watch.FindingResolved, Cluster: "c1", Namespace: "ns1", Issue: "CrashLoopBackOff"}
+	assert.Nil(t, s.Send(context.Background(), msg))
+	assert.Contains(t, <-data, "Subject: [Theliv] Resolved: CrashLoopBackOff in c1/ns1")
+
+	_, err = NewSender(config.NotificationChannel{Name: "mail", Type: config.NotificationEmail})
+	assert.NotNil(t, err)
+}
+
+func TestEmailEncodedSubject(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	data := make(chan string, 1)
+	go serveSMTP(listener, data)
+
+	s, err := NewSender(config.NotificationChannel{Name: "mail", Type: config.NotificationEmail,
+		Smtp: &config.SmtpConfig{Address: listener.Addr().String(), From: "theliv@example.com"},
+		To:   []string{"oncall@example.com"}})
+	assert.Nil(t, err)
+	msg := &Message{Type: watch.FindingResolved, Cluster: "集群", Namespace: "ns1", Issue: "CrashLoopBackOff"}
+	assert.Nil(t, s.Send(context.Background(), msg))
+	subject := "Subject: " + mime.QEncoding.Encode("utf-8", msg.Title()) + "\r\n"
+	assert.Contains(t, <-data, subject)
+	assert.True(t, strings.HasPrefix(subject, "Subject: =?utf-8?q?"))
+}
+
+func TestEmailCancelled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	// accepts the connection but never replies
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	s, err := NewSender(config.NotificationChannel{Name: "mail", Type: config.NotificationEmail,
+		Smtp: &config.SmtpConfig{Address: listener.Addr().String(), From: "theliv@example.com"},
+		To:   []string{"oncall@example.com"}})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.NotNil(t, s.Send(ctx, &Message{Type: watch.FindingResolved, Cluster: "c1", Namespace: "ns1"}))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// Minimal SMTP server, accepts one mail and sends its data to the channel.
+func serveSMTP(listener net.Listener, data chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case cmd == "DATA":
+			reply("354 end with .")
+			var sb strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				sb.WriteString(l)
+			}
+			data <- sb.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestNotifyDetection(t *testing.T) {
+	received := make(chan Message, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := Message{}
+		json.NewDecoder(r.Body).Decode(&msg)
+		received <- msg
+	}))
+	defer server.Close()
+
+	n, err := NewNotifier(&config.NotificationConfig{
+		UIAddress: "https://theliv.example.com",
+		Channels:  []config.NotificationChannel{{Name: "hook", Type: config.NotificationWebhook, URL: server.URL}},
+		Routes:    []config.NotificationRoute{{Severities: []string{"high"}, Channels: []string{"hook"}}},
+	})
+	assert.Nil(t, err)
+	n.NotifyDetection(context.Background(), "c1", "ns1", "abc", []*problem.ReportCard{
+		{Name: "web", TopResourceType: "Deployment",
+			RootCause: &problem.ReportCardIssue{Name: "CrashLoopBackOff", Severity: problem.SeverityHigh}},
+		{Name: "db", TopResourceType: "StatefulSet",
+			RootCause: &problem.ReportCardIssue{Name: "PodNotReady", Severity: problem.SeverityLow}},
+	})
+	n.Wait()
+
+	assert.Len(t, received, 1)
+	msg := <-received
+	assert.Equal(t, Detected, msg.Type)
+	assert.Equal(t, "web", msg.Card)
+	assert.Equal(t, "https://theliv.example.com/theliv-api/v1/detections/abc", msg.DetectionLink)
+
+	web := []*problem.ReportCard{{Name: "web", TopResourceType: "Deployment",
+		RootCause: &problem.ReportCardIssue{Name: "CrashLoopBackOff", Severity: problem.SeverityHigh}}}
+	// the card is sent already
+	n.NotifyDetection(context.Background(), "c1", "ns1", "def", web)
+	n.Wait()
+	assert.Empty(t, received)
+	// other namespaces are not affected
+	n.NotifyDetection(context.Background(), "c1", "ns2", "ghi", web)
+	n.Wait()
+	assert.Len(t, received, 1)
+	<-received
+	// sent again after it disappeared from a detection
+	n.NotifyDetection(context.Background(), "c1", "ns1", "jkl", nil)
+	n.NotifyDetection(context.Background(), "c1", "ns1", "mno", web)
+	n.Wait()
+	assert.Len(t, received, 1)
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fidelity/theliv/pkg/config"
+)
+
+const (
+	defaultMaxRecords = 500
+	searchTimeout     = 10 * time.Second
+)
+
+// Default event fields, match the Event objects shipped by Fluent Bit kubernetes_events input.
+var defaultEventFields = config.ElasticsearchFields{
+	Namespace: "involvedObject.namespace",
+	Name:      "involvedObject.name",
+	Kind:      "involvedObject.kind",
+	Reason:    "reason",
+	Type:      "type",
+	Message:   "message",
+	Timestamp: "@timestamp",
+}
+
+// Default log fields, match the records enriched by Fluent Bit kubernetes filter.
+var defaultLogFields = config.ElasticsearchFields{
+	Namespace: "kubernetes.namespace_name",
+	Name:      "kubernetes.pod_name",
+	Container: "kubernetes.container_name",
+	Message:   "log",
+	Timestamp: "@timestamp",
+}
+
+var httpClient = &http.Client{Timeout: searchTimeout}
+
+// searchClient calls the _search API of Elasticsearch or OpenSearch.
+type searchClient struct {
+	address    string
+	username   string
+	password   string
+	apiKey     string
+	maxRecords int
+}
+
+type searchResponse struct {
+	Hits struct {
+		Hits []struct {
+			ID     string                 `json:"_id"`
+			Source map[string]interface{} `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func newSearchClient(conf *config.ElasticsearchConfig) searchClient {
+	max := conf.MaxRecords
+	if max <= 0 {
+		max = defaultMaxRecords
+	}
+	return searchClient{
+		address:    strings.TrimSuffix(conf.Address, "/"),
+		username:   conf.Username,
+		password:   conf.Password,
+		apiKey:     conf.ApiKey,
+		maxRecords: max,
+	}
+}
+
+// Build the bool query, all the non-empty terms are matched as phrase,
+// documents are sorted by timestamp descending.
+func buildQuery(terms map[string]string, timestampField string, start time.Time, end time.Time, size int) map[string]interface{} {
+	filters := make([]interface{}, 0)
+	for field, value := range terms {
+		if field == "" || value == "" {
+			continue
+		}
+		filters = append(filters, map[string]interface{}{
+			"match_phrase": map[string]interface{}{field: value},
+		})
+	}
+	timeRange := map[string]interface{}{}
+	if !start.IsZero() {
+		timeRange["gte"] = start.UTC().Format(time.RFC3339)
+	}
+	if !end.IsZero() {
+		timeRange["lte"] = end.UTC().Format(time.RFC3339)
+	}
+	if len(timeRange) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{timestampField: timeRange},
+		})
+	}
+	return map[string]interface{}{
+		"size": size,
+		"sort": []interface{}{
+			map[string]interface{}{timestampField: map[string]string{"order": "desc"}},
+		},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filters},
+		},
+	}
+}
+
+func (c searchClient) search(ctx context.Context, index string, query map[string]interface{}) (*searchResponse, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+"/"+index+"/_search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call elasticsearch search API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("elasticsearch search API returned %d: %s", resp.StatusCode, string(msg))
+	}
+	result := &searchResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to decode elasticsearch response: %w", err)
+	}
+	return result, nil
+}
+
+// Get the string value of field from document source, nested field separated by dot.
+// Flattened keys, e.g. "kubernetes.pod_name" as a single key, are also supported.
+func getField(source map[string]interface{}, field string) string {
+	if field == "" {
+		return ""
+	}
+	if v, ok := source[field]; ok {
+		return toString(v)
+	}
+	var current interface{} = source
+	for _, part := range strings.Split(field, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		if current, ok = m[part]; !ok {
+			return ""
+		}
+	}
+	return toString(current)
+}
+
+func getTime(source map[string]interface{}, field string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, getField(source, field))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
+
+// Overrides the default fields with the configured ones.
+func mergeFields(defaults config.ElasticsearchFields, fields config.ElasticsearchFields) config.ElasticsearchFields {
+	merge := func(d string, f string) string {
+		if f != "" {
+			return f
+		}
+		return d
+	}
+	return config.ElasticsearchFields{
+		Namespace: merge(defaults.Namespace, fields.Namespace),
+		Name:      merge(defaults.Name, fields.Name),
+		Kind:      merge(defaults.Kind, fields.Kind),
+		Container: merge(defaults.Container, fields.Container),
+		Reason:    merge(defaults.Reason, fields.Reason),
+		Type:      merge(defaults.Type, fields.Type),
+		Message:   merge(defaults.Message, fields.Message),
+		Timestamp: merge(defaults.Timestamp, fields.Timestamp),
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/observability"
+	"github.com/stretchr/testify/assert"
+)
+
+const eventHits = `{"hits":{"hits":[{"_id":"1","_source":{
+	"@timestamp":"2024-01-01T10:00:00Z",
+	"involvedObject":{"name":"web-1","namespace":"team-a","kind":"Pod"},
+	"reason":"BackOff","type":"Warning","message":"Back-off restarting failed container"}}]}}`
+
+const logHits = `{"hits":{"hits":[{"_id":"1","_source":{
+	"time":"2024-01-01T10:00:00.123Z",
+	"kubernetes.namespace_name":"team-a","kubernetes.pod_name":"web-1","kubernetes.container_name":"app",
+	"log":"panic: runtime error"}}]}}`
+
+func newStub(t *testing.T, response string, check func(r *http.Request, query map[string]interface{})) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+		check(r, query)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestEsEventRetriever(t *testing.T) {
+	server := newStub(t, eventHits, func(r *http.Request, query map[string]interface{}) {
+		assert.Equal(t, "/k8s-events-*/_search", r.URL.Path)
+		assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"))
+		filters := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+		// namespace, name and time range
+		assert.Len(t, filters, 3)
+	})
+	defer server.Close()
+
+	retriever := NewEsEventRetriever(&config.ElasticsearchConfig{
+		Address:    server.URL,
+		ApiKey:     "secret",
+		EventIndex: "k8s-events-*",
+	})
+	now := time.Now()
+	events, err := retriever.Retrieve(observability.EventFilterCriteria{
+		FilterCriteria: retriever.AddFilters("web-1", "team-a"),
+		StartTime:      now.Add(-time.Hour),
+		EndTime:        now,
+	}).GetEvents(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "BackOff", events[0].Reason)
+	assert.Equal(t, "Warning", events[0].Type)
+	assert.Equal(t, "web-1", events[0].InvolvedObject["name"])
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), events[0].LastTimestamp)
+}
+
+func TestEsLogRetriever(t *testing.T) {
+	server := newStub(t, logHits, func(r *http.Request, query map[string]interface{}) {
+		assert.Equal(t, "/k8s-logs/_search", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "theliv", user)
+		assert.Equal(t, "pwd", pass)
+		assert.Equal(t, float64(10), query["size"])
+	})
+	defer server.Close()
+
+	retriever := NewEsLogRetriever(&config.ElasticsearchConfig{
+		Address:    server.URL,
+		Username:   "theliv",
+		Password:   "pwd",
+		LogIndex:   "k8s-logs",
+		MaxRecords: 10,
+		LogFields:  config.ElasticsearchFields{Timestamp: "time"},
+	})
+	logs, err := retriever.Retrieve(observability.LogFilterCriteria{
+		FilterCriteria: retriever.AddFilters("web-1", "app", "team-a"),
+	}).GetLogs(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "panic: runtime error", logs[0].Message)
+	assert.Equal(t, "app", logs[0].Container)
+	assert.False(t, logs[0].Timestamp.IsZero())
+}
+
+func TestEsSearchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "index_not_found_exception", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	retriever := NewEsEventRetriever(&config.ElasticsearchConfig{Address: server.URL, EventIndex: "missing"})
+	_, err := retriever.Retrieve(observability.EventFilterCriteria{}).GetEvents(context.Background())
+	assert.ErrorContains(t, err, "404")
+}
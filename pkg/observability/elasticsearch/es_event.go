@@ -0,0 +1,83 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package elasticsearch
+
+import (
+	"context"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	observability "github.com/fidelity/theliv/pkg/observability"
+)
+
+var _ observability.EventRetriever = EsEventRetriever{}
+
+type EsEventRetriever struct {
+	client searchClient
+	index  string
+	fields config.ElasticsearchFields
+}
+
+type EsEventDataRef struct {
+	EsEventRetriever
+	observability.EventFilterCriteria
+}
+
+// Return the instance of EventDataRef, with search client, and filtering conditions set.
+func (eventReceiver EsEventRetriever) Retrieve(filterCriteria observability.EventFilterCriteria) observability.EventDataRef {
+	return EsEventDataRef{eventReceiver, filterCriteria}
+}
+
+// Search events from the event index, filtered by namespace, resource name and time window.
+func (dataRef EsEventDataRef) GetEvents(ctx context.Context) ([]observability.EventRecord, error) {
+	fields := dataRef.fields
+	terms := map[string]string{
+		fields.Namespace: dataRef.FilterCriteria[com.Namespace],
+		fields.Name:      dataRef.FilterCriteria[com.Name],
+	}
+	query := buildQuery(terms, fields.Timestamp, dataRef.StartTime, dataRef.EndTime, dataRef.client.maxRecords)
+	res, err := dataRef.client.search(ctx, dataRef.index, query)
+	if err != nil {
+		return nil, err
+	}
+
+	eventRecord := make([]observability.EventRecord, 0, len(res.Hits.Hits))
+	for _, hit := range res.Hits.Hits {
+		source := hit.Source
+		timestamp := getTime(source, fields.Timestamp)
+		eventRecord = append(eventRecord,
+			observability.EventRecord{
+				EventId: hit.ID,
+				Title:   getField(source, fields.Name),
+				Message: getField(source, fields.Message),
+				Reason:  getField(source, fields.Reason),
+				InvolvedObject: map[string]string{
+					com.Name:      getField(source, fields.Name),
+					com.Namespace: getField(source, fields.Namespace),
+					"Kind":        getField(source, fields.Kind),
+				},
+				Source:        map[string]string{},
+				DateHappened:  timestamp,
+				Type:          getField(source, fields.Type),
+				LastTimestamp: timestamp,
+			})
+	}
+	return eventRecord, nil
+}
+
+// Default filter, add k8s resource Name and Namespace.
+func (eventReceiver EsEventRetriever) AddFilters(name string, namespace string) map[string]string {
+	return map[string]string{com.Name: name, com.Namespace: namespace}
+}
+
+// New for EsEventRetriever, fields not configured use the Fluent Bit defaults.
+func NewEsEventRetriever(conf *config.ElasticsearchConfig) EsEventRetriever {
+	return EsEventRetriever{
+		client: newSearchClient(conf),
+		index:  conf.EventIndex,
+		fields: mergeFields(defaultEventFields, conf.EventFields),
+	}
+}
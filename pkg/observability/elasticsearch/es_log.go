@@ -0,0 +1,75 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package elasticsearch
+
+import (
+	"context"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	observability "github.com/fidelity/theliv/pkg/observability"
+)
+
+var _ observability.LogRetriever = EsLogRetriever{}
+
+type EsLogRetriever struct {
+	client searchClient
+	index  string
+	fields config.ElasticsearchFields
+}
+
+type EsLogDataRef struct {
+	EsLogRetriever
+	observability.LogFilterCriteria
+}
+
+// Return the instance of LogDataRef, with search client, and filtering conditions set.
+func (logReceiver EsLogRetriever) Retrieve(filterCriteria observability.LogFilterCriteria) observability.LogDataRef {
+	return EsLogDataRef{logReceiver, filterCriteria}
+}
+
+// Search container logs from the log index, filtered by namespace, pod, container and time window.
+// Latest logs are returned first.
+func (dataRef EsLogDataRef) GetLogs(ctx context.Context) ([]observability.LogRecord, error) {
+	fields := dataRef.fields
+	terms := map[string]string{
+		fields.Namespace: dataRef.FilterCriteria[com.Namespace],
+		fields.Name:      dataRef.FilterCriteria[com.Pod],
+		fields.Container: dataRef.FilterCriteria[com.Container],
+	}
+	query := buildQuery(terms, fields.Timestamp, dataRef.StartTime, dataRef.EndTime, dataRef.client.maxRecords)
+	res, err := dataRef.client.search(ctx, dataRef.index, query)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]observability.LogRecord, 0, len(res.Hits.Hits))
+	for _, hit := range res.Hits.Hits {
+		source := hit.Source
+		logs = append(logs, observability.LogRecord{
+			Timestamp: getTime(source, fields.Timestamp),
+			Message:   getField(source, fields.Message),
+			Namespace: getField(source, fields.Namespace),
+			Pod:       getField(source, fields.Name),
+			Container: getField(source, fields.Container),
+		})
+	}
+	return logs, nil
+}
+
+// Default filter, add Pod, Container and Namespace.
+func (logReceiver EsLogRetriever) AddFilters(pod string, container string, namespace string) map[string]string {
+	return map[string]string{com.Pod: pod, com.Container: container, com.Namespace: namespace}
+}
+
+// New for EsLogRetriever, fields not configured use the Fluent Bit defaults.
+func NewEsLogRetriever(conf *config.ElasticsearchConfig) EsLogRetriever {
+	return EsLogRetriever{
+		client: newSearchClient(conf),
+		index:  conf.LogIndex,
+		fields: mergeFields(defaultLogFields, conf.LogFields),
+	}
+}
@@ -7,17 +7,32 @@ package k8s
 
 import (
 	"context"
-	"strings"
+	"slices"
+	"sync"
+	"time"
 
 	com "github.com/fidelity/theliv/pkg/common"
 	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
 	observability "github.com/fidelity/theliv/pkg/observability"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// Field selector keys, events.k8s.io/v1 uses regarding, core/v1 uses involvedObject.
+	regardingName      = "regarding.name"
+	involvedObjectName = "involvedObject.name"
 )
 
 type K8sEventRetriever struct {
 	kubeclient *kubeclient.KubeClient
+	cache      *eventCache
 }
 
 type K8sEventDataRef struct {
@@ -25,6 +40,20 @@ type K8sEventDataRef struct {
 	observability.EventFilterCriteria
 }
 
+// eventCache holds the events of a namespace, listed once and shared by all the investigators
+// of the same detection.
+type eventCache struct {
+	lock    sync.Mutex
+	entries map[string]*eventCacheEntry
+}
+
+// Failed lists are not kept, the next investigator lists again.
+type eventCacheEntry struct {
+	lock    sync.Mutex
+	records []observability.EventRecord
+	owners  map[string][]string
+}
+
 // Return the instance of EventDataRef, with k8sClient, and filtering conditions set.
 func (eventReceiver K8sEventRetriever) Retrieve(filterCriteria observability.EventFilterCriteria) observability.EventDataRef {
 	return K8sEventDataRef{eventReceiver, filterCriteria}
@@ -34,45 +63,263 @@ func (eventReceiver K8sEventRetriever) Retrieve(filterCriteria observability.Eve
 This function will call the k8s API to retrieve the events. Use predefined filtering conditions.
 In FilterCriteria, if namespace is provided, will get Events only under the specified namespace,
 otherwise get events of all the namespaces.
-In FilterCriteria, if resource name is provided, will do filtering after retrieving all the events,
-if the resource name can be found in event.Message or event.InvolvedObject.Name.
-In FilterCriteria, StartTime and EndTime won't be used for filtering.
+In FilterCriteria, if resource name is provided, only events regarding the resource or the resources it owns are
+returned, e.g. the ReplicaSets and Pods of a Deployment. The owned resources are found by the ownerReferences of
+the ReplicaSets, Jobs and Pods in the namespace, and matched by their exact names.
+The filtering is done by field selector, or in memory if the retriever is cached.
+StartTime and EndTime are applied to the last time the event was observed, a zero value means no bound.
+events.k8s.io/v1 is used if served by the cluster, otherwise falls back to core/v1.
 */
 func (dataRef K8sEventDataRef) GetEvents(ctx context.Context) ([]observability.EventRecord, error) {
+	name := dataRef.FilterCriteria[com.Name]
+	namespace := dataRef.FilterCriteria[com.Namespace]
+
+	var records []observability.EventRecord
+	var err error
+	if dataRef.cache != nil {
+		var owners map[string][]string
+		records, owners, err = dataRef.cache.get(ctx, dataRef.K8sEventRetriever, namespace)
+		if err == nil {
+			records = filterByName(records, ownedNames(owners, name))
+		}
+	} else {
+		records, err = dataRef.listOwnedEvents(ctx, namespace, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filterByTime(records, dataRef.StartTime, dataRef.EndTime), nil
+}
+
+// List events regarding the resource and the resources it owns, each name is listed by field selector.
+func (eventReceiver K8sEventRetriever) listOwnedEvents(ctx context.Context, namespace string,
+	name string) ([]observability.EventRecord, error) {
+	if name == "" {
+		return eventReceiver.listEvents(ctx, namespace, "")
+	}
+	records := make([]observability.EventRecord, 0)
+	owners, _ := eventReceiver.listOwners(ctx, namespace)
+	for _, owned := range ownedNames(owners, name) {
+		events, err := eventReceiver.listEvents(ctx, namespace, owned)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, events...)
+	}
+	return records, nil
+}
+
+// List events of the namespace, regarding the resource name if not empty.
+// Tries events.k8s.io/v1 first, and falls back to core/v1.
+func (eventReceiver K8sEventRetriever) listEvents(ctx context.Context, namespace string, name string) ([]observability.EventRecord, error) {
+	ns := kubeclient.NamespacedName{Namespace: namespace}
+
+	events := &eventsv1.EventList{}
+	err := eventReceiver.kubeclient.List(ctx, events, ns, listOptions(regardingName, name))
+	if err == nil {
+		return convertEvents(events.Items), nil
+	}
+	log.SWithContext(ctx).Debugf("Failed to list events.k8s.io/v1 events, fall back to core/v1, error is %s", err)
+
+	coreEvents := &v1.EventList{}
+	if err := eventReceiver.kubeclient.List(ctx, coreEvents, ns, listOptions(involvedObjectName, name)); err != nil {
+		return nil, err
+	}
+	return convertCoreEvents(coreEvents.Items), nil
+}
+
+func listOptions(field string, name string) metav1.ListOptions {
+	if name == "" {
+		return metav1.ListOptions{}
+	}
+	return metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector(field, name).String(),
+	}
+}
+
+// Returns the names of the resources owned by each owner name in the namespace, from the ownerReferences
+// of ReplicaSets, Jobs and Pods. A kind failed to list is skipped, its events are then not matched to the owner,
+// and complete is false.
+func (eventReceiver K8sEventRetriever) listOwners(ctx context.Context, namespace string) (owners map[string][]string, complete bool) {
+	ns := kubeclient.NamespacedName{Namespace: namespace}
+	owners = make(map[string][]string)
+	complete = true
+	add := func(list runtime.Object, metas func() []metav1.ObjectMeta) {
+		if err := eventReceiver.kubeclient.List(ctx, list, ns, metav1.ListOptions{}); err != nil {
+			log.SWithContext(ctx).Debugf("Failed to list %T to match the events of owned resources, error is %s", list, err)
+			complete = false
+			return
+		}
+		for _, meta := range metas() {
+			for _, ref := range meta.OwnerReferences {
+				owners[ref.Name] = append(owners[ref.Name], meta.Name)
+			}
+		}
+	}
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	add(replicaSets, func() (metas []metav1.ObjectMeta) {
+		for _, item := range replicaSets.Items {
+			metas = append(metas, item.ObjectMeta)
+		}
+		return
+	})
+	jobs := &batchv1.JobList{}
+	add(jobs, func() (metas []metav1.ObjectMeta) {
+		for _, item := range jobs.Items {
+			metas = append(metas, item.ObjectMeta)
+		}
+		return
+	})
+	pods := &v1.PodList{}
+	add(pods, func() (metas []metav1.ObjectMeta) {
+		for _, item := range pods.Items {
+			metas = append(metas, item.ObjectMeta)
+		}
+		return
+	})
+	return owners, complete
+}
 
-	eventRecord := make([]observability.EventRecord, 0)
-
-	var name string
-	if data, ok := dataRef.FilterCriteria[com.Name]; ok {
-		name = data
-	}
-	namespace := kubeclient.NamespacedName{}
-	if data, ok := dataRef.FilterCriteria[com.Namespace]; ok {
-		namespace = kubeclient.NamespacedName{Namespace: data}
-	}
-
-	events := &v1.EventList{}
-	eventsOptions := metav1.ListOptions{}
-	dataRef.kubeclient.List(ctx, events, namespace, eventsOptions)
-
-	for _, event := range events.Items {
-		if strings.Contains(event.InvolvedObject.Name, name) || strings.Contains(event.Message, name) {
-			eventRecord = append(eventRecord,
-				observability.EventRecord{
-					EventId:        string(event.ObjectMeta.UID),
-					Title:          event.ObjectMeta.Name,
-					Message:        event.Message,
-					Reason:         event.Reason,
-					DateHappened:   event.ObjectMeta.CreationTimestamp.Time,
-					InvolvedObject: getInvolvedObject(event.InvolvedObject),
-					Source:         getSource(event.Source),
-					Type:           event.Type,
-					LastTimestamp:  event.LastTimestamp.Time,
-				})
+// Returns the events and the owner index of the namespace, only the first successful calls list from API server.
+// The owner index is listed again if it was incomplete, e.g. the context was cancelled.
+func (c *eventCache) get(ctx context.Context, eventReceiver K8sEventRetriever,
+	namespace string) ([]observability.EventRecord, map[string][]string, error) {
+	c.lock.Lock()
+	entry, ok := c.entries[namespace]
+	if !ok {
+		entry = &eventCacheEntry{}
+		c.entries[namespace] = entry
+	}
+	c.lock.Unlock()
+
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.records == nil {
+		records, err := eventReceiver.listEvents(ctx, namespace, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		entry.records = records
+	}
+	if entry.owners == nil {
+		owners, complete := eventReceiver.listOwners(ctx, namespace)
+		if !complete {
+			return entry.records, owners, nil
+		}
+		entry.owners = owners
+	}
+	return entry.records, entry.owners, nil
+}
+
+func convertEvents(events []eventsv1.Event) []observability.EventRecord {
+	records := make([]observability.EventRecord, 0, len(events))
+	for _, event := range events {
+		lastTimestamp := event.DeprecatedLastTimestamp.Time
+		if event.Series != nil {
+			lastTimestamp = event.Series.LastObservedTime.Time
+		} else if !event.EventTime.IsZero() && event.EventTime.After(lastTimestamp) {
+			lastTimestamp = event.EventTime.Time
+		}
+		records = append(records,
+			observability.EventRecord{
+				EventId:        string(event.ObjectMeta.UID),
+				Title:          event.ObjectMeta.Name,
+				Message:        event.Note,
+				Reason:         event.Reason,
+				DateHappened:   event.ObjectMeta.CreationTimestamp.Time,
+				InvolvedObject: getInvolvedObject(event.Regarding),
+				Source:         map[string]string{"Component": getComponent(event)},
+				Type:           event.Type,
+				LastTimestamp:  orCreationTime(lastTimestamp, event.ObjectMeta),
+			})
+	}
+	return records
+}
+
+func convertCoreEvents(events []v1.Event) []observability.EventRecord {
+	records := make([]observability.EventRecord, 0, len(events))
+	for _, event := range events {
+		lastTimestamp := event.LastTimestamp.Time
+		if event.Series != nil {
+			lastTimestamp = event.Series.LastObservedTime.Time
+		} else if !event.EventTime.IsZero() && event.EventTime.After(lastTimestamp) {
+			lastTimestamp = event.EventTime.Time
 		}
+		records = append(records,
+			observability.EventRecord{
+				EventId:        string(event.ObjectMeta.UID),
+				Title:          event.ObjectMeta.Name,
+				Message:        event.Message,
+				Reason:         event.Reason,
+				DateHappened:   event.ObjectMeta.CreationTimestamp.Time,
+				InvolvedObject: getInvolvedObject(event.InvolvedObject),
+				Source:         getSource(event.Source),
+				Type:           event.Type,
+				LastTimestamp:  orCreationTime(lastTimestamp, event.ObjectMeta),
+			})
 	}
-	return eventRecord, nil
+	return records
+}
 
+// Returns the name and the names of the resources it owns, directly or not, e.g. ReplicaSet web-5d8f7 and
+// Pod web-5d8f7-x2kq9 of Deployment web. Returns nil if name is empty.
+func ownedNames(owners map[string][]string, name string) []string {
+	if name == "" {
+		return nil
+	}
+	names := []string{name}
+	for i := 0; i < len(names); i++ {
+		for _, owned := range owners[names[i]] {
+			if !slices.Contains(names, owned) {
+				names = append(names, owned)
+			}
+		}
+	}
+	return names
+}
+
+// Keeps the events regarding one of the resource names exactly, returns all if names is empty.
+func filterByName(records []observability.EventRecord, names []string) []observability.EventRecord {
+	if len(names) == 0 {
+		return records
+	}
+	result := make([]observability.EventRecord, 0)
+	for _, record := range records {
+		if slices.Contains(names, record.InvolvedObject[com.Name]) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+// Keeps the events last observed between start and end, zero start or end is not checked.
+func filterByTime(records []observability.EventRecord, start time.Time, end time.Time) []observability.EventRecord {
+	result := make([]observability.EventRecord, 0)
+	for _, record := range records {
+		if !start.IsZero() && record.LastTimestamp.Before(start) {
+			continue
+		}
+		if !end.IsZero() && record.DateHappened.After(end) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+func orCreationTime(t time.Time, meta metav1.ObjectMeta) time.Time {
+	if t.IsZero() {
+		return meta.CreationTimestamp.Time
+	}
+	return t
+}
+
+func getComponent(event eventsv1.Event) string {
+	if event.ReportingController != "" {
+		return event.ReportingController
+	}
+	return event.DeprecatedSource.Component
 }
 
 // Get Info from event.EventSource, returns map[string]string.
@@ -100,7 +347,16 @@ func (eventReceiver K8sEventRetriever) AddFilters(name string, namespace string)
 
 // New for K8sEventRetriever.
 func NewK8sEventRetriever(kubeclient *kubeclient.KubeClient) K8sEventRetriever {
-	return K8sEventRetriever{kubeclient}
+	return K8sEventRetriever{kubeclient: kubeclient}
+}
+
+// New for K8sEventRetriever with event cache, events of a namespace are listed only once,
+// should be created per detection.
+func NewCachedK8sEventRetriever(kubeclient *kubeclient.KubeClient) K8sEventRetriever {
+	return K8sEventRetriever{
+		kubeclient: kubeclient,
+		cache:      &eventCache{entries: make(map[string]*eventCacheEntry)},
+	}
 }
 
 func initMap() map[string]string {
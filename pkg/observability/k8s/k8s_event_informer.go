@@ -0,0 +1,60 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package k8s
+
+import (
+	"context"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/observability"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// InformerEventRetriever reads core/v1 events from the shared informer cache of watch mode,
+// no API call is made per detection.
+type InformerEventRetriever struct {
+	lister corelisters.EventLister
+}
+
+type InformerEventDataRef struct {
+	InformerEventRetriever
+	observability.EventFilterCriteria
+}
+
+func (eventReceiver InformerEventRetriever) Retrieve(filterCriteria observability.EventFilterCriteria) observability.EventDataRef {
+	return InformerEventDataRef{eventReceiver, filterCriteria}
+}
+
+func (eventReceiver InformerEventRetriever) AddFilters(name string, namespace string) map[string]string {
+	return map[string]string{com.Name: name, com.Namespace: namespace}
+}
+
+// Same filtering as K8sEventDataRef, by namespace, resource name and last observed time.
+// The informers don't hold the owned resources, only the events regarding the resource itself are returned.
+func (dataRef InformerEventDataRef) GetEvents(ctx context.Context) ([]observability.EventRecord, error) {
+	var events []*v1.Event
+	var err error
+	if namespace := dataRef.FilterCriteria[com.Namespace]; namespace != "" {
+		events, err = dataRef.lister.Events(namespace).List(labels.Everything())
+	} else {
+		events, err = dataRef.lister.List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := make([]v1.Event, 0, len(events))
+	for _, e := range events {
+		items = append(items, *e)
+	}
+	records := filterByName(convertCoreEvents(items), ownedNames(nil, dataRef.FilterCriteria[com.Name]))
+	return filterByTime(records, dataRef.StartTime, dataRef.EndTime), nil
+}
+
+func NewInformerEventRetriever(lister corelisters.EventLister) InformerEventRetriever {
+	return InformerEventRetriever{lister: lister}
+}
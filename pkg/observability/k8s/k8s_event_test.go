@@ -0,0 +1,178 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	"github.com/fidelity/theliv/pkg/observability"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestConvertEventsLastTimestamp(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	observed := created.Add(time.Hour)
+
+	events := []eventsv1.Event{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "series", CreationTimestamp: metav1.NewTime(created)},
+			Series:     &eventsv1.EventSeries{Count: 3, LastObservedTime: metav1.NewMicroTime(observed)},
+			Regarding:  v1.ObjectReference{Name: "pod-a"},
+			Note:       "Back-off restarting failed container",
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "single", CreationTimestamp: metav1.NewTime(created)},
+			EventTime:  metav1.NewMicroTime(observed),
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", CreationTimestamp: metav1.NewTime(created)},
+		},
+	}
+	records := convertEvents(events)
+	assert.Equal(t, observed, records[0].LastTimestamp)
+	assert.Equal(t, "pod-a", records[0].InvolvedObject[com.Name])
+	assert.Equal(t, "Back-off restarting failed container", records[0].Message)
+	assert.Equal(t, observed, records[1].LastTimestamp)
+	assert.Equal(t, created, records[2].LastTimestamp)
+}
+
+func TestFilterByTime(t *testing.T) {
+	now := time.Now()
+	records := []observability.EventRecord{
+		{Title: "old", DateHappened: now.Add(-72 * time.Hour), LastTimestamp: now.Add(-50 * time.Hour)},
+		{Title: "recurring", DateHappened: now.Add(-72 * time.Hour), LastTimestamp: now.Add(-time.Hour)},
+		{Title: "future", DateHappened: now.Add(time.Hour), LastTimestamp: now.Add(time.Hour)},
+	}
+
+	result := filterByTime(records, now.Add(-48*time.Hour), now)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "recurring", result[0].Title)
+
+	assert.Len(t, filterByTime(records, time.Time{}, time.Time{}), 3)
+}
+
+func TestFilterByName(t *testing.T) {
+	records := []observability.EventRecord{
+		{Title: "e1", InvolvedObject: map[string]string{com.Name: "web"}},
+		{Title: "e2", InvolvedObject: map[string]string{com.Name: "webapp"}},
+		{Title: "e3", InvolvedObject: map[string]string{com.Name: "api"}, Message: "Scaled up replica set web-7c9d to 2"},
+	}
+	result := filterByName(records, []string{"web"})
+	assert.Len(t, result, 1)
+	assert.Equal(t, "e1", result[0].Title)
+	assert.Len(t, filterByName(records, nil), 3)
+}
+
+// Events of the ReplicaSets and Pods owned by a Deployment match the Deployment name,
+// the Deployment with the name as prefix doesn't.
+func TestFilterByNameOwned(t *testing.T) {
+	records := []observability.EventRecord{
+		{Title: "deployment", InvolvedObject: map[string]string{com.Name: "web", "Kind": "Deployment"}},
+		{Title: "replicaset", InvolvedObject: map[string]string{com.Name: "web-5d8f7", "Kind": "ReplicaSet"}},
+		{Title: "pod", InvolvedObject: map[string]string{com.Name: "web-5d8f7-x2kq9", "Kind": "Pod"}},
+		{Title: "other", InvolvedObject: map[string]string{com.Name: "web-api-6b4c9-p7m2d", "Kind": "Pod"}},
+	}
+	owners := map[string][]string{
+		"web":           {"web-5d8f7"},
+		"web-5d8f7":     {"web-5d8f7-x2kq9"},
+		"web-api":       {"web-api-6b4c9"},
+		"web-api-6b4c9": {"web-api-6b4c9-p7m2d"},
+	}
+	assert.Equal(t, []string{"web", "web-5d8f7", "web-5d8f7-x2kq9"}, ownedNames(owners, "web"))
+	assert.Nil(t, ownedNames(owners, ""))
+
+	result := filterByName(records, ownedNames(owners, "web"))
+	titles := []string{}
+	for _, r := range result {
+		titles = append(titles, r.Title)
+	}
+	assert.Equal(t, []string{"deployment", "replicaset", "pod"}, titles)
+
+	// the pod only matches its own events
+	assert.Len(t, filterByName(records, ownedNames(owners, "web-5d8f7-x2kq9")), 1)
+}
+
+func TestListOwnedEvents(t *testing.T) {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":            "web-5d8f7-x2kq9",
+			"namespace":       "ns1",
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": "ReplicaSet", "name": "web-5d8f7"}},
+		},
+	}}
+	client, dynamicCli := fakeKubeClient(t, pod)
+
+	selectors := []string{}
+	dynamicCli.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selectors = append(selectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+		return false, nil, nil
+	})
+	_, err := NewK8sEventRetriever(client).listOwnedEvents(context.Background(), "ns1", "web-5d8f7")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"regarding.name=web-5d8f7", "regarding.name=web-5d8f7-x2kq9"}, selectors)
+}
+
+// A failed list is not cached, the next investigator lists again.
+func TestEventCacheRetry(t *testing.T) {
+	client, dynamicCli := fakeKubeClient(t)
+
+	calls := 0
+	dynamicCli.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		calls++
+		if calls <= 2 {
+			return true, nil, errors.New("context canceled")
+		}
+		return false, nil, nil
+	})
+	retriever := NewCachedK8sEventRetriever(client)
+	_, _, err := retriever.cache.get(context.Background(), retriever, "ns1")
+	assert.NotNil(t, err)
+	records, _, err := retriever.cache.get(context.Background(), retriever, "ns1")
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+	_, _, err = retriever.cache.get(context.Background(), retriever, "ns1")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func fakeKubeClient(t *testing.T, objects ...runtime.Object) (*kubeclient.KubeClient, *dynamicfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	gvks := []schema.GroupVersionKind{
+		v1.SchemeGroupVersion.WithKind("Pod"),
+		v1.SchemeGroupVersion.WithKind("Event"),
+		eventsv1.SchemeGroupVersion.WithKind("Event"),
+		appsv1.SchemeGroupVersion.WithKind("ReplicaSet"),
+		batchv1.SchemeGroupVersion.WithKind("Job"),
+	}
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, gvk := range gvks {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		listKinds[plural] = gvk.Kind + "List"
+	}
+	dynamicCli := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+	client, err := kubeclient.NewKubeClientFor(dynamicCli, mapper)
+	assert.Nil(t, err)
+	return client, dynamicCli
+}
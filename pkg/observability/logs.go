@@ -0,0 +1,35 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package observability
+
+import (
+	"context"
+	"time"
+)
+
+// LogRecord represents a single container log line
+type LogRecord struct {
+	Timestamp time.Time
+	Message   string
+	Namespace string
+	Pod       string
+	Container string
+}
+
+type LogFilterCriteria struct {
+	FilterCriteria map[string]string
+	StartTime      time.Time
+	EndTime        time.Time
+}
+
+type LogRetriever interface {
+	Retrieve(LogFilterCriteria) LogDataRef
+	AddFilters(pod string, container string, namespace string) map[string]string
+}
+
+type LogDataRef interface {
+	GetLogs(ctx context.Context) ([]LogRecord, error)
+}
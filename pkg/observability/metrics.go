@@ -0,0 +1,35 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package observability
+
+import (
+	"context"
+	"time"
+)
+
+// MetricSample represents a single value of a series
+type MetricSample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+type MetricSeries struct {
+	Labels  map[string]string
+	Samples []MetricSample
+}
+
+type MetricQueryCriteria struct {
+	Query     string
+	StartTime time.Time
+	EndTime   time.Time
+	Step      time.Duration
+}
+
+type MetricRetriever interface {
+	QueryRange(ctx context.Context, criteria MetricQueryCriteria) ([]MetricSeries, error)
+	// Label matchers selecting the cluster if the store has several clusters, e.g. cluster="c1"
+	ClusterMatchers() string
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/fidelity/theliv/internal/problem"
+	errors "github.com/fidelity/theliv/pkg/err"
+	log "github.com/fidelity/theliv/pkg/log"
+)
+
+// SuppressedAlert is an alert silenced or inhibited in alertmanager.
+type SuppressedAlert struct {
+	Labels map[string]string `json:"labels"`
+	Status struct {
+		State       string   `json:"state"`
+		SilencedBy  []string `json:"silencedBy"`
+		InhibitedBy []string `json:"inhibitedBy"`
+	} `json:"status"`
+}
+
+// GetSuppressedAlerts returns the silenced and inhibited alerts of the cluster from alertmanager v2 API.
+// Returns nil if alertmanager of the cluster is not configured.
+func GetSuppressedAlerts(ctx context.Context, input *problem.DetectorCreationInput) ([]SuppressedAlert, error) {
+	endpoint := getEndpoint(input.Prometheus)
+	if endpoint.Alertmanager == nil {
+		return nil, nil
+	}
+	address, rt, err := newRoundTripper(ctx, input.Kubeconfig, endpoint.Alertmanager)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("active", "false")
+	query.Set("unprocessed", "false")
+	query.Set("silenced", "true")
+	query.Set("inhibited", "true")
+	if endpoint.Alertmanager.ClusterLabel != "" {
+		label, value := clusterLabel(endpoint.Alertmanager, input.ClusterName)
+		query.Add("filter", fmt.Sprintf("%s=%q", label, value))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address+"/api/v2/alerts?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Got error when getting alertmanager alerts, error is %s", err)
+		return nil, errors.NewCommonError(ctx, 6, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.NewCommonError(ctx, 6, "unexpected status of alertmanager "+resp.Status)
+	}
+	alerts := make([]SuppressedAlert, 0)
+	if err = json.NewDecoder(resp.Body).Decode(&alerts); err != nil {
+		return nil, errors.NewCommonError(ctx, 6, err.Error())
+	}
+	return alerts, nil
+}
+
+// SuppressedBy returns the silences and inhibiting alerts.
+func (a *SuppressedAlert) SuppressedBy() []string {
+	return append(append([]string{}, a.Status.SilencedBy...), a.Status.InhibitedBy...)
+}
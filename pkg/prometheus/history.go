@@ -0,0 +1,182 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	errors "github.com/fidelity/theliv/pkg/err"
+	log "github.com/fidelity/theliv/pkg/log"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	historicalPoints  = 250
+	minHistoricalStep = 30 * time.Second
+	// prometheus rejects more than 11000 points per series
+	maxRecurrencePoints = 10000
+	minRecurrenceStep   = time.Minute
+)
+
+// Kinds with the kube_<kind>_metadata_generation metrics of kube-state-metrics.
+var generationKinds = []string{"deployment", "statefulset", "daemonset", "replicaset"}
+
+// GetHistoricalAlerts reconstructs the alerts firing in the window of the input from the ALERTS series.
+// ActiveAt is the first sample of the series in the window, annotations are not available.
+func GetHistoricalAlerts(ctx context.Context, input *problem.DetectorCreationInput) (v1.AlertsResult, error) {
+	step := input.Window.To.Sub(input.Window.From) / historicalPoints
+	matrix, err := queryFiringAlerts(ctx, input, "", input.Window.From, input.Window.To, step)
+	if err != nil {
+		return v1.AlertsResult{}, err
+	}
+	return v1.AlertsResult{Alerts: toAlerts(matrix)}, nil
+}
+
+// GetFiringIntervals returns the periods the alerts were firing in the namespaces between from and to,
+// keyed by problem.RecurrenceKey. An empty namespace matches the alerts without namespace, e.g. of nodes.
+func GetFiringIntervals(ctx context.Context, input *problem.DetectorCreationInput, alerts []string,
+	namespaces []string, from time.Time, to time.Time) (map[string][]problem.Interval, error) {
+	step := to.Sub(from) / maxRecurrencePoints
+	if step < minRecurrenceStep {
+		step = minRecurrenceStep
+	}
+	// the namespaces are mapped, match them on the alert label they are mapped from
+	mapper := NewLabelMapper(ctx, input.Prometheus)
+	matchers := mapper.Source(com.Namespace) + `=~"` + anyOf(namespaces) + `",alertname=~"` + anyOf(alerts) + `"`
+	matrix, err := queryFiringAlerts(ctx, input, matchers, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+	return toIntervals(matrix, step, mapper), nil
+}
+
+// Regex matching any of the values, quoted for the PromQL string.
+func anyOf(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strings.ReplaceAll(regexp.QuoteMeta(v), `\`, `\\`))
+	}
+	return strings.Join(quoted, "|")
+}
+
+func queryFiringAlerts(ctx context.Context, input *problem.DetectorCreationInput, matchers string, from time.Time,
+	to time.Time, step time.Duration) (model.Matrix, error) {
+	retriever := NewMetricRetriever(input)
+	v1api, err := newAPI(ctx, retriever.kubeconfig, retriever.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	selectors := []string{`alertstate="firing"`}
+	for _, m := range []string{retriever.ClusterMatchers(), matchers} {
+		if m != "" {
+			selectors = append(selectors, m)
+		}
+	}
+	query := "ALERTS{" + strings.Join(selectors, ",") + "}"
+	if step < minHistoricalStep {
+		step = minHistoricalStep
+	}
+	qctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	value, _, err := v1api.QueryRange(qctx, query, v1.Range{Start: from, End: to, Step: step})
+	if err != nil {
+		err = errors.NewCommonError(ctx, 6, err.Error())
+		log.SWithContext(ctx).Errorf("Got error when querying firing alerts, error is %s", err)
+		return nil, err
+	}
+	matrix, _ := value.(model.Matrix)
+	return matrix, nil
+}
+
+// GetGenerations returns the metadata generation of the workloads in the namespace at the time,
+// keyed by the lower case kind and name, e.g. deployment/web.
+func GetGenerations(ctx context.Context, input *problem.DetectorCreationInput, at time.Time) (map[string]int64, error) {
+	retriever := NewMetricRetriever(input)
+	v1api, err := newAPI(ctx, retriever.kubeconfig, retriever.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// federated kube-state-metrics series have the namespace in the mapped label, e.g. exported_namespace
+	namespace := NewLabelMapper(ctx, input.Prometheus).Source(com.Namespace)
+	query := `{__name__=~"kube_(` + strings.Join(generationKinds, "|") + `)_metadata_generation",` + namespace + `="` +
+		input.Namespace + `"`
+	if matchers := retriever.ClusterMatchers(); matchers != "" {
+		query += "," + matchers
+	}
+	query += "}"
+	qctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	value, _, err := v1api.Query(qctx, query, at)
+	if err != nil {
+		err = errors.NewCommonError(ctx, 6, err.Error())
+		log.SWithContext(ctx).Errorf("Got error when querying generations, error is %s", err)
+		return nil, err
+	}
+	vector, _ := value.(model.Vector)
+	generations := make(map[string]int64, len(vector))
+	for _, s := range vector {
+		for _, kind := range generationKinds {
+			if name := s.Metric[model.LabelName(kind)]; name != "" {
+				generations[kind+"/"+string(name)] = int64(s.Value)
+				break
+			}
+		}
+	}
+	return generations, nil
+}
+
+// Consecutive samples are in the same interval, a missing sample starts a new interval.
+// Series of the pods recreated by the controller have the same key, their intervals are merged.
+func toIntervals(matrix model.Matrix, step time.Duration, mapper *LabelMapper) map[string][]problem.Interval {
+	intervals := make(map[string][]problem.Interval)
+	for _, stream := range matrix {
+		tags := make(map[string]string, len(stream.Metric))
+		for k, v := range stream.Metric {
+			tags[string(k)] = string(v)
+		}
+		key := problem.RecurrenceKey(mapper.Map(tags))
+		for i, s := range stream.Values {
+			t := s.Timestamp.Time()
+			if i > 0 && t.Sub(stream.Values[i-1].Timestamp.Time()) <= step+step/2 {
+				intervals[key][len(intervals[key])-1].End = t
+				continue
+			}
+			intervals[key] = append(intervals[key], problem.Interval{Start: t, End: t})
+		}
+	}
+	for key, list := range intervals {
+		intervals[key] = problem.MergeIntervals(list)
+	}
+	return intervals
+}
+
+func toAlerts(matrix model.Matrix) []v1.Alert {
+	alerts := make([]v1.Alert, 0, len(matrix))
+	for _, stream := range matrix {
+		if len(stream.Values) == 0 {
+			continue
+		}
+		labels := model.LabelSet{}
+		for k, v := range stream.Metric {
+			if k != model.MetricNameLabel && k != "alertstate" {
+				labels[k] = v
+			}
+		}
+		alerts = append(alerts, v1.Alert{
+			Labels:   labels,
+			State:    v1.AlertStateFiring,
+			ActiveAt: stream.Values[0].Timestamp.Time(),
+			Value:    stream.Values[len(stream.Values)-1].Value.String(),
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func TestGetHistoricalAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, `ALERTS{alertstate="firing",cluster="c1"}`, r.FormValue("query"))
+		assert.Equal(t, "30", r.FormValue("step"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"ALERTS","alertname":"PodNotReady","alertstate":"firing","pod":"web-0"},
+			 "values":[[1704106800,"1"],[1704106830,"1"]]}]}}`))
+	}))
+	defer server.Close()
+
+	to := time.Unix(1704110400, 0)
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL,
+			ClusterLabel: "cluster",
+		},
+		Window: &problem.TimeWindow{From: to.Add(-time.Hour), To: to},
+	}
+	result, err := GetHistoricalAlerts(context.Background(), input)
+	assert.Nil(t, err)
+	assert.Len(t, result.Alerts, 1)
+	alert := result.Alerts[0]
+	assert.Equal(t, model.LabelSet{"alertname": "PodNotReady", "pod": "web-0"}, alert.Labels)
+	assert.Equal(t, int64(1704106800), alert.ActiveAt.Unix())
+}
+
+// The namespaces are mapped from exported_namespace, the query matches the alert label.
+func TestGetFiringIntervalsLabelMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `ALERTS{alertstate="firing",cluster="c1",exported_namespace=~"ns1",alertname=~"PodNotReady"}`,
+			r.FormValue("query"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"ALERTS","alertname":"PodNotReady","alertstate":"firing","namespace":"monitoring",
+			 "exported_namespace":"ns1","pod":"web-0"},"values":[[1704106800,"1"],[1704106860,"1"]]}]}}`))
+	}))
+	defer server.Close()
+
+	to := time.Unix(1704110400, 0)
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL,
+			ClusterLabel: "cluster",
+			LabelMapping: &config.LabelMapping{Labels: map[string]string{"namespace": "exported_namespace"}},
+		},
+	}
+	intervals, err := GetFiringIntervals(context.Background(), input, []string{"PodNotReady"}, []string{"ns1"},
+		to.Add(-time.Hour), to)
+	assert.Nil(t, err)
+	assert.Len(t, intervals["PodNotReady/ns1/web//"], 1)
+}
+
+// Federated kube-state-metrics series have the namespace in exported_namespace.
+func TestGetGenerationsLabelMapping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{__name__=~"kube_(deployment|statefulset|daemonset|replicaset)_metadata_generation",`+
+			`exported_namespace="ns1",cluster="c1"}`, r.FormValue("query"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"__name__":"kube_deployment_metadata_generation","namespace":"monitoring",
+			 "exported_namespace":"ns1","deployment":"web"},"value":[1704110400,"3"]}]}}`))
+	}))
+	defer server.Close()
+
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Namespace:   "ns1",
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL,
+			ClusterLabel: "cluster",
+			LabelMapping: &config.LabelMapping{Labels: map[string]string{"namespace": "exported_namespace"}},
+		},
+	}
+	generations, err := GetGenerations(context.Background(), input, time.Unix(1704110400, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"deployment/web": 3}, generations)
+}
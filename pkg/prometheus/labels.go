@@ -0,0 +1,102 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+)
+
+// Functions of the label templates, the arguments follow the pipeline order, e.g. {{.alertname | hasPrefix "Node"}}.
+var labelFuncs = template.FuncMap{
+	"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+	"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"match": func(pattern string, s string) bool {
+		matched, err := regexp.MatchString(pattern, s)
+		return err == nil && matched
+	},
+}
+
+// LabelMapper maps the alert labels by the label mapping of the Prometheus endpoint.
+// A nil LabelMapper keeps the labels unchanged.
+type LabelMapper struct {
+	labels map[string]string
+	// sorted by label, so a template can use the labels derived before it
+	names     []string
+	templates map[string]*template.Template
+}
+
+// NewLabelMapper returns nil if the endpoint has no label mapping, invalid templates are logged and skipped.
+func NewLabelMapper(ctx context.Context, endpoint *config.PrometheusEndpoint) *LabelMapper {
+	if endpoint == nil || endpoint.LabelMapping == nil {
+		return nil
+	}
+	m := &LabelMapper{
+		labels:    endpoint.LabelMapping.Labels,
+		templates: make(map[string]*template.Template),
+	}
+	for name, text := range endpoint.LabelMapping.Templates {
+		t, err := template.New(name).Funcs(labelFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			log.SWithContext(ctx).Errorf("Invalid template of label %s, error is %s", name, err)
+			continue
+		}
+		m.names = append(m.names, name)
+		m.templates[name] = t
+	}
+	sort.Strings(m.names)
+	return m
+}
+
+// Source returns the alert label mapped to the Theliv label, or the label itself if it is not mapped.
+// It is used to match the series in queries, before the labels are mapped.
+func (m *LabelMapper) Source(label string) string {
+	if m == nil || m.labels[label] == "" {
+		return label
+	}
+	return m.labels[label]
+}
+
+// Map returns the mapped copy of the labels.
+func (m *LabelMapper) Map(labels map[string]string) map[string]string {
+	if m == nil {
+		return labels
+	}
+	mapped := make(map[string]string, len(labels))
+	for k, v := range labels {
+		mapped[k] = v
+	}
+	for name, from := range m.labels {
+		if v := labels[from]; v != "" {
+			mapped[name] = v
+		}
+	}
+	for _, name := range m.names {
+		if mapped[name] != "" {
+			continue
+		}
+		var b bytes.Buffer
+		if err := m.templates[name].Execute(&b, mapped); err != nil {
+			continue
+		}
+		if v := strings.TrimSpace(b.String()); v != "" {
+			mapped[name] = v
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelMapper(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, NewLabelMapper(ctx, &config.PrometheusEndpoint{}))
+
+	mapper := NewLabelMapper(ctx, &config.PrometheusEndpoint{LabelMapping: &config.LabelMapping{
+		Labels: map[string]string{"namespace": "exported_namespace", "pod": "kubernetes_pod_name"},
+		Templates: map[string]string{
+			"resourcetype": `{{if hasPrefix "Node" .alertname}}node{{else if .container}}container{{else}}pod{{end}}`,
+			"invalid":      `{{if}}`,
+		},
+	}})
+	labels := map[string]string{"alertname": "PodNotReady", "namespace": "monitoring",
+		"exported_namespace": "ns1", "kubernetes_pod_name": "web-0"}
+	mapped := mapper.Map(labels)
+	assert.Equal(t, "ns1", mapped["namespace"])
+	assert.Equal(t, "web-0", mapped["pod"])
+	assert.Equal(t, "pod", mapped["resourcetype"])
+	assert.NotContains(t, mapped, "invalid")
+	// the original labels are not changed
+	assert.Equal(t, "monitoring", labels["namespace"])
+
+	assert.Equal(t, "node", mapper.Map(map[string]string{"alertname": "NodeNotReady"})["resourcetype"])
+	assert.Equal(t, "deployment", mapper.Map(map[string]string{"alertname": "NodeNotReady",
+		"resourcetype": "deployment"})["resourcetype"])
+}
@@ -22,9 +22,13 @@ import (
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
 	"k8s.io/client-go/rest"
 )
 
+// Tenant header of Thanos and Mimir.
+const defaultTenantHeader = "X-Scope-OrgID"
+
 func loadDataFromFileOrInline(inline []byte, filePath string) ([]byte, error) {
 	if len(inline) > 0 {
 		return inline, nil
@@ -40,23 +44,11 @@ func loadDataFromFileOrInline(inline []byte, filePath string) ([]byte, error) {
 }
 
 func buildTLSRoundTripper(kubeconfig *rest.Config) (http.RoundTripper, error) {
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
-
 	caData, err := loadDataFromFileOrInline(
 		kubeconfig.CAData, kubeconfig.CAFile)
 	if err != nil {
 		return nil, err
 	}
-	if len(caData) > 0 {
-		caPool := x509.NewCertPool()
-		if !caPool.AppendCertsFromPEM(caData) {
-			return nil, fmt.Errorf("failed to parse CA certificate from kubeconfig")
-		}
-		tlsConfig.RootCAs = caPool
-	}
-
 	certData, err := loadDataFromFileOrInline(
 		kubeconfig.CertData, kubeconfig.CertFile)
 	if err != nil {
@@ -67,6 +59,21 @@ func buildTLSRoundTripper(kubeconfig *rest.Config) (http.RoundTripper, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newTransport(caData, certData, keyData, false)
+}
+
+func newTransport(caData []byte, certData []byte, keyData []byte, insecure bool) (http.RoundTripper, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure,
+	}
+	if len(caData) > 0 {
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("failed to parse CA certificate")
+		}
+		tlsConfig.RootCAs = caPool
+	}
 	if len(certData) > 0 && len(keyData) > 0 {
 		cert, err := tls.X509KeyPair(certData, keyData)
 		if err != nil {
@@ -86,29 +93,14 @@ func buildTLSRoundTripper(kubeconfig *rest.Config) (http.RoundTripper, error) {
 	}, nil
 }
 
+// GetAlerts returns the alerts of the cluster. If the endpoint has a cluster label,
+// alerts of the other clusters are dropped.
 func GetAlerts(ctx context.Context, input *problem.DetectorCreationInput) (v1.AlertsResult, error) {
-	promcfg := config.GetThelivConfig().Prometheus
-	address := input.Kubeconfig.Host + "/api/v1/namespaces/" +
-		promcfg.Namespace + "/services/https:" + promcfg.Name + ":" + promcfg.Port + "/proxy"
-
-	transport, err := buildTLSRoundTripper(input.Kubeconfig)
-	if err != nil {
-		log.SWithContext(ctx).Errorf("Failed to build TLS transport from kubeconfig: %s", err)
-		return v1.AlertsResult{}, err
-	}
-
-	client, err := api.NewClient(api.Config{
-		Address: address,
-		RoundTripper: promconfig.NewAuthorizationCredentialsRoundTripper("Bearer",
-			promconfig.NewInlineSecret(input.Kubeconfig.BearerToken),
-			transport),
-	})
+	endpoint := getEndpoint(input.Prometheus)
+	v1api, err := newAPI(ctx, input.Kubeconfig, endpoint)
 	if err != nil {
-		log.SWithContext(ctx).Errorf("Got error when creating Prometheus client, error is %s", err)
 		return v1.AlertsResult{}, err
 	}
-
-	v1api := v1.NewAPI(client)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	result, err := v1api.Alerts(ctx)
@@ -117,5 +109,107 @@ func GetAlerts(ctx context.Context, input *problem.DetectorCreationInput) (v1.Al
 		log.SWithContext(ctx).Errorf("Got error when getting Prometheus alerts, error is %s", err)
 		return v1.AlertsResult{}, err
 	}
+	if endpoint.ClusterLabel != "" {
+		label, value := clusterLabel(endpoint, input.ClusterName)
+		alerts := make([]v1.Alert, 0, len(result.Alerts))
+		for _, a := range result.Alerts {
+			if string(a.Labels[label]) == value {
+				alerts = append(alerts, a)
+			}
+		}
+		result.Alerts = alerts
+	}
 	return result, nil
 }
+
+func getEndpoint(endpoint *config.PrometheusEndpoint) *config.PrometheusEndpoint {
+	if endpoint != nil {
+		return endpoint
+	}
+	if promcfg := config.GetThelivConfig().Prometheus; promcfg != nil {
+		return &promcfg.PrometheusEndpoint
+	}
+	return &config.PrometheusEndpoint{}
+}
+
+func clusterLabel(endpoint *config.PrometheusEndpoint, cluster string) (model.LabelName, string) {
+	value := endpoint.ClusterLabelValue
+	if value == "" {
+		value = cluster
+	}
+	return model.LabelName(endpoint.ClusterLabel), value
+}
+
+// Prometheus API of the endpoint, direct or proxied by the kubernetes API server of the cluster.
+func newAPI(ctx context.Context, kubeconfig *rest.Config, endpoint *config.PrometheusEndpoint) (v1.API, error) {
+	address, rt, err := newRoundTripper(ctx, kubeconfig, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client, err := api.NewClient(api.Config{
+		Address:      address,
+		RoundTripper: rt,
+	})
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Got error when creating Prometheus client, error is %s", err)
+		return nil, err
+	}
+	return v1.NewAPI(client), nil
+}
+
+// Returns the base address of the endpoint, and the transport with its credentials and tenant header.
+func newRoundTripper(ctx context.Context, kubeconfig *rest.Config, endpoint *config.PrometheusEndpoint) (
+	string, http.RoundTripper, error) {
+	var address string
+	var rt http.RoundTripper
+	var err error
+	if endpoint.Mode == config.PrometheusDirect {
+		address = endpoint.Address
+		rt, err = directRoundTripper(endpoint.Auth)
+	} else {
+		scheme := endpoint.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		address = kubeconfig.Host + "/api/v1/namespaces/" + endpoint.Namespace +
+			"/services/" + scheme + ":" + endpoint.Name + ":" + endpoint.Port + "/proxy"
+		rt, err = buildTLSRoundTripper(kubeconfig)
+		if err == nil {
+			rt = promconfig.NewAuthorizationCredentialsRoundTripper("Bearer",
+				promconfig.NewInlineSecret(kubeconfig.BearerToken), rt)
+		}
+	}
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to build TLS transport of %s: %s", address, err)
+		return "", nil, err
+	}
+	if endpoint.Tenant != "" {
+		header := endpoint.TenantHeader
+		if header == "" {
+			header = defaultTenantHeader
+		}
+		rt = promconfig.NewHeadersRoundTripper(&promconfig.Headers{
+			Headers: map[string]promconfig.Header{header: {Values: []string{endpoint.Tenant}}},
+		}, rt)
+	}
+	return address, rt, nil
+}
+
+// Transport with the configured auth, not the kubeconfig credentials.
+func directRoundTripper(auth *config.PrometheusAuth) (http.RoundTripper, error) {
+	if auth == nil {
+		return newTransport(nil, nil, nil, false)
+	}
+	rt, err := newTransport([]byte(auth.CA), []byte(auth.Cert), []byte(auth.Key), auth.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case auth.BearerToken != "":
+		rt = promconfig.NewAuthorizationCredentialsRoundTripper("Bearer", promconfig.NewInlineSecret(auth.BearerToken), rt)
+	case auth.Username != "":
+		rt = promconfig.NewBasicAuthRoundTripper(promconfig.NewInlineSecret(auth.Username),
+			promconfig.NewInlineSecret(auth.Password), rt)
+	}
+	return rt, nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/observability"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+const alertsResponse = `{"status":"success","data":{"alerts":[
+	{"labels":{"alertname":"PodNotReady","cluster":"c1"},"state":"firing","activeAt":"2024-01-01T00:00:00Z","value":"1"},
+	{"labels":{"alertname":"PodNotReady","cluster":"c2"},"state":"firing","activeAt":"2024-01-01T00:00:00Z","value":"1"}
+]}}`
+
+func TestDirectEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "theliv" || password != "secret" || r.Header.Get("X-Scope-OrgID") != "team-a" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/prometheus/api/v1/alerts":
+			w.Write([]byte(alertsResponse))
+		case "/prometheus/api/v1/query_range":
+			assert.Equal(t, `max(up{cluster="c1"})`, r.FormValue("query"))
+			w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"job":"kubelet"},"values":[[1704067200,"1"],[1704067260,"0"]]}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL + "/prometheus",
+			Tenant:       "team-a",
+			ClusterLabel: "cluster",
+			Auth:         &config.PrometheusAuth{Username: "theliv", Password: "secret"},
+		},
+	}
+	result, err := GetAlerts(context.Background(), input)
+	assert.Nil(t, err)
+	assert.Len(t, result.Alerts, 1)
+	assert.Equal(t, "c1", string(result.Alerts[0].Labels["cluster"]))
+
+	retriever := NewMetricRetriever(input)
+	assert.Equal(t, `cluster="c1"`, retriever.ClusterMatchers())
+	now := time.Now()
+	series, err := retriever.QueryRange(context.Background(), observability.MetricQueryCriteria{
+		Query:     `max(up{` + retriever.ClusterMatchers() + `})`,
+		StartTime: now.Add(-time.Hour),
+		EndTime:   now,
+		Step:      time.Minute,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, series, 1)
+	assert.Equal(t, "kubelet", series[0].Labels["job"])
+	assert.Len(t, series[0].Samples, 2)
+}
+
+func TestServiceProxyEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/monitoring/services/http:prometheus:9090/proxy/api/v1/alerts" ||
+			r.Header.Get("Authorization") != "Bearer kube-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(alertsResponse))
+	}))
+	defer server.Close()
+
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: server.URL, BearerToken: "kube-token"},
+		ClusterName: "c1",
+		Prometheus: &config.PrometheusEndpoint{
+			Namespace: "monitoring",
+			Name:      "prometheus",
+			Port:      "9090",
+			Scheme:    "http",
+		},
+	}
+	result, err := GetAlerts(context.Background(), input)
+	assert.Nil(t, err)
+	// no cluster label, all alerts belong to the cluster
+	assert.Len(t, result.Alerts, 2)
+	assert.Equal(t, "", NewMetricRetriever(input).ClusterMatchers())
+}
+
+func TestGetSuppressedAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/alertmanager/api/v2/alerts", r.URL.Path)
+		assert.Equal(t, "false", r.URL.Query().Get("active"))
+		assert.Equal(t, []string{`cluster="c1"`}, r.URL.Query()["filter"])
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"labels":{"alertname":"PodNotReady","cluster":"c1"},
+			"status":{"state":"suppressed","silencedBy":["s1"],"inhibitedBy":["a1"]}}]`))
+	}))
+	defer server.Close()
+
+	input := &problem.DetectorCreationInput{ClusterName: "c1", Prometheus: &config.PrometheusEndpoint{}}
+	alerts, err := GetSuppressedAlerts(context.Background(), input)
+	assert.Nil(t, err)
+	assert.Nil(t, alerts)
+
+	input.Prometheus.Alertmanager = &config.PrometheusEndpoint{
+		Mode:         config.PrometheusDirect,
+		Address:      server.URL + "/alertmanager",
+		ClusterLabel: "cluster",
+	}
+	alerts, err = GetSuppressedAlerts(context.Background(), input)
+	assert.Nil(t, err)
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, []string{"s1", "a1"}, alerts[0].SuppressedBy())
+}
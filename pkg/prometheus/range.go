@@ -0,0 +1,86 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/observability"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"k8s.io/client-go/rest"
+)
+
+const queryTimeout = 10 * time.Second
+
+// MetricRetriever runs range queries through the same client as the alerts.
+type MetricRetriever struct {
+	kubeconfig *rest.Config
+	endpoint   *config.PrometheusEndpoint
+	cluster    string
+}
+
+func NewMetricRetriever(input *problem.DetectorCreationInput) *MetricRetriever {
+	return &MetricRetriever{
+		kubeconfig: input.Kubeconfig,
+		endpoint:   getEndpoint(input.Prometheus),
+		cluster:    input.ClusterName,
+	}
+}
+
+// ClusterMatchers returns the matcher of the cluster label, empty if the endpoint has no cluster label.
+func (m *MetricRetriever) ClusterMatchers() string {
+	if m.endpoint.ClusterLabel == "" {
+		return ""
+	}
+	label, value := clusterLabel(m.endpoint, m.cluster)
+	return fmt.Sprintf("%s=%q", label, value)
+}
+
+func (m *MetricRetriever) QueryRange(ctx context.Context, criteria observability.MetricQueryCriteria) (
+	[]observability.MetricSeries, error) {
+	v1api, err := newAPI(ctx, m.kubeconfig, m.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	qctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	value, _, err := v1api.QueryRange(qctx, criteria.Query, v1.Range{
+		Start: criteria.StartTime,
+		End:   criteria.EndTime,
+		Step:  criteria.Step,
+	})
+	if err != nil {
+		return nil, err
+	}
+	matrix, ok := value.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s of range query", value.Type())
+	}
+	return toSeries(matrix), nil
+}
+
+func toSeries(matrix model.Matrix) []observability.MetricSeries {
+	result := make([]observability.MetricSeries, 0, len(matrix))
+	for _, stream := range matrix {
+		series := observability.MetricSeries{Labels: make(map[string]string, len(stream.Metric))}
+		for k, v := range stream.Metric {
+			series.Labels[string(k)] = string(v)
+		}
+		for _, v := range stream.Values {
+			series.Samples = append(series.Samples, observability.MetricSample{
+				Timestamp: v.Timestamp.Time(),
+				Value:     float64(v.Value),
+			})
+		}
+		result = append(result, series)
+	}
+	return result
+}
@@ -0,0 +1,289 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	errors "github.com/fidelity/theliv/pkg/err"
+	log "github.com/fidelity/theliv/pkg/log"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// job of kube-state-metrics in the rule expressions
+	kubeStateMetricsJob = "kube-state-metrics"
+
+	PrometheusRuleAPIVersion = "monitoring.coreos.com/v1"
+	PrometheusRuleKind       = "PrometheusRule"
+)
+
+// RulesFile is a Prometheus rules file, and the spec of PrometheusRule.
+type RulesFile struct {
+	Groups []RuleGroup `json:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string         `json:"name"`
+	Rules []AlertingRule `json:"rules"`
+}
+
+type AlertingRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type PrometheusRule struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   PrometheusRuleMeta `json:"metadata"`
+	Spec       RulesFile          `json:"spec"`
+}
+
+type PrometheusRuleMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// DefaultRuleGroups are the alerting rules Theliv requires, the same as alerting_rules.md.
+var DefaultRuleGroups = []RuleGroup{
+	{
+		Name: "theliv-pod",
+		Rules: []AlertingRule{
+			newRule("PodNotRunning", com.Pod,
+				`kube_pod_status_phase{job='kube-state-metrics',phase=~'Failed|Pending|Unknown'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Pod {{$labels.pod}} in namespace {{$labels.namespace}} is in {{$labels.phase}} status."),
+			newRule("PodNotReady", com.Pod,
+				`kube_pod_status_phase{job='kube-state-metrics',phase='Running'} * on(uid) group_left(condition) kube_pod_status_ready{job='kube-state-metrics', condition='false'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Pod {{$labels.pod}} in namespace {{$labels.namespace}} is running but not ready."),
+		},
+	},
+	{
+		Name: "theliv-container",
+		Rules: []AlertingRule{
+			newRule("ContainerWaitingAsCrashLoopBackoff", com.Container,
+				`kube_pod_container_status_waiting_reason{job='kube-state-metrics', reason='CrashLoopBackOff'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerWaitingAsImagePullBackOff", com.Container,
+				`kube_pod_container_status_waiting_reason{job='kube-state-metrics', reason=~'ImagePullBackOff|ErrImagePull|InvalidImageName'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerWaitingAsCreateContainerError", com.Container,
+				`kube_pod_container_status_waiting_reason{job='kube-state-metrics', reason=~'CreateContainerConfigError|CreateContainerError'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerTerminatedAsOOMKilled", com.Container,
+				`kube_pod_container_status_terminated_reason{job='kube-state-metrics',reason='OOMKilled'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerTerminatedAsError", com.Container,
+				`kube_pod_container_status_terminated_reason{job='kube-state-metrics',reason='Error'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerTerminatedAsContainerCannotRun", com.Container,
+				`kube_pod_container_status_terminated_reason{job='kube-state-metrics',reason='ContainerCannotRun'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerTerminatedAsDeadlineExceeded", com.Container,
+				`kube_pod_container_status_terminated_reason{job='kube-state-metrics',reason='DeadlineExceeded'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("ContainerTerminatedAsEvicted", com.Container,
+				`kube_pod_container_status_terminated_reason{job='kube-state-metrics',reason='Evicted'} * on(uid, container) group_left(image) kube_pod_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+		},
+	},
+	{
+		Name: "theliv-initcontainer",
+		Rules: []AlertingRule{
+			newRule("InitContainerWaitingAsCrashLoopBackoff", com.Initcontainer,
+				`kube_pod_init_container_status_waiting_reason{job='kube-state-metrics', reason='CrashLoopBackOff'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("InitContainerWaitingAsImagePullBackOff", com.Initcontainer,
+				`kube_pod_init_container_status_waiting_reason{job='kube-state-metrics', reason=~'ImagePullBackOff|ErrImagePull|InvalidImageName'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("InitContainerTerminatedAsOOMKilled", com.Initcontainer,
+				`kube_pod_init_container_status_terminated_reason{job='kube-state-metrics',reason='OOMKilled'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("InitContainerTerminatedAsError", com.Initcontainer,
+				`kube_pod_init_container_status_terminated_reason{job='kube-state-metrics',reason='Error'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("InitContainerTerminatedAsContainerCannotRun", com.Initcontainer,
+				`kube_pod_init_container_status_terminated_reason{job='kube-state-metrics',reason='ContainerCannotRun'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("InitContainerTerminatedAsDeadlineExceeded", com.Initcontainer,
+				`kube_pod_init_container_status_terminated_reason{job='kube-state-metrics',reason='DeadlineExceeded'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+			newRule("InitContainerTerminatedAsEvicted", com.Initcontainer,
+				`kube_pod_init_container_status_terminated_reason{job='kube-state-metrics',reason='Evicted'} * on(uid, container) group_left(image) kube_pod_init_container_info{job='kube-state-metrics'} * on(uid) group_left(owner_kind, owner_is_controller, owner_name) kube_pod_owner{job='kube-state-metrics'} >0`,
+				"Init container {{$labels.container}} of pod {{$labels.pod}} in namespace {{$labels.namespace}} is {{$labels.reason}}."),
+		},
+	},
+	{
+		Name: "theliv-deployment",
+		Rules: []AlertingRule{
+			newRule("DeploymentNotAvailable", com.Deployment,
+				`kube_deployment_status_condition{job='kube-state-metrics', condition='Available', status!='true'} >0`,
+				"Deployment {{$labels.deployment}} in namespace {{$labels.namespace}} is not available."),
+			newRule("DeploymentGenerationMismatch", com.Deployment,
+				`kube_deployment_status_observed_generation{job='kube-state-metrics'} - on (deployment, namespace) kube_deployment_metadata_generation{job='kube-state-metrics'} !=0`,
+				"Deployment {{$labels.deployment}} in namespace {{$labels.namespace}} generation is not observed by the controller."),
+			newRule("DeploymentReplicasMismatch", com.Deployment,
+				`(kube_deployment_spec_replicas{job='kube-state-metrics'} - on(deployment, namespace) kube_deployment_status_replicas_available{job='kube-state-metrics'} !=0 ) and (kube_deployment_status_replicas_updated ==0)`,
+				"Deployment {{$labels.deployment}} in namespace {{$labels.namespace}} does not have the desired number of available replicas."),
+		},
+	},
+	{
+		Name: "theliv-node",
+		Rules: []AlertingRule{
+			newRule("NodeNotReady", com.Node,
+				`kube_node_status_condition{job='kube-state-metrics', condition!='Ready', status=~'true|unknown'} >0`,
+				"Node {{$labels.node}} condition {{$labels.condition}} is {{$labels.status}}."),
+			newRule("NodeDiskPressure", com.Node,
+				`kube_node_status_condition{job='kube-state-metrics', condition='DiskPressure', status='true'} >0`,
+				"Node {{$labels.node}} has condition {{$labels.condition}}."),
+			newRule("NodeMemoryPressure", com.Node,
+				`kube_node_status_condition{job='kube-state-metrics', condition='MemoryPressure', status='true'} >0`,
+				"Node {{$labels.node}} has condition {{$labels.condition}}."),
+			newRule("NodePIDPressure", com.Node,
+				`kube_node_status_condition{job='kube-state-metrics', condition='PIDPressure', status='true'} >0`,
+				"Node {{$labels.node}} has condition {{$labels.condition}}."),
+			newRule("NodeNetworkUnavailable", com.Node,
+				`kube_node_status_condition{job='kube-state-metrics', condition='NetworkUnavailable', status='true'} >0`,
+				"Node {{$labels.node}} has condition {{$labels.condition}}."),
+		},
+	},
+	{
+		Name: "theliv-endpoint",
+		Rules: []AlertingRule{
+			newRule("EndpointAddressNotAvailable", com.Endpoint,
+				`kube_endpoint_address_available{job='kube-state-metrics'} == 0`,
+				"Endpoint {{$labels.endpoint}} in namespace {{$labels.namespace}} has no available address."),
+		},
+	},
+	{
+		Name: "theliv-statefulset",
+		Rules: []AlertingRule{
+			newRule("StatefulsetGenerationMismatch", com.Statefulset,
+				`kube_statefulset_status_observed_generation{job='kube-state-metrics'} - on (statefulset, namespace) kube_statefulset_metadata_generation{job='kube-state-metrics'} !=0`,
+				"StatefulSet {{$labels.statefulset}} in namespace {{$labels.namespace}} generation is not observed by the controller."),
+			newRule("StatefulsetReplicasMismatch", com.Statefulset,
+				`(kube_statefulset_replicas{job='kube-state-metrics'} - on(deployment, namespace) kube_statefulset_status_replicas_ready{job='kube-state-metrics'} !=0 ) and (kube_statefulset_status_replicas_updated{job='kube-state-metrics'} ==0)`,
+				"StatefulSet {{$labels.statefulset}} in namespace {{$labels.namespace}} does not have the desired number of ready replicas."),
+			newRule("StatefulsetUpdateNotRolledOut", com.Statefulset,
+				`(max without (revision) (kube_statefulset_status_current_revision{job='kube-state-metrics'} unless kube_statefulset_status_update_revision{job='kube-state-metrics'}) *  (kube_statefulset_replicas{job='kube-state-metrics'} != kube_statefulset_status_replicas_updated{job='kube-state-metrics'})) and (changes(kube_statefulset_status_replicas_updated{job='kube-state-metrics'}[5m]) == 0)`,
+				"StatefulSet {{$labels.statefulset}} in namespace {{$labels.namespace}} update is not rolled out."),
+		},
+	},
+	{
+		Name: "theliv-daemonset",
+		Rules: []AlertingRule{
+			newRule("DaemonSetRolloutStuck", com.Daemonset,
+				`((kube_daemonset_status_current_number_scheduled{job='kube-state-metrics'}!=kube_daemonset_status_desired_number_scheduled{job='kube-state-metrics'}) or (kube_daemonset_status_number_misscheduled{job='kube-state-metrics'}!=0) or (kube_daemonset_status_updated_number_scheduled{job='kube-state-metrics'}!=kube_daemonset_status_desired_number_scheduled{job='kube-state-metrics'}) or (kube_daemonset_status_number_available{job='kube-state-metrics'}!=kube_daemonset_status_desired_number_scheduled{job='kube-state-metrics'})) and (changes(kube_daemonset_status_updated_number_scheduled{job='kube-state-metrics'}[5m])==0)`,
+				"DaemonSet {{$labels.daemonset}} in namespace {{$labels.namespace}} rollout is stuck."),
+			newRule("DaemonSetNotScheduled", com.Daemonset,
+				`kube_daemonset_status_desired_number_scheduled{job='kube-state-metrics'} - kube_daemonset_status_current_number_scheduled{job='kube-state-metrics'} > 0`,
+				"DaemonSet {{$labels.daemonset}} in namespace {{$labels.namespace}} pods are not scheduled."),
+			newRule("DaemonSetMissScheduled", com.Daemonset,
+				`kube_daemonset_status_number_misscheduled{job='kube-state-metrics'}>0`,
+				"DaemonSet {{$labels.daemonset}} in namespace {{$labels.namespace}} pods are running where they are not supposed to run."),
+			newRule("DaemonSetUnavailable", com.Daemonset,
+				`kube_daemonset_status_number_unavailable{job='kube-state-metrics'} >0`,
+				"DaemonSet {{$labels.daemonset}} in namespace {{$labels.namespace}} pods are not available."),
+		},
+	},
+}
+
+func newRule(alert string, resourceType string, expr string, description string) AlertingRule {
+	return AlertingRule{
+		Alert:       alert,
+		Expr:        expr,
+		Labels:      map[string]string{com.Resourcetype: resourceType},
+		Annotations: map[string]string{"description": description},
+	}
+}
+
+// RequiredAlerts returns the names of the default alerting rules.
+func RequiredAlerts() []string {
+	alerts := make([]string, 0)
+	for _, g := range DefaultRuleGroups {
+		for _, r := range g.Rules {
+			alerts = append(alerts, r.Alert)
+		}
+	}
+	return alerts
+}
+
+// RenderRulesFile renders the default alerting rules as a Prometheus rules file.
+func RenderRulesFile() ([]byte, error) {
+	return yaml.Marshal(RulesFile{Groups: DefaultRuleGroups})
+}
+
+// RenderPrometheusRule renders the default alerting rules as a PrometheusRule manifest,
+// labels are usually the ruleSelector of the Prometheus operator.
+func RenderPrometheusRule(name string, namespace string, labels map[string]string) ([]byte, error) {
+	return yaml.Marshal(PrometheusRule{
+		APIVersion: PrometheusRuleAPIVersion,
+		Kind:       PrometheusRuleKind,
+		Metadata:   PrometheusRuleMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec:       RulesFile{Groups: DefaultRuleGroups},
+	})
+}
+
+// GetLoadedAlerts returns the names of the alerting rules loaded by Prometheus.
+func GetLoadedAlerts(ctx context.Context, input *problem.DetectorCreationInput) (map[string]bool, error) {
+	v1api, err := newAPI(ctx, input.Kubeconfig, getEndpoint(input.Prometheus))
+	if err != nil {
+		return nil, err
+	}
+	qctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	result, err := v1api.Rules(qctx)
+	if err != nil {
+		err = errors.NewCommonError(ctx, 6, err.Error())
+		log.SWithContext(ctx).Errorf("Got error when getting prometheus rules, error is %s", err)
+		return nil, err
+	}
+	alerts := make(map[string]bool)
+	for _, g := range result.Groups {
+		for _, r := range g.Rules {
+			if rule, ok := r.(v1.AlertingRule); ok {
+				alerts[rule.Name] = true
+			}
+		}
+	}
+	return alerts, nil
+}
+
+// IsKubeStateMetricsScraped returns true if a kube-state-metrics target of the cluster is up.
+func IsKubeStateMetricsScraped(ctx context.Context, input *problem.DetectorCreationInput) (bool, error) {
+	retriever := NewMetricRetriever(input)
+	v1api, err := newAPI(ctx, retriever.kubeconfig, retriever.endpoint)
+	if err != nil {
+		return false, err
+	}
+	query := `up{job="` + kubeStateMetricsJob + `"}`
+	if matchers := retriever.ClusterMatchers(); matchers != "" {
+		query = `up{job="` + kubeStateMetricsJob + `",` + matchers + `}`
+	}
+	qctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	value, _, err := v1api.Query(qctx, query, time.Now())
+	if err != nil {
+		err = errors.NewCommonError(ctx, 6, err.Error())
+		log.SWithContext(ctx).Errorf("Got error when querying kube-state-metrics target, error is %s", err)
+		return false, err
+	}
+	vector, _ := value.(model.Vector)
+	for _, s := range vector {
+		if s.Value == 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
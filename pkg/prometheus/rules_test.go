@@ -0,0 +1,51 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package prometheus
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+// The default rules are the same as the documented rules.
+func TestDefaultRuleGroups(t *testing.T) {
+	doc, err := os.ReadFile("../../alerting_rules.md")
+	assert.Nil(t, err)
+	rows := regexp.MustCompile("(?m)^\\| (\\w+) \\| `(.*?)\\s*` \\|").FindAllStringSubmatch(string(doc), -1)
+	documented := make(map[string]string, len(rows))
+	for _, row := range rows {
+		documented[row[1]] = row[2]
+	}
+	rules := make(map[string]string)
+	for _, g := range DefaultRuleGroups {
+		for _, r := range g.Rules {
+			rules[r.Alert] = r.Expr
+			assert.NotEmpty(t, r.Labels["resourcetype"], r.Alert)
+		}
+	}
+	assert.Equal(t, documented, rules)
+	assert.Len(t, RequiredAlerts(), len(rules))
+}
+
+func TestRenderPrometheusRule(t *testing.T) {
+	b, err := RenderPrometheusRule("theliv-rules", "monitoring", map[string]string{"release": "prometheus"})
+	assert.Nil(t, err)
+	rule := PrometheusRule{}
+	assert.Nil(t, yaml.Unmarshal(b, &rule))
+	assert.Equal(t, PrometheusRuleKind, rule.Kind)
+	assert.Equal(t, "prometheus", rule.Metadata.Labels["release"])
+	assert.Equal(t, DefaultRuleGroups, rule.Spec.Groups)
+
+	b, err = RenderRulesFile()
+	assert.Nil(t, err)
+	file := RulesFile{}
+	assert.Nil(t, yaml.Unmarshal(b, &file))
+	assert.Equal(t, DefaultRuleGroups, file.Groups)
+}
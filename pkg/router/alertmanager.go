@@ -0,0 +1,40 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func Alertmanager(r chi.Router) {
+	r.Post("/webhook", alertmanagerWebhook)
+}
+
+// Receives the alertmanager webhook, the access key of alertmanager should be granted /alertmanager/webhook.
+// Detections run in background, returns 202 with the scheduled namespaces, or 400 if the payload is invalid.
+// Namespaces detected within the cooldown are not scheduled, as alertmanager re-sends the firing groups.
+func alertmanagerWebhook(w http.ResponseWriter, r *http.Request) {
+	payload := &service.AlertmanagerWebhook{}
+	if err := decodeBody(r.Body, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	namespaces := service.AffectedNamespaces(payload, config.GetThelivConfig().Alertmanager)
+	log.SWithContext(r.Context()).Infof("Received %d alerts from alertmanager receiver %s, %d namespaces affected",
+		len(payload.Alerts), payload.Receiver, len(namespaces))
+
+	// not canceled when the response is sent
+	scheduled := service.ScheduleDetections(context.WithoutCancel(r.Context()), namespaces)
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, scheduled)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/fidelity/theliv/pkg/auth/authmiddleware"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	theErr "github.com/fidelity/theliv/pkg/err"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// Response header of detect API, the ID of the stored detection.
+const DetectionIDHeader = "X-Detection-Id"
+
+func Detections(r chi.Router) {
+	r.Get("/{id}", getDetection)
+	r.Get("/{id}/diff", diffDetection)
+	r.Get("/diff/{cluster}/{namespace}", diffLatestDetections)
+}
+
+func getDetection(w http.ResponseWriter, r *http.Request) {
+	detection, ok := loadDetection(w, r, chi.URLParam(r, "id"))
+	if ok {
+		render.JSON(w, r, detection)
+	}
+}
+
+// Compares the detection with the base detection in query parameter "base".
+func diffDetection(w http.ResponseWriter, r *http.Request) {
+	target, ok := loadDetection(w, r, chi.URLParam(r, "id"))
+	if !ok {
+		return
+	}
+	base, ok := loadDetection(w, r, r.URL.Query().Get("base"))
+	if !ok {
+		return
+	}
+	diff := service.DiffDetections(base, target)
+	if diff == nil {
+		groupingDiffers(w, r)
+		return
+	}
+	render.JSON(w, r, diff)
+}
+
+// Compares the latest detection of the cluster and namespace with the previous one.
+func diffLatestDetections(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cluster := chi.URLParam(r, "cluster")
+	namespace := chi.URLParam(r, "namespace")
+	if !checkDetectorPath(w, r, service.DetectorPath(cluster, namespace)) {
+		return
+	}
+	base, target, err := service.GetLatestDetections(ctx, cluster, namespace)
+	if err != nil {
+		processError(w, r, err)
+		return
+	}
+	if base == nil {
+		detectionNotFound(w, r)
+		return
+	}
+	render.JSON(w, r, service.DiffDetections(base, target))
+}
+
+// Load the detection and check RBAC, writes the error response and returns false if failed.
+func loadDetection(w http.ResponseWriter, r *http.Request, id string) (*service.Detection, bool) {
+	detection, err := service.GetDetection(r.Context(), id)
+	if err != nil {
+		processError(w, r, err)
+		return nil, false
+	}
+	if detection == nil {
+		detectionNotFound(w, r)
+		return nil, false
+	}
+	if !checkDetectorPath(w, r, detection.DetectorPath()) {
+		return nil, false
+	}
+	return detection, true
+}
+
+// Same RBAC check as the detect API of the detection cluster and namespace.
+func checkDetectorPath(w http.ResponseWriter, r *http.Request, path string) bool {
+	ok, err := authmiddleware.CheckPath(r, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		http.Error(w, "", http.StatusForbidden)
+	}
+	return ok
+}
+
+func groupingDiffers(w http.ResponseWriter, r *http.Request) {
+	contact := fmt.Sprintf(com.Contact, config.GetThelivConfig().TeamName)
+	w.WriteHeader(http.StatusConflict)
+	render.JSON(w, r, theErr.NewCommonError(r.Context(), theErr.API, com.DetectionGroupingDiffs+contact))
+}
+
+func detectionNotFound(w http.ResponseWriter, r *http.Request) {
+	contact := fmt.Sprintf(com.Contact, config.GetThelivConfig().TeamName)
+	w.WriteHeader(http.StatusNotFound)
+	render.JSON(w, r, theErr.NewCommonError(r.Context(), 7, com.DetectionNotFound+contact))
+}
+
+// Store the detection result, failure is logged only, the detection result is still returned.
+// Returns the ID of the stored detection, empty if not stored.
+func saveDetection(ctx context.Context, r *http.Request, result interface{}) string {
+	user := ""
+	if u, err := authmiddleware.GetUser(r, false); err == nil && u != nil {
+		user = u.UID
+	}
+	detection, err := service.SaveDetection(ctx, user, result)
+	if err != nil {
+		log.SWithContext(ctx).Warnf("Detection result is not saved, error is %s", err)
+		return ""
+	}
+	if detection == nil {
+		return ""
+	}
+	return detection.ID
+}
@@ -24,16 +24,23 @@ func Detector(r chi.Router) {
 	r.Get("/{cluster}/{namespace}/event", getK8sNsEvents)
 }
 
+// Streams the detection with Server-Sent Events if the request accepts text/event-stream.
 func detectPrometheusAlerts(w http.ResponseWriter, r *http.Request) {
-
+	if acceptsEventStream(r) {
+		streamDetection(w, r)
+		return
+	}
 	ctx, err := createDetectorInputWithContext(r)
 	if err != nil {
 		processError(w, r, err)
-	} else {
-		con, err := service.DetectAlerts(ctx)
+	} else if setDetectWindow(ctx, w, r) && setGrouping(ctx, w, r) {
+		con, err := service.Detect(ctx)
 		if err != nil {
 			processError(w, r, err)
 		} else {
+			if id := saveDetection(ctx, r, con); id != "" {
+				w.Header().Set(DetectionIDHeader, id)
+			}
 			render.JSON(w, r, con)
 		}
 	}
@@ -81,6 +88,7 @@ func createDetectorInputWithContext(r *http.Request) (context.Context, error) {
 		Kubeconfig:  k8sconfig,
 		ClusterName: cluster,
 		Namespace:   namespace,
+		Prometheus:  conf.GetPrometheusEndpoint(),
 		// AwsConfig:   ac,
 	}
 
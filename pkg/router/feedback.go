@@ -23,9 +23,13 @@ type FeebackData struct {
 	User    *rbac.User `json:"user,omitempty"`
 	Time    time.Time  `json:"time"`
 	Message string     `json:"message"`
+	Code    string     `json:"code,omitempty"`
 }
+
+// Code is optional, the error code of the issue the feedback is about, e.g. IMAGEPULL_ERR.
 type Feedback struct {
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
 }
 
 func SubmitFeedback(r chi.Router) {
@@ -48,6 +52,7 @@ func SubmitFeedback(r chi.Router) {
 			User:    user,
 			Time:    currentTime,
 			Message: d.Message,
+			Code:    d.Code,
 		}
 
 		err = etcd.Put(key, data)
@@ -0,0 +1,44 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/service"
+)
+
+// Sets the report card grouping strategies from the query parameter, writes 400 and returns false if invalid.
+func setGrouping(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
+	grouping, err := parseGrouping(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	service.GetDetectorInput(ctx).Grouping = grouping
+	return true
+}
+
+// Parses "grouping", comma separated or repeated, e.g. grouping=label:app.kubernetes.io/part-of,helm.
+// Returns nil if not set.
+func parseGrouping(query url.Values) ([]string, error) {
+	var grouping []string
+	for _, v := range query["grouping"] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				grouping = append(grouping, s)
+			}
+		}
+	}
+	if err := problem.ValidateGrouping(grouping); err != nil {
+		return nil, err
+	}
+	return grouping, nil
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGrouping(t *testing.T) {
+	grouping, err := parseGrouping(url.Values{})
+	assert.Nil(t, err)
+	assert.Nil(t, grouping)
+
+	grouping, err = parseGrouping(url.Values{"grouping": {"label:app.kubernetes.io/part-of, helm", "owner"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"label:app.kubernetes.io/part-of", "helm", "owner"}, grouping)
+
+	_, err = parseGrouping(url.Values{"grouping": {"team"}})
+	assert.NotNil(t, err)
+}
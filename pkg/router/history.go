@@ -0,0 +1,87 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/service"
+)
+
+// Max duration of the window of historical detection.
+const maxWindow = 7 * 24 * time.Hour
+
+// Sets the window of historical detection from the query parameters, writes 400 and returns false if invalid.
+func setDetectWindow(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
+	window, err := parseWindow(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	service.GetDetectorInput(ctx).Window = window
+	return true
+}
+
+// Parses "at", or "from" and optional "to" defaulting to now, in RFC3339 or unix seconds.
+// Returns nil if not a historical detection.
+func parseWindow(query url.Values, now time.Time) (*problem.TimeWindow, error) {
+	at, from, to := query.Get("at"), query.Get("from"), query.Get("to")
+	switch {
+	case at != "" && (from != "" || to != ""):
+		return nil, errors.New("at can not be used with from and to")
+	case at != "":
+		t, err := parseTime(at)
+		if err != nil {
+			return nil, err
+		}
+		return checkWindow(&problem.TimeWindow{From: t, To: t}, now)
+	case from != "":
+		window := &problem.TimeWindow{To: now}
+		var err error
+		if window.From, err = parseTime(from); err != nil {
+			return nil, err
+		}
+		if to != "" {
+			if window.To, err = parseTime(to); err != nil {
+				return nil, err
+			}
+		}
+		return checkWindow(window, now)
+	case to != "":
+		return nil, errors.New("from is required with to")
+	}
+	return nil, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid time " + value + ", should be RFC3339 or unix seconds")
+	}
+	return t, nil
+}
+
+func checkWindow(window *problem.TimeWindow, now time.Time) (*problem.TimeWindow, error) {
+	if window.To.After(now) {
+		return nil, errors.New("time window should not be in the future")
+	}
+	if window.From.After(window.To) {
+		return nil, errors.New("from should not be after to")
+	}
+	if window.To.Sub(window.From) > maxWindow {
+		return nil, errors.New("time window should not be longer than 7 days")
+	}
+	return window, nil
+}
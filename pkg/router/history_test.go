@@ -0,0 +1,43 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWindow(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	window, err := parseWindow(url.Values{}, now)
+	assert.Nil(t, err)
+	assert.Nil(t, window)
+
+	window, err = parseWindow(url.Values{"at": {"2024-01-01T12:00:00Z"}}, now)
+	assert.Nil(t, err)
+	assert.True(t, window.From.Equal(at) && window.To.Equal(at))
+
+	window, err = parseWindow(url.Values{"from": {"1704110400"}}, now)
+	assert.Nil(t, err)
+	assert.True(t, window.From.Equal(at) && window.To.Equal(now))
+
+	for _, query := range []url.Values{
+		{"at": {"yesterday"}},
+		{"at": {"2024-01-01T12:00:00Z"}, "from": {"2024-01-01T11:00:00Z"}},
+		{"to": {"2024-01-01T12:00:00Z"}},
+		{"from": {"2024-01-01T12:00:00Z"}, "to": {"2024-01-01T11:00:00Z"}},
+		{"at": {"2024-01-03T00:00:00Z"}},
+		{"from": {"2023-12-01T00:00:00Z"}},
+	} {
+		_, err = parseWindow(query, now)
+		assert.NotNil(t, err, query.Encode())
+	}
+}
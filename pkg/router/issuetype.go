@@ -0,0 +1,21 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"net/http"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// List all the issue types Theliv can emit, with error code, domain and default severity.
+func IssueTypes(r chi.Router) {
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		render.Respond(w, r, problem.ListIssueTypes())
+	})
+}
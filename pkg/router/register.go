@@ -25,6 +25,13 @@ const (
 	errClusterNameInvalid  = "cluster name contains invalid characters"
 )
 
+type registerResponse struct {
+	Status string `json:"status"`
+	// nil if the rules are not checked
+	Rules    *service.RulesCheck `json:"rules,omitempty"`
+	Warnings []string            `json:"warnings,omitempty"`
+}
+
 var clusterNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]{0,61}[a-z0-9])?$`)
 
 func Register(r chi.Router) {
@@ -36,7 +43,8 @@ func Register(r chi.Router) {
 // If cluster name is less than 3 characters, 400 will be returned.
 // Request body should include {"Url": "", "CA": "", "Token": ""}, or 400 will be returned.
 // If backend DB operation failed, return 503.
-// if backend DB operation success, return "Registered".
+// if backend DB operation success, return {"status": "Registered"} with the alerting rules check of the cluster,
+// misconfigurations are in the warnings, the registration succeeds anyway. See docs/api/register.md.
 func clusterRegister(w http.ResponseWriter, r *http.Request) {
 	cluster := chi.URLParam(r, "cluster")
 
@@ -78,7 +86,8 @@ func clusterRegister(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, SERVICE_UNAVAILABLE, http.StatusServiceUnavailable)
 		return
 	}
-	render.Respond(w, r, Registered)
+	check, warnings := service.CheckRegisteredRules(r.Context(), basic.Name)
+	render.JSON(w, r, registerResponse{Status: Registered, Rules: check, Warnings: warnings})
 }
 
 // validateClusterName checks for path traversal and validates cluster name format
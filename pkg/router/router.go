@@ -58,6 +58,15 @@ func Route(r chi.Router) {
 	// detector
 	r.Route("/detector", Detector)
 
+	// stored detection results
+	r.Route("/detections", Detections)
+
+	// alertmanager webhook receiver
+	r.Route("/alertmanager", Alertmanager)
+
+	// issue taxonomy
+	r.Route("/issuetypes", IssueTypes)
+
 	// userinfo
 	r.Route("/userinfo", Userinfo)
 
@@ -73,6 +82,9 @@ func Route(r chi.Router) {
 	// new cluster registration
 	r.Route("/register", Register)
 
+	// required alerting rules
+	r.Route("/rules", Rules)
+
 	// config for UI
 	r.Route("/configinfo", ConfigInfo)
 
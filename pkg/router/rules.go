@@ -0,0 +1,97 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	"github.com/fidelity/theliv/pkg/service"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultRuleName      = "theliv-rules"
+	defaultRuleNamespace = "monitoring"
+)
+
+func Rules(r chi.Router) {
+	// GET /prometheusrule?name=&namespace=&labels=k1=v1,k2=v2
+	r.Get("/prometheusrule", getPrometheusRule)
+	// GET /file
+	r.Get("/file", getRulesFile)
+	// GET /{cluster}/check
+	r.Get("/{cluster}/check", checkRules)
+}
+
+// Renders the required alerting rules as a PrometheusRule manifest in yaml.
+// Query parameter labels are the labels of the manifest, usually the ruleSelector of the Prometheus operator.
+func getPrometheusRule(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name, namespace := query.Get("name"), query.Get("namespace")
+	if name == "" {
+		name = defaultRuleName
+	}
+	if namespace == "" {
+		namespace = defaultRuleNamespace
+	}
+	labels, ok := parseLabels(query.Get("labels"))
+	if !ok {
+		http.Error(w, "labels should be in format k1=v1,k2=v2", http.StatusBadRequest)
+		return
+	}
+	b, err := prometheus.RenderPrometheusRule(name, namespace, labels)
+	writeYaml(w, r, b, err)
+}
+
+// Renders the required alerting rules as a Prometheus rules file in yaml.
+func getRulesFile(w http.ResponseWriter, r *http.Request) {
+	b, err := prometheus.RenderRulesFile()
+	writeYaml(w, r, b, err)
+}
+
+// Checks the required alerting rules and kube-state-metrics of the cluster.
+// The rules of all namespaces are checked, the user must be granted the detect API of the whole cluster.
+func checkRules(w http.ResponseWriter, r *http.Request) {
+	cluster := chi.URLParam(r, "cluster")
+	if !checkDetectorPath(w, r, service.ClusterDetectorPath(cluster)) {
+		return
+	}
+	check, err := service.CheckRules(r.Context(), cluster)
+	if err != nil {
+		processError(w, r, err)
+		return
+	}
+	render.JSON(w, r, check)
+}
+
+func writeYaml(w http.ResponseWriter, r *http.Request, b []byte, err error) {
+	if err != nil {
+		log.SWithContext(r.Context()).Errorf("Failed to render alerting rules, error is %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(b)
+}
+
+func parseLabels(value string) (map[string]string, bool) {
+	if value == "" {
+		return nil, true
+	}
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, false
+		}
+		labels[k] = v
+	}
+	return labels, true
+}
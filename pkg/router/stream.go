@@ -0,0 +1,122 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/fidelity/theliv/internal/problem"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/service"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Server-Sent Events of the streaming detection.
+const (
+	EventProgress = "progress"
+	EventCard     = "card"
+	EventRanked   = "ranked"
+	EventError    = "error"
+	EventDone     = "done"
+)
+
+// cardRank is the root cause and score of a streamed report card, they are set after all the cards are complete.
+type cardRank struct {
+	ID                   string                   `json:"id"`
+	Score                float64                  `json:"score"`
+	ScoreBreakdown       *problem.ScoreBreakdown  `json:"scoreBreakdown,omitempty"`
+	RootCause            *problem.ReportCardIssue `json:"rootCause"`
+	Hypotheses           []*problem.Hypothesis    `json:"hypotheses,omitempty"`
+	ContributingFindings []*problem.Finding       `json:"contributingFindings,omitempty"`
+}
+
+type streamDone struct {
+	RequestID   string `json:"requestId,omitempty"`
+	DetectionID string `json:"detectionId,omitempty"`
+	Cards       int    `json:"cards"`
+}
+
+// sseWriter writes events to the response, investigators report progress concurrently.
+type sseWriter struct {
+	mx      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) send(event string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		log.S().Errorf("Failed to marshal %s event, error is %s", event, err)
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	s.flusher.Flush()
+}
+
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// Same path as the detect API, so the same RBAC checks apply. Progress events are sent while detecting,
+// each report card is sent as soon as its problems are investigated. When all the cards are complete,
+// the ranked event has the root causes and scores, sorted by score, then the done event has the stored detection ID.
+func streamDetection(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	ctx, err := createDetectorInputWithContext(r)
+	if err != nil {
+		processError(w, r, err)
+		return
+	}
+	if !setDetectWindow(ctx, w, r) || !setGrouping(ctx, w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	sse := &sseWriter{w: w, flusher: flusher}
+
+	ctx = service.SetProgressFunc(ctx, func(event service.ProgressEvent) {
+		sse.send(EventProgress, event)
+	})
+	ctx = service.SetCardFunc(ctx, func(card *problem.ReportCard) {
+		sse.send(EventCard, card)
+	})
+	con, err := service.DetectAlerts(ctx)
+	if err != nil {
+		sse.send(EventError, err)
+		return
+	}
+	cards, _ := con.([]*problem.ReportCard)
+	ranks := make([]cardRank, 0, len(cards))
+	for _, card := range cards {
+		ranks = append(ranks, cardRank{
+			ID:                   card.ID,
+			Score:                card.Score,
+			ScoreBreakdown:       card.ScoreBreakdown,
+			RootCause:            card.RootCause,
+			Hypotheses:           card.Hypotheses,
+			ContributingFindings: card.ContributingFindings,
+		})
+	}
+	sse.send(EventRanked, ranks)
+	sse.send(EventDone, streamDone{
+		RequestID:   middleware.GetReqID(ctx),
+		DetectionID: saveDetection(ctx, r, con),
+		Cards:       len(cards),
+	})
+}
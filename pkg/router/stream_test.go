@@ -0,0 +1,30 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fidelity/theliv/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sse := &sseWriter{w: rec, flusher: rec}
+	sse.send(EventProgress, service.ProgressEvent{Stage: service.StageAlertsFetched, Count: 3})
+
+	assert.Equal(t, "event: progress\ndata: {\"stage\":\"alerts\",\"count\":3}\n\n", rec.Body.String())
+	assert.True(t, rec.Flushed)
+}
+
+func TestAcceptsEventStream(t *testing.T) {
+	r := httptest.NewRequest("GET", "/theliv-api/v1/detector/c1/ns1/detect", nil)
+	assert.False(t, acceptsEventStream(r))
+	r.Header.Set("Accept", "text/event-stream")
+	assert.True(t, acceptsEventStream(r))
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// Drops or flags the problems of pending, short lived, silenced and inhibited alerts, by the alert filter config.
+func filterAlertProblems(ctx context.Context, input *problem.DetectorCreationInput, problems []*problem.Problem,
+	now time.Time) []*problem.Problem {
+	filter := &config.AlertFilterConfig{}
+	if promcfg := config.GetThelivConfig().Prometheus; promcfg != nil && promcfg.AlertFilter != nil {
+		filter = promcfg.AlertFilter
+	}
+	suppressed, err := prometheus.GetSuppressedAlerts(ctx, input)
+	if err != nil {
+		// alerts are kept if alertmanager is not available
+		log.SWithContext(ctx).Warnf("Failed to get suppressed alerts from alertmanager, error is %s", err)
+	}
+	return applyAlertFilter(ctx, filter, problems, suppressed, now)
+}
+
+func applyAlertFilter(ctx context.Context, filter *config.AlertFilterConfig, problems []*problem.Problem,
+	suppressed []prometheus.SuppressedAlert, now time.Time) []*problem.Problem {
+	minActive := time.Duration(filter.MinActiveSeconds) * time.Second
+	results := make([]*problem.Problem, 0, len(problems))
+	for _, p := range problems {
+		if filter.DropPending && p.AlertState == string(v1.AlertStatePending) {
+			continue
+		}
+		if minActive > 0 && !p.ActiveAt.IsZero() && now.Sub(p.ActiveAt) < minActive {
+			continue
+		}
+		if by := suppressedBy(p, suppressed); len(by) > 0 {
+			if !filter.FlagSuppressed {
+				continue
+			}
+			p.SuppressedBy = by
+		}
+		results = append(results, p)
+	}
+	if dropped := len(problems) - len(results); dropped > 0 {
+		log.SWithContext(ctx).Infof("Dropped %d alerts by alert filter", dropped)
+	}
+	return results
+}
+
+// Alertmanager alerts have the external labels of Prometheus, matched if all the labels of the problem are equal.
+func suppressedBy(p *problem.Problem, suppressed []prometheus.SuppressedAlert) []string {
+	for i := range suppressed {
+		if labelsMatch(p.Tags, suppressed[i].Labels) {
+			return suppressed[i].SuppressedBy()
+		}
+	}
+	return nil
+}
+
+func labelsMatch(labels map[string]string, superset map[string]string) bool {
+	for k, v := range labels {
+		if superset[k] != v {
+			return false
+		}
+	}
+	return len(labels) > 0
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAlertProblem(pod string, state string, activeAt time.Time) *problem.Problem {
+	return &problem.Problem{
+		Name:       "PodNotReady",
+		Tags:       map[string]string{"alertname": "PodNotReady", "namespace": "ns1", "pod": pod},
+		ActiveAt:   activeAt,
+		AlertState: state,
+	}
+}
+
+func TestApplyAlertFilter(t *testing.T) {
+	now := time.Now()
+	problems := []*problem.Problem{
+		newAlertProblem("p1", "firing", now.Add(-time.Hour)),
+		newAlertProblem("p2", "pending", now.Add(-time.Hour)),
+		newAlertProblem("p3", "firing", now.Add(-time.Minute)),
+		newAlertProblem("p4", "firing", now.Add(-time.Hour)),
+	}
+	suppressed := prometheus.SuppressedAlert{Labels: map[string]string{
+		"alertname": "PodNotReady", "namespace": "ns1", "pod": "p4", "prometheus": "monitoring/k8s"}}
+	suppressed.Status.SilencedBy = []string{"silence-1"}
+	ctx := context.Background()
+
+	// default, only the suppressed alert is dropped
+	result := applyAlertFilter(ctx, &config.AlertFilterConfig{}, problems, []prometheus.SuppressedAlert{suppressed}, now)
+	assert.Len(t, result, 3)
+
+	result = applyAlertFilter(ctx, &config.AlertFilterConfig{DropPending: true, MinActiveSeconds: 600, FlagSuppressed: true},
+		problems, []prometheus.SuppressedAlert{suppressed}, now)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "p1", result[0].Tags["pod"])
+	assert.Empty(t, result[0].SuppressedBy)
+	assert.Equal(t, "p4", result[1].Tags["pod"])
+	assert.Equal(t, []string{"silence-1"}, result[1].SuppressedBy)
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/notification"
+)
+
+const (
+	defaultClusterLabel   = "cluster"
+	defaultNamespaceLabel = "namespace"
+	defaultCooldown       = 300 * time.Second
+	defaultMaxConcurrent  = 4
+	// user of the detections triggered by alertmanager
+	AlertmanagerUser = "alertmanager"
+)
+
+// Detections triggered by alertmanager, by namespace. A namespace is detected once within the cooldown.
+type detectionRuns struct {
+	mx       sync.Mutex
+	inFlight map[AffectedNamespace]bool
+	finished map[AffectedNamespace]time.Time
+	once     sync.Once
+	slots    chan struct{}
+}
+
+var alertmanagerRuns = &detectionRuns{
+	inFlight: make(map[AffectedNamespace]bool),
+	finished: make(map[AffectedNamespace]time.Time),
+}
+
+// AlertmanagerWebhook is the payload of alertmanager webhook receiver, version 4.
+type AlertmanagerWebhook struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// AffectedNamespace is a namespace with firing alerts.
+type AffectedNamespace struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+}
+
+// AffectedNamespaces maps the firing alerts to cluster and namespace by the configured labels.
+// Alerts without namespace, or without cluster if no default cluster, are ignored.
+func AffectedNamespaces(payload *AlertmanagerWebhook, conf *config.AlertmanagerConfig) []AffectedNamespace {
+	clusterLabel, namespaceLabel, defaultCluster := defaultClusterLabel, defaultNamespaceLabel, ""
+	if conf != nil {
+		if conf.ClusterLabel != "" {
+			clusterLabel = conf.ClusterLabel
+		}
+		if conf.NamespaceLabel != "" {
+			namespaceLabel = conf.NamespaceLabel
+		}
+		defaultCluster = conf.DefaultCluster
+	}
+	seen := make(map[AffectedNamespace]bool)
+	result := make([]AffectedNamespace, 0)
+	for _, a := range payload.Alerts {
+		if a.Status != "firing" {
+			continue
+		}
+		ns := AffectedNamespace{Cluster: a.Labels[clusterLabel], Namespace: a.Labels[namespaceLabel]}
+		if ns.Cluster == "" {
+			ns.Cluster = defaultCluster
+		}
+		if ns.Cluster == "" || ns.Namespace == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		result = append(result, ns)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cluster != result[j].Cluster {
+			return result[i].Cluster < result[j].Cluster
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
+
+// ScheduleDetections runs the detections of the namespaces in background, the number of detections running at
+// the same time is limited. Namespaces being detected, or detected within the cooldown, are skipped.
+// Returns the scheduled namespaces.
+func ScheduleDetections(ctx context.Context, namespaces []AffectedNamespace) []AffectedNamespace {
+	conf := config.GetThelivConfig().Alertmanager
+	cooldown, maxConcurrent := defaultCooldown, defaultMaxConcurrent
+	if conf != nil {
+		if conf.CooldownSeconds > 0 {
+			cooldown = time.Duration(conf.CooldownSeconds) * time.Second
+		}
+		if conf.MaxConcurrent > 0 {
+			maxConcurrent = conf.MaxConcurrent
+		}
+	}
+	alertmanagerRuns.once.Do(func() {
+		alertmanagerRuns.slots = make(chan struct{}, maxConcurrent)
+	})
+
+	scheduled := make([]AffectedNamespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if !alertmanagerRuns.start(ns, cooldown, time.Now()) {
+			log.SWithContext(ctx).Infof("Skipped alertmanager detection of %s/%s, detected within %s",
+				ns.Cluster, ns.Namespace, cooldown)
+			continue
+		}
+		scheduled = append(scheduled, ns)
+		go func() {
+			alertmanagerRuns.slots <- struct{}{}
+			defer func() {
+				<-alertmanagerRuns.slots
+				alertmanagerRuns.finish(ns, time.Now())
+			}()
+			DetectAffectedNamespace(ctx, ns)
+		}()
+	}
+	return scheduled
+}
+
+// Returns false if the namespace is being detected, or detected within the cooldown.
+func (r *detectionRuns) start(ns AffectedNamespace, cooldown time.Duration, now time.Time) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.inFlight[ns] {
+		return false
+	}
+	if last, ok := r.finished[ns]; ok && now.Sub(last) < cooldown {
+		return false
+	}
+	r.inFlight[ns] = true
+	return true
+}
+
+func (r *detectionRuns) finish(ns AffectedNamespace, now time.Time) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	delete(r.inFlight, ns)
+	r.finished[ns] = now
+}
+
+// DetectAffectedNamespace runs the detection of the namespace, stores the result,
+// and notifies the channels if configured. Errors are logged, as alertmanager is not waiting for the result.
+func DetectAffectedNamespace(ctx context.Context, ns AffectedNamespace) {
+	cluster, err := config.GetConfigLoader().GetKubernetesConfig(ctx, ns.Cluster)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to load kubeconfig of cluster %s, error is %s", ns.Cluster, err)
+		return
+	}
+	kubeconfig, err := cluster.GetKubeConfig(ctx)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to load kubeconfig of cluster %s, error is %s", ns.Cluster, err)
+		return
+	}
+	ctx = SetDetectorInput(ctx, &problem.DetectorCreationInput{
+		Kubeconfig:  kubeconfig,
+		ClusterName: ns.Cluster,
+		Namespace:   ns.Namespace,
+		Prometheus:  cluster.GetPrometheusEndpoint(),
+	})
+	result, err := DetectAlerts(ctx)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Alertmanager detection failed for %s/%s, error is %s", ns.Cluster, ns.Namespace, err)
+		return
+	}
+	id := ""
+	detection, err := SaveDetection(ctx, AlertmanagerUser, result)
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to save detection of %s/%s, error is %s", ns.Cluster, ns.Namespace, err)
+	} else if detection != nil {
+		id = detection.ID
+	}
+	cards, _ := result.([]*problem.ReportCard)
+	log.SWithContext(ctx).Infof("Alertmanager detection of %s/%s generated %d report cards, detection is %s",
+		ns.Cluster, ns.Namespace, len(cards), id)
+	if conf := config.GetThelivConfig().Alertmanager; conf != nil && conf.Notify {
+		notification.NotifyDetection(ctx, ns.Cluster, ns.Namespace, id, cards)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAffectedNamespaces(t *testing.T) {
+	payload := &AlertmanagerWebhook{}
+	err := json.Unmarshal([]byte(`{
+		"version": "4",
+		"status": "firing",
+		"receiver": "theliv",
+		"alerts": [
+			{"status": "firing", "labels": {"alertname": "PodCrashLooping", "k8s_cluster": "c1", "ns": "web"}},
+			{"status": "firing", "labels": {"alertname": "PodNotReady", "k8s_cluster": "c1", "ns": "web"}},
+			{"status": "firing", "labels": {"alertname": "PodNotReady", "ns": "api"}},
+			{"status": "resolved", "labels": {"alertname": "PodNotReady", "k8s_cluster": "c1", "ns": "db"}},
+			{"status": "firing", "labels": {"alertname": "NodeNotReady", "k8s_cluster": "c1"}}
+		]
+	}`), payload)
+	assert.Nil(t, err)
+
+	namespaces := AffectedNamespaces(payload, &config.AlertmanagerConfig{
+		ClusterLabel: "k8s_cluster", NamespaceLabel: "ns", DefaultCluster: "c0"})
+	assert.Equal(t, []AffectedNamespace{{Cluster: "c0", Namespace: "api"}, {Cluster: "c1", Namespace: "web"}}, namespaces)
+
+	// default labels, no default cluster
+	assert.Empty(t, AffectedNamespaces(payload, nil))
+}
+
+func TestDetectionRuns(t *testing.T) {
+	runs := &detectionRuns{
+		inFlight: make(map[AffectedNamespace]bool),
+		finished: make(map[AffectedNamespace]time.Time),
+	}
+	web := AffectedNamespace{Cluster: "c1", Namespace: "web"}
+	now := time.Now()
+
+	assert.True(t, runs.start(web, time.Minute, now))
+	// re-sent while detecting
+	assert.False(t, runs.start(web, time.Minute, now))
+	assert.True(t, runs.start(AffectedNamespace{Cluster: "c1", Namespace: "api"}, time.Minute, now))
+
+	runs.finish(web, now)
+	assert.False(t, runs.start(web, time.Minute, now.Add(30*time.Second)))
+	assert.True(t, runs.start(web, time.Minute, now.Add(2*time.Minute)))
+}
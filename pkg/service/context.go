@@ -15,6 +15,8 @@ type ContextKey int
 
 const (
 	DetecotrInputKey ContextKey = iota
+	ProgressKey
+	CardKey
 )
 
 func GetDetectorInput(ctx context.Context) *problem.DetectorCreationInput {
@@ -0,0 +1,68 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Labels of the kube-state-metrics custom resource state metrics.
+const (
+	crGroupLabel   = "customresource_group"
+	crVersionLabel = "customresource_version"
+	crKindLabel    = "customresource_kind"
+	crNameLabel    = "name"
+)
+
+// Loads the affected resource of a kind not in resourceObjects, e.g. Crossplane claims or database operators.
+// The kind is discovered from the resourcetype label, e.g. postgresql or postgresqls.acid.zalan.do,
+// or from the labels of kube-state-metrics custom resource state metrics.
+func loadCustomResource(ctx context.Context, client *kubeclient.KubeClient, p *problem.Problem) {
+	resourceType := p.Tags[com.Resourcetype]
+	gvk, err := customResourceKind(p.Tags, client.KindFor)
+	if err != nil {
+		log.SWithContext(ctx).Warnf("Not found affected resource for resource type %s: %s", resourceType, err)
+		return
+	}
+	name := customResourceName(p.Tags, gvk)
+	obj, err := client.GetUnstructured(ctx, gvk, kubeclient.NamespacedName{Namespace: p.Tags[com.Namespace], Name: name})
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Not found affected resource for %s: %s, %s", resourceType, name, err)
+		return
+	}
+	if p.Code == "" || p.Code == problem.UnknownErr {
+		p.Code = problem.CustomResourceErr
+	}
+	buildAffectedResource(p, name, strings.ToLower(gvk.Kind), obj)
+}
+
+func customResourceKind(tags map[string]string, kindFor func(string) (schema.GroupVersionKind, error)) (schema.GroupVersionKind, error) {
+	if kind := tags[crKindLabel]; kind != "" {
+		return schema.GroupVersionKind{Group: tags[crGroupLabel], Version: tags[crVersionLabel], Kind: kind}, nil
+	}
+	if tags[com.Resourcetype] == "" {
+		return schema.GroupVersionKind{}, errors.New("resourcetype label is empty")
+	}
+	return kindFor(tags[com.Resourcetype])
+}
+
+// The name is in the label of the resource type, the lower case kind, or name of custom resource state metrics.
+func customResourceName(tags map[string]string, gvk schema.GroupVersionKind) string {
+	for _, label := range []string{tags[com.Resourcetype], strings.ToLower(gvk.Kind), crNameLabel} {
+		if name := tags[label]; label != "" && name != "" {
+			return name
+		}
+	}
+	return ""
+}
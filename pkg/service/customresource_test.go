@@ -0,0 +1,149 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestCustomResourceKind(t *testing.T) {
+	postgresql := schema.GroupVersionKind{Group: "acid.zalan.do", Version: "v1", Kind: "postgresql"}
+	kindFor := func(resource string) (schema.GroupVersionKind, error) {
+		if resource == "postgresql" {
+			return postgresql, nil
+		}
+		return schema.GroupVersionKind{}, errors.New("no matches for " + resource)
+	}
+
+	gvk, err := customResourceKind(map[string]string{com.Resourcetype: "postgresql"}, kindFor)
+	assert.Nil(t, err)
+	assert.Equal(t, postgresql, gvk)
+	_, err = customResourceKind(map[string]string{com.Resourcetype: "unknown"}, kindFor)
+	assert.NotNil(t, err)
+	_, err = customResourceKind(map[string]string{}, kindFor)
+	assert.NotNil(t, err)
+
+	// kube-state-metrics custom resource state metrics
+	tags := map[string]string{
+		com.Resourcetype: "claim",
+		crGroupLabel:     "database.example.org",
+		crVersionLabel:   "v1alpha1",
+		crKindLabel:      "PostgreSQLInstance",
+		crNameLabel:      "orders-db",
+	}
+	gvk, err = customResourceKind(tags, kindFor)
+	assert.Nil(t, err)
+	assert.Equal(t, schema.GroupVersionKind{Group: "database.example.org", Version: "v1alpha1", Kind: "PostgreSQLInstance"}, gvk)
+	assert.Equal(t, "orders-db", customResourceName(tags, gvk))
+
+	assert.Equal(t, "orders", customResourceName(map[string]string{com.Resourcetype: "postgresql", "postgresql": "orders"}, postgresql))
+	assert.Equal(t, "orders", customResourceName(map[string]string{com.Resourcetype: "postgresqls.acid.zalan.do", "postgresql": "orders"}, postgresql))
+	assert.Empty(t, customResourceName(map[string]string{com.Resourcetype: "postgresql"}, postgresql))
+}
+
+const claimConfig = `port: 8080
+clusterDir: %s
+problemlevel:
+  managednamespaces:
+  - kube-system
+`
+
+// Loads the config, the clusterDir is formatted into the content.
+func loadTestConfig(t *testing.T, content string) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "theliv.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(fmt.Sprintf(content, dir)), 0600))
+	config.NewFileConfigLoader(file).LoadConfigs()
+}
+
+// Composite resources are cluster-scoped, they are detected in the namespace of their claim and grouped
+// into the report card of the claim.
+func TestClaimedCompositeProblems(t *testing.T) {
+	loadTestConfig(t, claimConfig)
+
+	claimGVK := schema.GroupVersionKind{Group: "database.example.org", Version: "v1alpha1", Kind: "PostgreSQLInstance"}
+	compositeGVK := schema.GroupVersionKind{Group: "database.example.org", Version: "v1alpha1", Kind: "XPostgreSQLInstance"}
+	object := func(gvk schema.GroupVersionKind, name string, namespace string, claimNamespace string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		u.SetGroupVersionKind(gvk)
+		u.SetName(name)
+		u.SetNamespace(namespace)
+		u.SetUID(types.UID(name))
+		if claimNamespace != "" {
+			u.Object["spec"] = map[string]interface{}{"claimRef": map[string]interface{}{
+				"apiVersion": claimGVK.GroupVersion().String(), "kind": claimGVK.Kind,
+				"name": "orders-db", "namespace": claimNamespace,
+			}}
+		}
+		return u
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(claimGVK, meta.RESTScopeNamespace)
+	mapper.Add(compositeGVK, meta.RESTScopeRoot)
+	dynamicCli := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			claimGVK.GroupVersion().WithResource("postgresqlinstances"):      "PostgreSQLInstanceList",
+			compositeGVK.GroupVersion().WithResource("xpostgresqlinstances"): "XPostgreSQLInstanceList",
+		},
+		object(claimGVK, "orders-db", "team-a", ""),
+		object(compositeGVK, "orders-db-x7k2p", "", "team-a"),
+		object(compositeGVK, "billing-db-m4q9z", "", "team-b"),
+	)
+	client, err := kubeclient.NewKubeClientFor(dynamicCli, mapper)
+	assert.Nil(t, err)
+
+	composite := func(name string) *problem.Problem {
+		return &problem.Problem{
+			Name: "CompositeNotReady",
+			Tags: map[string]string{
+				com.Resourcetype: "xpostgresqlinstance",
+				crGroupLabel:     compositeGVK.Group,
+				crVersionLabel:   compositeGVK.Version,
+				crKindLabel:      compositeGVK.Kind,
+				crNameLabel:      name,
+			},
+			SolutionDetails: com.InitLockedSlice(),
+			UsefulCommands:  com.InitLockedSlice(),
+		}
+	}
+	other := &problem.Problem{Name: "PodNotReady", Tags: map[string]string{
+		com.Resourcetype: com.Pod, com.Namespace: "team-b", com.Pod: "billing-0"}}
+	input := &problem.DetectorCreationInput{ClusterName: "c1", Namespace: "team-a", KubeClient: client}
+
+	problems := filterProblems(context.Background(),
+		[]*problem.Problem{composite("orders-db-x7k2p"), composite("billing-db-m4q9z"), other}, input)
+	assert.Len(t, problems, 2)
+	var wg sync.WaitGroup
+	assert.Nil(t, buildProblemAffectedResource(context.Background(), &wg, problems, input))
+	problems = filterClaimed(problems, input)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "orders-db-x7k2p", problems[0].AffectedResources.ResourceName)
+
+	result, err := problem.Aggregate(context.Background(), problems, client, nil)
+	assert.Nil(t, err)
+	cards := result.([]*problem.ReportCard)
+	assert.Len(t, cards, 1)
+	assert.Equal(t, "orders-db", cards[0].Name)
+	assert.Equal(t, claimGVK.Kind, cards[0].TopResourceType)
+}
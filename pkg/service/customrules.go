@@ -0,0 +1,108 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/internal/rules"
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var customRules []*rules.Rule
+
+// LoadCustomRules compiles the custom rules in config and registers their issue types,
+// invalid rules are logged and skipped. Called at startup, before any detection.
+func LoadCustomRules() {
+	conf := config.GetThelivConfig().CustomRules
+	if conf == nil {
+		return
+	}
+	loaded := make([]*rules.Rule, 0, len(conf.Rules))
+	for _, c := range conf.Rules {
+		rule, err := rules.Compile(c)
+		if err != nil {
+			log.S().Errorf("Invalid custom rule %s, error is %s", c.Name, err)
+			continue
+		}
+		problem.RegisterIssueType(rule.IssueType())
+		loaded = append(loaded, rule)
+	}
+	customRules = loaded
+	log.S().Infof("Loaded %d custom rules", len(loaded))
+}
+
+// Evaluates the custom rules against the objects in the namespace, the objects of each kind are listed once.
+// Kinds not installed on the cluster are skipped.
+func detectCustomRules(ctx context.Context, input *problem.DetectorCreationInput, ruleset []*rules.Rule,
+	list func(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error)) []*problem.Problem {
+	l := log.SWithContext(ctx)
+	lists := make(map[schema.GroupVersionKind][]unstructured.Unstructured)
+	listOnce := func(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+		if items, ok := lists[gvk]; ok {
+			return items, nil
+		}
+		items, err := list(gvk)
+		if err != nil {
+			return nil, err
+		}
+		lists[gvk] = items
+		return items, nil
+	}
+
+	problems := make([]*problem.Problem, 0)
+	for _, rule := range ruleset {
+		objects, err := listOnce(rule.Target)
+		if err != nil {
+			l.Warnf("Skipped custom rule %s, failed to list %s, error is %s", rule.Name, rule.Target.Kind, err)
+			continue
+		}
+		related := make(map[string][]interface{}, len(rule.Related))
+		for _, gvk := range rule.Related {
+			items, err := listOnce(gvk)
+			if err != nil {
+				l.Warnf("Failed to list %s of custom rule %s, error is %s", gvk.Kind, rule.Name, err)
+			}
+			values := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				values = append(values, item.Object)
+			}
+			related[gvk.Kind] = values
+		}
+		failed := 0
+		for i := range objects {
+			violated, err := rule.Violated(&objects[i], input.ClusterName, related)
+			if err != nil {
+				failed++
+				continue
+			}
+			if violated {
+				problems = append(problems, rule.NewProblem(ctx, &objects[i], input.ClusterName))
+			}
+		}
+		if failed > 0 {
+			l.Warnf("Custom rule %s failed to evaluate %d objects", rule.Name, failed)
+		}
+	}
+	l.Infof("Generated %d problems by custom rules", len(problems))
+	return problems
+}
+
+func listNamespaceObjects(ctx context.Context, input *problem.DetectorCreationInput) func(
+	gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+	return func(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+		list, err := input.KubeClient.ListUnstructured(ctx, gvk, input.Namespace, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+}
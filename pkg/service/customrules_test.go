@@ -0,0 +1,54 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/internal/rules"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDetectCustomRules(t *testing.T) {
+	compile := func(c config.CustomRule) *rules.Rule {
+		r, err := rules.Compile(c)
+		assert.Nil(t, err)
+		return r
+	}
+	ruleset := []*rules.Rule{
+		compile(config.CustomRule{Name: "NoOwner", APIVersion: "v1", Kind: "Pod",
+			Expression: "!has(object.metadata.ownerReferences)"}),
+		compile(config.CustomRule{Name: "Missing", APIVersion: "example.com/v1", Kind: "Widget", Expression: "true"}),
+	}
+	pod := func(name string, owned bool) unstructured.Unstructured {
+		meta := map[string]interface{}{"name": name, "namespace": "ns1"}
+		if owned {
+			meta["ownerReferences"] = []interface{}{map[string]interface{}{"kind": "ReplicaSet", "name": "web"}}
+		}
+		return unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "metadata": meta}}
+	}
+	calls := 0
+	list := func(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+		calls++
+		if gvk.Kind != "Pod" {
+			return nil, errors.New("no matches for kind Widget")
+		}
+		return []unstructured.Unstructured{pod("web-1", true), pod("debug", false)}, nil
+	}
+	input := &problem.DetectorCreationInput{ClusterName: "c1", Namespace: "ns1"}
+
+	problems := detectCustomRules(context.Background(), input, ruleset, list)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "NoOwner", problems[0].Name)
+	assert.Equal(t, "debug", problems[0].AffectedResources.ResourceName)
+	assert.Equal(t, 2, calls)
+}
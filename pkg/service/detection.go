@@ -0,0 +1,239 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/database/etcd"
+	theErr "github.com/fidelity/theliv/pkg/err"
+	log "github.com/fidelity/theliv/pkg/log"
+)
+
+const defaultRetentionHours = 7 * 24
+
+// Detection is a stored detection result, shared by its ID.
+type Detection struct {
+	ID        string                `json:"id"`
+	Cluster   string                `json:"cluster"`
+	Namespace string                `json:"namespace"`
+	User      string                `json:"user,omitempty"`
+	Timestamp time.Time             `json:"timestamp"`
+	Cards     []*problem.ReportCard `json:"cards"`
+	// grouping strategies of the report cards, the card IDs depend on the grouping
+	Grouping []string `json:"grouping,omitempty"`
+}
+
+// SaveDetection stores the report cards of DetectAlerts in etcd, the key expires after the configured retention.
+// Returns nil if storing detections is disabled, or the detection is of a past window, since the stored
+// detections are the current state for the diff and the recurrence of the alerts.
+func SaveDetection(ctx context.Context, user string, result interface{}) (*Detection, error) {
+	input := GetDetectorInput(ctx)
+	if input.Window != nil {
+		return nil, nil
+	}
+	thelivcfg := config.GetThelivConfig()
+	retention := defaultRetentionHours
+	if thelivcfg.Detection != nil {
+		if thelivcfg.Detection.Disabled {
+			return nil, nil
+		}
+		if thelivcfg.Detection.RetentionHours > 0 {
+			retention = thelivcfg.Detection.RetentionHours
+		}
+	}
+	cards, _ := result.([]*problem.ReportCard)
+
+	detection := &Detection{
+		ID:        newDetectionID(),
+		Cluster:   input.ClusterName,
+		Namespace: input.Namespace,
+		User:      user,
+		Timestamp: time.Now().UTC(),
+		Cards:     cards,
+		Grouping:  groupingStrategies(ctx, input),
+	}
+	ttl := time.Duration(retention) * time.Hour
+	err := etcd.PutWithTTL(ctx, detectionKey(detection.ID), detection, ttl)
+	if err == nil {
+		// index by cluster and namespace, to find the latest detections
+		err = etcd.PutWithTTL(ctx, detectionIndexKey(detection), newDetectionIndex(detection), ttl)
+	}
+	if err != nil {
+		return nil, theErr.NewCommonError(ctx, 2, "failed to save detection "+detection.ID)
+	}
+	log.SWithContext(ctx).Infof("Saved detection %s, retention is %d hours", detection.ID, retention)
+	return detection, nil
+}
+
+// GetDetection loads the stored detection, returns nil if not found or expired.
+func GetDetection(ctx context.Context, id string) (*Detection, error) {
+	contact := fmt.Sprintf(com.Contact, config.GetThelivConfig().TeamName)
+	value, err := etcd.Get(ctx, detectionKey(id))
+	if err != nil {
+		return nil, theErr.NewCommonError(ctx, 2, com.LoadDetectionFailed+contact)
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	detection := &Detection{}
+	if err = json.Unmarshal(value, detection); err != nil {
+		return nil, theErr.NewCommonError(ctx, 2, com.LoadDetectionFailed+contact)
+	}
+	return detection, nil
+}
+
+// DetectorPath is the detect API path of the detection, used for RBAC check.
+func (d *Detection) DetectorPath() string {
+	return DetectorPath(d.Cluster, d.Namespace)
+}
+
+func DetectorPath(cluster string, namespace string) string {
+	return fmt.Sprintf("/theliv-api/v1/detector/%s/%s/detect", cluster, namespace)
+}
+
+// ClusterDetectorPath is the detect API path of all the namespaces of the cluster, used for RBAC check.
+func ClusterDetectorPath(cluster string) string {
+	return fmt.Sprintf("/theliv-api/v1/detector/%s/*", cluster)
+}
+
+func detectionKey(id string) string {
+	return etcd.DETECTIONS_KEY + "/" + id
+}
+
+// Index key ends with zero padded timestamp, sorting keys sorts detections by time.
+func detectionIndexKey(d *Detection) string {
+	return fmt.Sprintf("%s/%020d", detectionIndexPrefix(d.Cluster, d.Namespace), d.Timestamp.UnixNano())
+}
+
+func detectionIndexPrefix(cluster string, namespace string) string {
+	return fmt.Sprintf("%s/%s/%s", etcd.DETECTION_INDEX_KEY, cluster, namespace)
+}
+
+// detectionIndex is the value of the index key, with the recurrence keys of the alert issues,
+// so the recurrence is computed from the index without loading the detections.
+type detectionIndex struct {
+	ID     string   `json:"id"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+func newDetectionIndex(d *Detection) *detectionIndex {
+	index := &detectionIndex{ID: d.ID}
+	seen := make(map[string]bool)
+	for _, card := range d.Cards {
+		for _, res := range card.Resources {
+			if res.Issue == nil || res.Issue.Tags["alertname"] == "" {
+				continue
+			}
+			key := problem.RecurrenceKey(res.Issue.Tags)
+			if !seen[key] {
+				seen[key] = true
+				index.Issues = append(index.Issues, key)
+			}
+		}
+	}
+	return index
+}
+
+// Index values stored before the issues were indexed are the detection ID only, legacy is true for them.
+func parseDetectionIndex(value []byte) (index *detectionIndex, legacy bool, err error) {
+	index = &detectionIndex{}
+	if err = json.Unmarshal(value, index); err == nil {
+		return index, false, nil
+	}
+	if err = json.Unmarshal(value, &index.ID); err != nil {
+		return nil, false, err
+	}
+	return index, true, nil
+}
+
+// DetectionDiff compares the target detection with the base detection.
+type DetectionDiff struct {
+	Base   *DetectionSummary   `json:"base"`
+	Target *DetectionSummary   `json:"target"`
+	Cards  []*problem.CardDiff `json:"cards"`
+}
+
+type DetectionSummary struct {
+	ID        string    `json:"id"`
+	Cluster   string    `json:"cluster"`
+	Namespace string    `json:"namespace"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// DiffDetections compares the report cards, nil if the detections are grouped differently, the same issues
+// would be in report cards of different IDs.
+func DiffDetections(base *Detection, target *Detection) *DetectionDiff {
+	if !base.sameGrouping(target) {
+		return nil
+	}
+	return &DetectionDiff{
+		Base:   base.summary(),
+		Target: target.summary(),
+		Cards:  problem.DiffCards(base.Cards, target.Cards),
+	}
+}
+
+func (d *Detection) sameGrouping(other *Detection) bool {
+	return slices.Equal(d.Grouping, other.Grouping)
+}
+
+// GetLatestDetections returns the previous and the latest detections of the cluster and namespace, in this order.
+// The previous one has the same grouping as the latest, it is nil if there is no such detection.
+func GetLatestDetections(ctx context.Context, cluster string, namespace string) (*Detection, *Detection, error) {
+	contact := fmt.Sprintf(com.Contact, config.GetThelivConfig().TeamName)
+	index, err := etcd.GetWithPrefix(detectionIndexPrefix(cluster, namespace) + "/")
+	if err != nil {
+		return nil, nil, theErr.NewCommonError(ctx, 2, com.LoadDetectionFailed+contact)
+	}
+	keys := make([]string, 0, len(index))
+	for k := range index {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var latest, previous *Detection
+	for i := len(keys) - 1; i >= 0 && previous == nil; i-- {
+		entry, _, err := parseDetectionIndex(index[keys[i]])
+		if err != nil {
+			continue
+		}
+		d, err := GetDetection(ctx, entry.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case d == nil:
+		case latest == nil:
+			latest = d
+		case latest.sameGrouping(d):
+			previous = d
+		}
+	}
+	return previous, latest, nil
+}
+
+func (d *Detection) summary() *DetectionSummary {
+	return &DetectionSummary{ID: d.ID, Cluster: d.Cluster, Namespace: d.Namespace, Timestamp: d.Timestamp}
+}
+
+func newDetectionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,28 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDetectionsGrouping(t *testing.T) {
+	card := func(id string) *problem.ReportCard {
+		return &problem.ReportCard{ID: id, Name: "web"}
+	}
+	base := &Detection{ID: "d1", Grouping: problem.DefaultGrouping, Cards: []*problem.ReportCard{card("1")}}
+	target := &Detection{ID: "d2", Grouping: problem.DefaultGrouping, Cards: []*problem.ReportCard{card("1")}}
+	diff := DiffDetections(base, target)
+	assert.NotNil(t, diff)
+	assert.Equal(t, "d1", diff.Base.ID)
+
+	// the same issues are in cards of other IDs
+	target.Grouping = []string{"label:team", problem.GroupByOwner}
+	assert.Nil(t, DiffDetections(base, target))
+}
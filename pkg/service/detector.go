@@ -8,7 +8,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/fidelity/theliv/internal/investigators"
 	in "github.com/fidelity/theliv/internal/investigators"
@@ -19,7 +21,6 @@ import (
 	theErr "github.com/fidelity/theliv/pkg/err"
 	"github.com/fidelity/theliv/pkg/kubeclient"
 	log "github.com/fidelity/theliv/pkg/log"
-	"github.com/fidelity/theliv/pkg/observability/k8s"
 	"github.com/fidelity/theliv/pkg/prometheus"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
@@ -28,6 +29,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -56,63 +58,143 @@ var alertInvestigatorMap = map[string][]investigatorFunc{
 	"DeploymentReplicasMismatch":   {in.DeploymentReplicasMismatchInvestigator},
 
 	com.IngressMisconfigured: {in.IngressMisconfiguredInvestigator},
+
+	com.FluxHelmReleaseNotReady:   {in.FluxHelmReleaseInvestigator},
+	com.FluxKustomizationNotReady: {in.FluxKustomizationInvestigator},
+	com.FluxSourceNotReady:        {in.FluxSourceInvestigator},
 }
 
 func DetectAlerts(ctx context.Context) (interface{}, error) {
+	input := GetDetectorInput(ctx)
+	problems, err := detectProblems(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateProblems(ctx, input, nil, problems), nil
+}
+
+// detectProblems builds the problems from the alerts and unhealthy resources, with the affected resources loaded.
+// The kube client and retrievers of the input are reused if set, e.g. by watch mode.
+func detectProblems(ctx context.Context, input *problem.DetectorCreationInput) ([]*problem.Problem, error) {
 	var wg sync.WaitGroup
 	contact := fmt.Sprintf(com.Contact, config.GetThelivConfig().TeamName)
-	input := GetDetectorInput(ctx)
 
-	client, err := kubeclient.NewKubeClient(ctx, input.Kubeconfig)
-	if err != nil {
-		return nil, theErr.NewCommonError(ctx, 4, com.LoadKubeConfigFailed+contact)
+	if input.KubeClient == nil {
+		client, err := kubeclient.NewKubeClient(ctx, input.Kubeconfig)
+		if err != nil {
+			return nil, theErr.NewCommonError(ctx, 4, com.LoadKubeConfigFailed+contact)
+		}
+		log.SWithContext(ctx).Infof("Kube client successfully created")
+		input.KubeClient = client
 	}
-	log.SWithContext(ctx).Infof("Kube client successfully created")
-	input.KubeClient = client
 
-	eventRetriever := k8s.NewK8sEventRetriever(client)
-	input.EventRetriever = eventRetriever
+	// watch mode sets the retriever reading from the informer cache
+	if input.EventRetriever == nil {
+		input.EventRetriever = newEventRetriever(ctx, input.KubeClient)
+	}
+	if input.LogRetriever == nil {
+		input.LogRetriever = newLogRetriever(ctx)
+	}
+	if input.MetricRetriever == nil {
+		input.MetricRetriever = prometheus.NewMetricRetriever(input)
+	}
 
-	ingress := getUnhealthyIngress(ctx, input)
-	alerts, err := prometheus.GetAlerts(ctx, input)
+	// historical detection only has the alerts in the window, current ingress and flux issues are not included
+	var unhealthy []*problem.Problem
+	var alerts v1.AlertsResult
+	var err error
+	if input.Window != nil {
+		alerts, err = prometheus.GetHistoricalAlerts(ctx, input)
+	} else {
+		unhealthy = append(getUnhealthyIngress(ctx, input), getUnhealthyFlux(ctx, input)...)
+		alerts, err = prometheus.GetAlerts(ctx, input)
+	}
 	if err != nil {
 		return nil, theErr.NewCommonError(ctx, 6, com.PrometheusNotAvailable+contact)
 	}
 	log.SWithContext(ctx).Infof("%d prometheus alerts found", len(alerts.Alerts))
+	reportProgress(ctx, ProgressEvent{Stage: StageAlertsFetched, Count: len(alerts.Alerts)})
 
 	// build problems from  alerts, problem is investigator input
 	problems := buildProblemsFromAlerts(alerts.Alerts)
-	if len(ingress) > 0 {
-		problems = append(problems, ingress...)
+	if input.Window == nil {
+		problems = filterAlertProblems(ctx, input, problems, time.Now())
+	}
+	// after the alert filter, alertmanager has the original labels
+	if mapper := prometheus.NewLabelMapper(ctx, input.Prometheus); mapper != nil {
+		for _, p := range problems {
+			p.Tags = mapper.Map(p.Tags)
+		}
+	}
+	if len(unhealthy) > 0 {
+		problems = append(problems, unhealthy...)
 	}
 	problems = filterProblems(ctx, problems, input)
 	log.SWithContext(ctx).Infof("Generated %d problems after filtering", len(problems))
 	if err = buildProblemAffectedResource(ctx, &wg, problems, input); err != nil {
 		return nil, theErr.NewCommonError(ctx, 4, com.LoadResourceFailed+contact)
 	}
+	problems = filterClaimed(problems, input)
+	if input.Window != nil {
+		noteHistoricalResources(ctx, input, problems)
+	}
+	problems = problem.CollapseReplicas(problems)
+	log.SWithContext(ctx).Infof("Generated %d problems after collapsing replicas", len(problems))
 
 	problemresults := make([]*problem.Problem, 0)
 	for _, p := range problems {
 		if p.AffectedResources.Resource != nil {
 			problemresults = append(problemresults, p)
-			// check investigator func map or use common investigator for each problem
-			if funcs, ok := alertInvestigatorMap[p.Name]; ok {
-				for _, fc := range funcs {
-					wg.Add(1)
-					go fc(ctx, &wg, p, input)
-				}
-			} else {
-				wg.Add(1)
-				go investigators.CommonInvestigator(ctx, &wg, p, input)
-			}
 		}
 	}
+	addRecurrence(ctx, input, problemresults)
+	reportProgress(ctx, ProgressEvent{Stage: StageProblemsBuilt, Count: len(problemresults)})
+	return problemresults, nil
+}
 
-	wg.Wait()
-	log.SWithContext(ctx).Infof("Generated %d problem results", len(problemresults))
+// aggregateProblems runs the investigators of the problems, then aggregates them with the problems investigated
+// already and the custom rule violations into report cards. Each card is reported as soon as its problems are investigated.
+func aggregateProblems(ctx context.Context, input *problem.DetectorCreationInput, investigated []*problem.Problem,
+	problemresults []*problem.Problem) []*problem.ReportCard {
+	var wg sync.WaitGroup
 
-	// Convert problems to report cards
-	return problem.Aggregate(ctx, problemresults, client)
+	// custom rules check the objects as they are now, not in historical detection
+	ruleProblems := make([]*problem.Problem, 0)
+	if input.Window == nil && len(customRules) > 0 {
+		ruleProblems = detectCustomRules(ctx, input, customRules, listNamespaceObjects(ctx, input))
+	}
+	investigated = append(slices.Clone(investigated), ruleProblems...)
+	log.SWithContext(ctx).Infof("Generated %d problem results", len(problemresults)+len(investigated))
+
+	// Convert problems to report cards, each card is reported as soon as its problems are investigated
+	builder := problem.NewCardBuilder(ctx, append(slices.Clone(problemresults), investigated...), input.KubeClient,
+		groupingStrategies(ctx, input), func(card *problem.ReportCard) {
+			card.Historical = input.Window
+			reportCard(ctx, card)
+		})
+	for _, p := range investigated {
+		builder.Add(ctx, p)
+	}
+
+	progress := &investigationProgress{}
+	for _, p := range problemresults {
+		progress.total += max(len(alertInvestigatorMap[p.Name]), 1)
+	}
+	for _, p := range problemresults {
+		// check investigator func map or use common investigator for each problem
+		funcs, ok := alertInvestigatorMap[p.Name]
+		if !ok {
+			funcs = []investigatorFunc{investigators.CommonInvestigator}
+		}
+		wg.Add(1)
+		go investigate(ctx, &wg, funcs, p, input, progress, builder)
+	}
+
+	wg.Wait()
+	cards := builder.Cards(ctx)
+	recordNewIssues(input, cards)
+	reportProgress(ctx, ProgressEvent{Stage: StageCardsAggregated, Count: len(cards)})
+	return cards
 }
 
 func buildProblemsFromAlerts(alerts []v1.Alert) []*problem.Problem {
@@ -120,7 +202,10 @@ func buildProblemsFromAlerts(alerts []v1.Alert) []*problem.Problem {
 	for _, alert := range alerts {
 		p := initProblem()
 		p.Name = string(alert.Labels[model.LabelName("alertname")])
+		p.Code = problem.GetErrorCode(p.Name)
 		p.Description = string(alert.Annotations[model.LabelName("description")])
+		p.ActiveAt = alert.ActiveAt
+		p.AlertState = string(alert.State)
 		p.Tags = make(map[string]string)
 		for ln, lv := range alert.Labels {
 			p.Tags[string(ln)] = string(lv)
@@ -136,9 +221,9 @@ func initProblem() problem.Problem {
 		Description:       "",
 		Tags:              make(map[string]string),
 		Level:             0,
-		CauseLevel:        0,
 		SolutionDetails:   common.InitLockedSlice(),
 		UsefulCommands:    common.InitLockedSlice(),
+		Findings:          problem.InitFindings(),
 		AffectedResources: problem.ResourceDetails{},
 	}
 }
@@ -152,7 +237,8 @@ func filterProblems(ctx context.Context, problems []*problem.Problem, input *pro
 		if p.Tags[com.Resourcetype] == com.Node || contains(managednamespaces, p.Tags[com.Namespace]) {
 			// node & managednamespaces are cluster level problem
 			p.Level = problem.Cluster
-		} else if p.Tags[com.Namespace] == input.Namespace {
+		} else if p.Tags[com.Namespace] == input.Namespace || clusterCustomResource(p) {
+			// cluster-scoped custom resources are filtered by their claim when loaded, see filterClaimed
 			p.Level = problem.UserNamespace
 		} else {
 			// filter out other problems that not related to user namespace
@@ -163,6 +249,27 @@ func filterProblems(ctx context.Context, problems []*problem.Problem, input *pro
 	return results
 }
 
+// Custom resources without namespace, e.g. Crossplane composite resources.
+func clusterCustomResource(p *problem.Problem) bool {
+	_, builtin := resourceObjects[p.Tags[com.Resourcetype]]
+	return p.Tags[com.Namespace] == "" && p.Tags[com.Resourcetype] != "" && !builtin
+}
+
+// Keeps the cluster-scoped custom resources claimed from the namespace, their claim is the report card.
+func filterClaimed(problems []*problem.Problem, input *problem.DetectorCreationInput) []*problem.Problem {
+	return slices.DeleteFunc(problems, func(p *problem.Problem) bool {
+		if !clusterCustomResource(p) || p.AffectedResources.Resource == nil {
+			return false
+		}
+		u, ok := p.AffectedResources.Resource.(*unstructured.Unstructured)
+		if !ok {
+			return true
+		}
+		namespace, _, _ := unstructured.NestedString(u.Object, "spec", "claimRef", "namespace")
+		return namespace != input.Namespace
+	})
+}
+
 func buildProblemAffectedResource(ctx context.Context, wg *sync.WaitGroup, problems []*problem.Problem, input *problem.DetectorCreationInput) error {
 	client := input.KubeClient
 	wg.Add(len(problems))
@@ -173,49 +280,36 @@ func buildProblemAffectedResource(ctx context.Context, wg *sync.WaitGroup, probl
 	return nil
 }
 
+// Object type, kind tag and sub type tag of the affected resource, by resource type.
+type resourceObject struct {
+	new     func() runtime.Object
+	kind    string
+	subType string
+}
+
+var resourceObjects = map[string]resourceObject{
+	com.Pod:           {func() runtime.Object { return &corev1.Pod{} }, com.Pod, ""},
+	com.Container:     {func() runtime.Object { return &corev1.Pod{} }, com.Pod, com.Container},
+	com.Initcontainer: {func() runtime.Object { return &corev1.Pod{} }, com.Pod, com.Container},
+	com.Deployment:    {func() runtime.Object { return &appsv1.Deployment{} }, com.Deployment, ""},
+	com.Replicaset:    {func() runtime.Object { return &appsv1.ReplicaSet{} }, com.Replicaset, ""},
+	com.Statefulset:   {func() runtime.Object { return &appsv1.StatefulSet{} }, com.Statefulset, ""},
+	com.Daemonset:     {func() runtime.Object { return &appsv1.DaemonSet{} }, com.Daemonset, ""},
+	com.Node:          {func() runtime.Object { return &corev1.Node{} }, com.Node, ""},
+	com.Job:           {func() runtime.Object { return &batchv1.Job{} }, com.Job, ""},
+	com.Cronjob:       {func() runtime.Object { return &batchv1.CronJob{} }, com.Cronjob, ""},
+	com.Service:       {func() runtime.Object { return &corev1.Service{} }, com.Service, ""},
+	com.Endpoint:      {func() runtime.Object { return &corev1.Endpoints{} }, com.Endpoint, ""},
+}
+
 func loadResourceByType(ctx context.Context, wg *sync.WaitGroup, client *kubeclient.KubeClient, problem *problem.Problem) error {
 	defer wg.Done()
-	switch problem.Tags[com.Resourcetype] {
-	case com.Pod:
-		loadNamespacedResource(client, ctx, problem, &corev1.Pod{}, com.Pod, "")
-		problem.CauseLevel = 2
-	case com.Container:
-		loadNamespacedResource(client, ctx, problem, &corev1.Pod{}, com.Pod, com.Container)
-		problem.CauseLevel = 1
-	case com.Initcontainer:
-		loadNamespacedResource(client, ctx, problem, &corev1.Pod{}, com.Pod, com.Container)
-		problem.CauseLevel = 1
-	case com.Deployment:
-		loadNamespacedResource(client, ctx, problem, &appsv1.Deployment{}, com.Deployment, "")
-		problem.CauseLevel = 4
-	case com.Replicaset:
-		loadNamespacedResource(client, ctx, problem, &appsv1.ReplicaSet{}, com.Replicaset, "")
-		problem.CauseLevel = 3
-	case com.Statefulset:
-		loadNamespacedResource(client, ctx, problem, &appsv1.StatefulSet{}, com.Statefulset, "")
-		problem.CauseLevel = 3
-	case com.Daemonset:
-		loadNamespacedResource(client, ctx, problem, &appsv1.DaemonSet{}, com.Daemonset, "")
-		problem.CauseLevel = 3
-	case com.Node:
-		loadNamespacedResource(client, ctx, problem, &corev1.Node{}, com.Node, "")
-		problem.CauseLevel = 0
-	case com.Job:
-		loadNamespacedResource(client, ctx, problem, &batchv1.Job{}, com.Job, "")
-		problem.CauseLevel = 5
-	case com.Cronjob:
-		loadNamespacedResource(client, ctx, problem, &batchv1.CronJob{}, com.Cronjob, "")
-		problem.CauseLevel = 5
-	case com.Service:
-		loadNamespacedResource(client, ctx, problem, &corev1.Service{}, com.Service, "")
-		problem.CauseLevel = 6
-	case com.Ingress:
-		problem.CauseLevel = 7
-	case com.Endpoint:
-		loadNamespacedResource(client, ctx, problem, &corev1.Endpoints{}, com.Endpoint, "")
-		problem.CauseLevel = 6
-	default:
-		log.SWithContext(ctx).Warnf("Not found affected resource for resource type %s: ", problem.Tags[com.Resourcetype])
+	resourceType := problem.Tags[com.Resourcetype]
+	if r, ok := resourceObjects[resourceType]; ok {
+		loadNamespacedResource(client, ctx, problem, r.new(), r.kind, r.subType)
+	} else if problem.AffectedResources.Resource == nil {
+		// ingress and flux objects are loaded when building their problems
+		loadCustomResource(ctx, client, problem)
 	}
 	return nil
 }
@@ -70,6 +70,7 @@ func getUnhealthyIngress(ctx context.Context, input *problem.DetectorCreationInp
 func buildIngressProblem(ingress *IngressWithIssue) *problem.Problem {
 	p := initProblem()
 	p.Name = com.IngressMisconfigured
+	p.Code = problem.GetErrorCode(p.Name)
 	p.Description = strings.Replace(strings.Replace(ingress.events[0].Message,
 		"Failed build model due to ", "", 1), "Failed deploy model due to", "", 1)
 	p.Tags = make(map[string]string)
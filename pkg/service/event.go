@@ -15,7 +15,6 @@ import (
 	errors "github.com/fidelity/theliv/pkg/err"
 	"github.com/fidelity/theliv/pkg/kubeclient"
 	log "github.com/fidelity/theliv/pkg/log"
-	"github.com/fidelity/theliv/pkg/observability/k8s"
 )
 
 func GetEvents(ctx context.Context) (interface{}, error) {
@@ -29,8 +28,7 @@ func GetEvents(ctx context.Context) (interface{}, error) {
 	}
 	input.KubeClient = client
 
-	eventRetriever := k8s.NewK8sEventRetriever(client)
-	input.EventRetriever = eventRetriever
+	input.EventRetriever = newEventRetriever(ctx, client)
 
 	filter := invest.CreateEventFilterCriteria(invest.DefaultTimespan,
 		input.EventRetriever.AddFilters("", input.Namespace))
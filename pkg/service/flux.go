@@ -0,0 +1,81 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	in "github.com/fidelity/theliv/internal/investigators"
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	log "github.com/fidelity/theliv/pkg/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Flux kinds checked in the namespace and the problem names, the preferred version of the cluster is listed.
+var fluxKinds = []struct {
+	gvk  schema.GroupVersionKind
+	name string
+}{
+	{schema.GroupVersionKind{Group: problem.FluxHelmGroup, Kind: problem.FluxHelmRelease}, com.FluxHelmReleaseNotReady},
+	{schema.GroupVersionKind{Group: problem.FluxKustomizeGroup, Kind: problem.FluxKustomization}, com.FluxKustomizationNotReady},
+	{schema.GroupVersionKind{Group: problem.FluxSourceGroup, Kind: problem.FluxGitRepository}, com.FluxSourceNotReady},
+	{schema.GroupVersionKind{Group: problem.FluxSourceGroup, Kind: problem.FluxHelmRepository}, com.FluxSourceNotReady},
+}
+
+// Returns the problems of the Flux objects in the namespace which are not ready.
+func getUnhealthyFlux(ctx context.Context, input *problem.DetectorCreationInput) []*problem.Problem {
+	return unhealthyFlux(ctx, listNamespaceObjects(ctx, input))
+}
+
+// Suspended objects are not reconciled and skipped, the kinds are not found if Flux is not installed.
+func unhealthyFlux(ctx context.Context,
+	list func(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error)) []*problem.Problem {
+	problems := []*problem.Problem{}
+	for _, kind := range fluxKinds {
+		items, err := list(kind.gvk)
+		if err != nil {
+			if !meta.IsNoMatchError(err) {
+				log.SWithContext(ctx).Warnf("Failed to list %s, error is %s", kind.gvk.Kind, err)
+			}
+			continue
+		}
+		for i := range items {
+			obj := &items[i]
+			if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); suspended {
+				continue
+			}
+			ready, _ := in.GetCondition(obj, "Ready")
+			stalled, _ := in.GetCondition(obj, "Stalled")
+			if ready.Status == "False" || stalled.Status == "True" {
+				problems = append(problems, buildFluxProblem(kind.name, obj, ready))
+			}
+		}
+	}
+	return problems
+}
+
+func buildFluxProblem(name string, obj *unstructured.Unstructured, ready in.ResourceCondition) *problem.Problem {
+	p := initProblem()
+	p.Name = name
+	p.Code = problem.GetErrorCode(p.Name)
+	p.Description = ready.Message
+	if p.Description == "" {
+		p.Description = fmt.Sprintf("%s %s is not ready.", obj.GetKind(), obj.GetName())
+	}
+	kind := strings.ToLower(obj.GetKind())
+	p.Tags[com.Name] = obj.GetName()
+	p.Tags[com.Namespace] = obj.GetNamespace()
+	p.Tags[com.Resourcetype] = kind
+	p.Tags[kind] = obj.GetName()
+	p.Tags["reason"] = ready.Reason
+	buildAffectedResource(&p, obj.GetName(), kind, obj)
+	return &p
+}
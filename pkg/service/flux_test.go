@@ -0,0 +1,141 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"testing"
+
+	in "github.com/fidelity/theliv/internal/investigators"
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func fluxObject(kind string, name string, suspend bool, conditions ...map[string]interface{}) unstructured.Unstructured {
+	list := make([]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		list = append(list, c)
+	}
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "helm.toolkit.fluxcd.io/v2",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "apps"},
+		"spec":       map[string]interface{}{"suspend": suspend},
+		"status":     map[string]interface{}{"conditions": list},
+	}}
+}
+
+func condition(cType string, status string, reason string, message string) map[string]interface{} {
+	return map[string]interface{}{"type": cType, "status": status, "reason": reason, "message": message}
+}
+
+func TestUnhealthyFlux(t *testing.T) {
+	list := func(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+		switch gvk.Kind {
+		case problem.FluxHelmRelease:
+			return []unstructured.Unstructured{
+				fluxObject(problem.FluxHelmRelease, "podinfo", false,
+					condition("Ready", "False", "UpgradeFailed", "Helm upgrade failed: timed out waiting for the condition")),
+				fluxObject(problem.FluxHelmRelease, "paused", true, condition("Ready", "False", "UpgradeFailed", "")),
+				fluxObject(problem.FluxHelmRelease, "healthy", false, condition("Ready", "True", "UpgradeSucceeded", "")),
+				fluxObject(problem.FluxHelmRelease, "progressing", false, condition("Ready", "Unknown", "Progressing", "")),
+			}, nil
+		case problem.FluxGitRepository:
+			return []unstructured.Unstructured{
+				fluxObject(problem.FluxGitRepository, "repo", false,
+					condition("Ready", "Unknown", "Progressing", ""), condition("Stalled", "True", "InvalidURL", "")),
+			}, nil
+		}
+		return nil, &meta.NoKindMatchError{GroupKind: gvk.GroupKind()}
+	}
+
+	problems := unhealthyFlux(context.Background(), list)
+	assert.Len(t, problems, 2)
+	release := problems[0]
+	assert.Equal(t, com.FluxHelmReleaseNotReady, release.Name)
+	assert.Equal(t, problem.FluxReleaseErr, release.Code)
+	assert.Equal(t, "Helm upgrade failed: timed out waiting for the condition", release.Description)
+	assert.Equal(t, "helmrelease", release.Tags[com.Resourcetype])
+	assert.Equal(t, "podinfo", release.Tags["helmrelease"])
+	assert.Equal(t, "apps", release.Tags[com.Namespace])
+	assert.Equal(t, "UpgradeFailed", release.Tags["reason"])
+	assert.Equal(t, "podinfo", release.AffectedResources.ResourceName)
+
+	source := problems[1]
+	assert.Equal(t, com.FluxSourceNotReady, source.Name)
+	assert.Equal(t, problem.FluxSourceErr, source.Code)
+	assert.Equal(t, "GitRepository repo is not ready.", source.Description)
+}
+
+// The Kustomization applies the HelmRelease, the HelmRelease installs the deployment of the pod. The pod is in the
+// report card of the HelmRelease, the Kustomization has its own.
+func TestFluxGrouping(t *testing.T) {
+	loadTestConfig(t, "port: 8080\nclusterDir: %s\n")
+
+	controller := true
+	labels := func(group string, name string, namespace string) map[string]string {
+		return map[string]string{group + "/name": name, group + "/namespace": namespace}
+	}
+	deploy := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "apps", UID: "d1",
+			Labels: labels(problem.FluxHelmGroup, "podinfo", "apps")},
+	}
+	rs := &appsv1.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: "podinfo-7d9f", Namespace: "apps", UID: "r1",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "podinfo", Controller: &controller}}},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "podinfo-7d9f-x2k4p", Namespace: "apps", UID: "p1",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "podinfo-7d9f", Controller: &controller}}}}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), meta.RESTScopeNamespace)
+	scheme := runtime.NewScheme()
+	assert.Nil(t, appsv1.AddToScheme(scheme))
+	client, err := kubeclient.NewKubeClientFor(dynamicfake.NewSimpleDynamicClient(scheme, deploy, rs), mapper)
+	assert.Nil(t, err)
+
+	notReady := in.ResourceCondition{Type: "Ready", Status: "False", Reason: "ReconciliationFailed"}
+	kustomization := fluxObject(problem.FluxKustomization, "apps", false)
+	kustomization.SetAPIVersion("kustomize.toolkit.fluxcd.io/v1")
+	kustomization.SetNamespace("flux-system")
+	release := fluxObject(problem.FluxHelmRelease, "podinfo", false)
+	release.SetLabels(labels(problem.FluxKustomizeGroup, "apps", "flux-system"))
+
+	podProblem := initProblem()
+	podProblem.Name = "PodNotReady"
+	podProblem.Tags[com.Resourcetype] = com.Pod
+	buildAffectedResource(&podProblem, pod.Name, com.Pod, pod)
+	problems := []*problem.Problem{
+		buildFluxProblem("FluxKustomizationNotReady", &kustomization, notReady),
+		buildFluxProblem("FluxHelmReleaseNotReady", &release, notReady),
+		&podProblem,
+	}
+
+	result, err := problem.Aggregate(context.Background(), problems, client, problem.DefaultGrouping)
+	assert.Nil(t, err)
+	cards := map[string]*problem.ReportCard{}
+	for _, card := range result.([]*problem.ReportCard) {
+		cards[card.TopResourceType+"/"+card.Name] = card
+	}
+	assert.Len(t, cards, 2)
+	assert.Len(t, cards[problem.FluxHelmRelease+"/podinfo"].Resources, 2)
+	assert.Len(t, cards[problem.FluxKustomization+"/apps"].Resources, 1)
+}
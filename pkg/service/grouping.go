@@ -0,0 +1,38 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/config"
+	log "github.com/fidelity/theliv/pkg/log"
+)
+
+// Returns the grouping strategies of the request, or of the cluster in config, or the default in config,
+// or problem.DefaultGrouping. Invalid strategies in config are logged and the default is used.
+func groupingStrategies(ctx context.Context, input *problem.DetectorCreationInput) []string {
+	if len(input.Grouping) > 0 {
+		return input.Grouping
+	}
+	conf := config.GetThelivConfig().Grouping
+	if conf == nil {
+		return problem.DefaultGrouping
+	}
+	strategies := conf.Strategies
+	if s := conf.Clusters[input.ClusterName]; len(s) > 0 {
+		strategies = s
+	}
+	if len(strategies) == 0 {
+		return problem.DefaultGrouping
+	}
+	if err := problem.ValidateGrouping(strategies); err != nil {
+		log.SWithContext(ctx).Errorf("Invalid grouping config of cluster %s, error is %s", input.ClusterName, err)
+		return problem.DefaultGrouping
+	}
+	return strategies
+}
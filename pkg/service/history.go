@@ -0,0 +1,99 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	HistoricalDeleted   = "Deleted after the detection window, details are not available."
+	HistoricalRecreated = "Recreated after the detection window, showing the current resource."
+	HistoricalChanged   = "Changed after the detection window, showing the current state."
+)
+
+// Labels of kube_pod_owner, joined into the pod alerts of the alerting rules.
+const (
+	ownerKindLabel         = "owner_kind"
+	ownerNameLabel         = "owner_name"
+	ownerIsControllerLabel = "owner_is_controller"
+)
+
+// API versions of the pod owner kinds reported by kube-state-metrics.
+var ownerAPIVersions = map[string]string{
+	"ReplicaSet":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"DaemonSet":   "apps/v1",
+	"Job":         "batch/v1",
+}
+
+// Resources of historical detection are loaded as they are now. Resources deleted since the window
+// are replaced by an object with the name only, so the report cards are still built.
+// Changed resources are found by the generation at the end of the window.
+func noteHistoricalResources(ctx context.Context, input *problem.DetectorCreationInput, problems []*problem.Problem) {
+	generations, err := prometheus.GetGenerations(ctx, input, input.Window.To)
+	if err != nil {
+		log.SWithContext(ctx).Warnf("Changed resources are not noted, error is %s", err)
+	}
+	for _, p := range problems {
+		if p.AffectedResources.Resource == nil {
+			r, ok := resourceObjects[p.Tags[com.Resourcetype]]
+			if !ok {
+				continue
+			}
+			obj := r.new()
+			meta := obj.(metav1.Object)
+			meta.SetName(p.Tags[r.kind])
+			if r.kind != com.Node {
+				meta.SetNamespace(p.Tags[com.Namespace])
+			}
+			// the placeholders of the same owner are grouped in one report card
+			if owner := recordedOwner(p.Tags); owner != nil {
+				meta.SetOwnerReferences([]metav1.OwnerReference{*owner})
+			}
+			name, kind := p.Tags[r.kind], r.kind
+			if r.subType != "" {
+				name, kind = p.Tags[r.subType], r.subType
+			}
+			buildAffectedResource(p, name, kind, obj)
+			p.HistoricalNote = HistoricalDeleted
+			continue
+		}
+		if meta, ok := p.AffectedResources.Resource.(metav1.Object); ok {
+			p.HistoricalNote = historicalNote(meta, generations[p.Tags[com.Resourcetype]+"/"+meta.GetName()],
+				input.Window)
+		}
+	}
+}
+
+// The resource is changed if its generation is newer than the generation observed at the end of the window,
+// observed is zero if kube-state-metrics has no generation of the kind, e.g. pods.
+func historicalNote(meta metav1.Object, observed int64, window *problem.TimeWindow) string {
+	if meta.GetCreationTimestamp().Time.After(window.To) {
+		return HistoricalRecreated
+	}
+	if observed > 0 && meta.GetGeneration() > observed {
+		return HistoricalChanged
+	}
+	return ""
+}
+
+// Returns the controller owner recorded in the alert labels, nil if not recorded.
+func recordedOwner(tags map[string]string) *metav1.OwnerReference {
+	kind, name := tags[ownerKindLabel], tags[ownerNameLabel]
+	apiVersion, ok := ownerAPIVersions[kind]
+	if !ok || name == "" || name == "<none>" || tags[ownerIsControllerLabel] == "false" {
+		return nil
+	}
+	controller := true
+	return &metav1.OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, Controller: &controller}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+func TestNoteHistoricalResources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+		assert.Contains(t, r.FormValue("query"), `namespace="ns1",cluster="c1"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"__name__":"kube_deployment_metadata_generation","deployment":"web"},"value":[1704110400,"3"]},
+			{"metric":{"__name__":"kube_deployment_metadata_generation","deployment":"api"},"value":[1704110400,"2"]}]}}`))
+	}))
+	defer server.Close()
+
+	to := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Namespace:   "ns1",
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL,
+			ClusterLabel: "cluster",
+		},
+		Window: &problem.TimeWindow{From: to.Add(-time.Hour), To: to},
+	}
+	pod := func(created time.Time) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", CreationTimestamp: metav1.NewTime(created),
+			Generation: 5}}
+	}
+	deployment := func(name string, generation int64) *problem.Problem {
+		return &problem.Problem{
+			Tags: map[string]string{com.Resourcetype: com.Deployment},
+			AffectedResources: problem.ResourceDetails{Resource: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+				Name: name, CreationTimestamp: metav1.NewTime(to.Add(-time.Hour)), Generation: generation}}},
+		}
+	}
+
+	deleted := &problem.Problem{Tags: map[string]string{
+		com.Resourcetype: com.Container, com.Pod: "web-0", com.Container: "app", com.Namespace: "ns1",
+		"owner_kind": "ReplicaSet", "owner_name": "web-5d8f7", "owner_is_controller": "true"}}
+	orphan := &problem.Problem{Tags: map[string]string{
+		com.Resourcetype: com.Pod, com.Pod: "debug", com.Namespace: "ns1", "owner_kind": "<none>", "owner_name": "<none>"}}
+	recreated := &problem.Problem{Tags: map[string]string{com.Resourcetype: com.Pod},
+		AffectedResources: problem.ResourceDetails{Resource: pod(to.Add(time.Minute))}}
+	// pods have no generation metrics, they are not changed
+	unchanged := &problem.Problem{Tags: map[string]string{com.Resourcetype: com.Pod},
+		AffectedResources: problem.ResourceDetails{Resource: pod(to.Add(-time.Hour))}}
+	changed, same := deployment("web", 4), deployment("api", 2)
+	noteHistoricalResources(context.Background(), input,
+		[]*problem.Problem{deleted, orphan, recreated, unchanged, changed, same})
+
+	assert.Equal(t, HistoricalDeleted, deleted.HistoricalNote)
+	assert.Equal(t, "app", deleted.AffectedResources.ResourceName)
+	assert.Equal(t, com.Container, deleted.AffectedResources.ResourceKind)
+	stub := deleted.AffectedResources.Resource.(*corev1.Pod)
+	assert.Equal(t, "web-0", stub.Name)
+	assert.Equal(t, "ns1", stub.Namespace)
+	assert.Len(t, stub.OwnerReferences, 1)
+	assert.Equal(t, "apps/v1", stub.OwnerReferences[0].APIVersion)
+	assert.Equal(t, "ReplicaSet", stub.OwnerReferences[0].Kind)
+	assert.Equal(t, "web-5d8f7", stub.OwnerReferences[0].Name)
+	assert.True(t, *stub.OwnerReferences[0].Controller)
+	assert.Empty(t, orphan.AffectedResources.Resource.(*corev1.Pod).OwnerReferences)
+	assert.Equal(t, HistoricalRecreated, recreated.HistoricalNote)
+	assert.Empty(t, unchanged.HistoricalNote)
+	assert.Equal(t, HistoricalChanged, changed.HistoricalNote)
+	assert.Empty(t, same.HistoricalNote)
+}
+
+func TestSaveHistoricalDetection(t *testing.T) {
+	to := time.Now()
+	ctx := SetDetectorInput(context.Background(), &problem.DetectorCreationInput{ClusterName: "c1", Namespace: "ns1",
+		Window: &problem.TimeWindow{From: to.Add(-time.Hour), To: to}})
+	detection, err := SaveDetection(ctx, "", []*problem.ReportCard{})
+	assert.Nil(t, err)
+	assert.Nil(t, detection)
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"sync"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/metrics"
+)
+
+// Issue fingerprints of the latest detection by cluster and namespace, a persisting issue is counted
+// once when it first appears, not again by every API call, watch re-run or webhook run.
+var seenIssues = struct {
+	sync.Mutex
+	fingerprints map[string]map[string]bool
+}{fingerprints: make(map[string]map[string]bool)}
+
+// recordNewIssues counts the issues not in the previous detection of the cluster and namespace.
+// Historical and custom grouping detections are skipped, the card fingerprints differ by grouping.
+func recordNewIssues(input *problem.DetectorCreationInput, cards []*problem.ReportCard) {
+	if input.Window != nil || len(input.Grouping) > 0 {
+		return
+	}
+	for _, issue := range newIssues(input.ClusterName+"/"+input.Namespace, cards) {
+		metrics.RecordIssue(string(issue.Code), string(issue.DomainName), string(issue.Severity))
+	}
+}
+
+func newIssues(key string, cards []*problem.ReportCard) []*problem.ReportCardIssue {
+	current := make(map[string]bool)
+	issues := make([]*problem.ReportCardIssue, 0)
+
+	seenIssues.Lock()
+	defer seenIssues.Unlock()
+	previous := seenIssues.fingerprints[key]
+	for _, card := range cards {
+		for _, res := range card.Resources {
+			if res.Issue == nil {
+				continue
+			}
+			fp := problem.IssueFingerprint(card, res)
+			if !previous[fp] && !current[fp] {
+				issues = append(issues, res.Issue)
+			}
+			current[fp] = true
+		}
+	}
+	seenIssues.fingerprints[key] = current
+	return issues
+}
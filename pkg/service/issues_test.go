@@ -0,0 +1,34 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIssues(t *testing.T) {
+	card := func(codes ...problem.ErrorCode) []*problem.ReportCard {
+		c := &problem.ReportCard{Name: "web", TopResourceType: "Deployment"}
+		for _, code := range codes {
+			c.Resources = append(c.Resources, &problem.ReportCardResource{Name: "web-1",
+				Issue: &problem.ReportCardIssue{Code: code}})
+		}
+		return []*problem.ReportCard{c}
+	}
+
+	assert.Len(t, newIssues("dev/ns", card(problem.CrashLoopErr)), 1)
+	// a persisting issue is not counted again
+	assert.Empty(t, newIssues("dev/ns", card(problem.CrashLoopErr)))
+	assert.Len(t, newIssues("dev/ns", card(problem.CrashLoopErr, problem.OOMKilledErr)), 1)
+	// other namespaces are tracked separately
+	assert.Len(t, newIssues("dev/other", card(problem.CrashLoopErr)), 1)
+	// a resolved issue is counted again when it reappears
+	assert.Empty(t, newIssues("dev/ns", card()))
+	assert.Len(t, newIssues("dev/ns", card(problem.CrashLoopErr)), 1)
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/observability"
+	"github.com/fidelity/theliv/pkg/observability/elasticsearch"
+	"github.com/fidelity/theliv/pkg/observability/k8s"
+)
+
+// Returns the EventRetriever selected by ThelivConfig.EventDriver, defaults to k8s events.
+// The k8s retriever is cached, events of a namespace are listed once and shared by all the investigators.
+func newEventRetriever(ctx context.Context, client *kubeclient.KubeClient) observability.EventRetriever {
+	thelivcfg := config.GetThelivConfig()
+	switch thelivcfg.EventDriver {
+	case config.DriverElasticsearch:
+		if esConfigured(thelivcfg.Elasticsearch) {
+			return elasticsearch.NewEsEventRetriever(thelivcfg.Elasticsearch)
+		}
+		log.SWithContext(ctx).Warnf("Event driver %s is not configured, use k8s events", thelivcfg.EventDriver)
+	}
+	return k8s.NewCachedK8sEventRetriever(client)
+}
+
+// Returns the LogRetriever selected by ThelivConfig.LogDriver, nil if no log driver available.
+func newLogRetriever(ctx context.Context) observability.LogRetriever {
+	thelivcfg := config.GetThelivConfig()
+	switch thelivcfg.LogDriver {
+	case config.DriverElasticsearch:
+		if esConfigured(thelivcfg.Elasticsearch) {
+			return elasticsearch.NewEsLogRetriever(thelivcfg.Elasticsearch)
+		}
+		log.SWithContext(ctx).Warnf("Log driver %s is not configured", thelivcfg.LogDriver)
+	}
+	return nil
+}
+
+func esConfigured(conf *config.ElasticsearchConfig) bool {
+	return conf != nil && conf.Address != ""
+}
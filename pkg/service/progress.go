@@ -0,0 +1,97 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fidelity/theliv/internal/problem"
+)
+
+// Progress stages of DetectAlerts, reported when the detection is streamed.
+const (
+	StageAlertsFetched   = "alerts"
+	StageProblemsBuilt   = "problems"
+	StageInvestigated    = "investigated"
+	StageCardsAggregated = "aggregated"
+)
+
+type ProgressEvent struct {
+	Stage   string `json:"stage"`
+	Count   int    `json:"count,omitempty"`
+	Total   int    `json:"total,omitempty"`
+	Problem string `json:"problem,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ProgressFunc receives the progress of DetectAlerts, it may be called concurrently by investigators.
+type ProgressFunc func(event ProgressEvent)
+
+func SetProgressFunc(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, ProgressKey, f)
+}
+
+func reportProgress(ctx context.Context, event ProgressEvent) {
+	if f, ok := ctx.Value(ProgressKey).(ProgressFunc); ok && f != nil {
+		f(event)
+	}
+}
+
+// CardFunc receives each report card of DetectAlerts when all its problems are investigated, it may be called
+// concurrently. Root causes and scores are set after all the report cards are complete.
+type CardFunc func(card *problem.ReportCard)
+
+func SetCardFunc(ctx context.Context, f CardFunc) context.Context {
+	return context.WithValue(ctx, CardKey, f)
+}
+
+func reportCard(ctx context.Context, card *problem.ReportCard) {
+	if f, ok := ctx.Value(CardKey).(CardFunc); ok && f != nil {
+		f(card)
+	}
+}
+
+// Run the investigators of the problem, then add the problem to its report card.
+func investigate(ctx context.Context, wg *sync.WaitGroup, funcs []investigatorFunc, p *problem.Problem,
+	input *problem.DetectorCreationInput, progress *investigationProgress, builder *problem.CardBuilder) {
+	defer wg.Done()
+	var pwg sync.WaitGroup
+	for _, fc := range funcs {
+		pwg.Add(1)
+		go runInvestigator(ctx, &pwg, fc, p, input, progress)
+	}
+	pwg.Wait()
+	builder.Add(ctx, p)
+}
+
+// Run the investigator and report progress when it is done.
+func runInvestigator(ctx context.Context, wg *sync.WaitGroup, fc investigatorFunc, p *problem.Problem,
+	input *problem.DetectorCreationInput, progress *investigationProgress) {
+	defer wg.Done()
+	var iwg sync.WaitGroup
+	iwg.Add(1)
+	fc(ctx, &iwg, p, input)
+	reportProgress(ctx, ProgressEvent{
+		Stage:   StageInvestigated,
+		Count:   progress.done(),
+		Total:   progress.total,
+		Problem: p.Name,
+	})
+}
+
+type investigationProgress struct {
+	mx       sync.Mutex
+	total    int
+	finished int
+}
+
+func (p *investigationProgress) done() int {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.finished++
+	return p.finished
+}
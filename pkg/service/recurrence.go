@@ -0,0 +1,244 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/database/etcd"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/prometheus"
+)
+
+// ALERTS history of the namespaces, reused by the detections within the cache period.
+var firingCache = &firingIntervals{entries: make(map[string]*firingEntry)}
+
+type firingIntervals struct {
+	mx      sync.Mutex
+	entries map[string]*firingEntry
+}
+
+type firingEntry struct {
+	alerts     map[string]bool
+	namespaces map[string]bool
+	intervals  map[string][]problem.Interval
+	expires    time.Time
+}
+
+// Computes the recurrence of the alert problems in the recurrence window ending now, or at the end of
+// the historical window. The recurring problems get the recurrence solution hint.
+// Recurrence is optional, errors are logged only.
+func addRecurrence(ctx context.Context, input *problem.DetectorCreationInput, problems []*problem.Problem) {
+	conf := recurrenceConfig()
+	if conf.Disabled || input.Refresh || len(problems) == 0 {
+		return
+	}
+	alerts, namespaces := alertLabels(problems)
+	if len(alerts) == 0 {
+		return
+	}
+	end := time.Now()
+	if input.Window != nil {
+		end = input.Window.To
+	}
+	from := end.Add(-time.Duration(conf.WindowHours) * time.Hour)
+
+	var intervals map[string][]problem.Interval
+	var err error
+	if conf.Source == config.RecurrenceDetections {
+		intervals, err = detectionIntervals(ctx, input.ClusterName, input.Namespace, from, end)
+	} else {
+		intervals, err = firingCache.get(ctx, input, alerts, namespaces, from, end,
+			time.Duration(conf.CacheSeconds)*time.Second)
+	}
+	if err != nil {
+		log.SWithContext(ctx).Errorf("Failed to compute the recurrence of problems, error is %s", err)
+		return
+	}
+	for _, p := range problems {
+		if p.Tags["alertname"] == "" {
+			continue
+		}
+		list := intervals[problem.RecurrenceKey(p.Tags)]
+		if conf.Source == config.RecurrenceDetections {
+			// the current detection is not stored yet
+			start := p.ActiveAt
+			if start.IsZero() || start.After(end) {
+				start = end
+			}
+			list = problem.MergeIntervals(append(list, problem.Interval{Start: start, End: end}))
+		}
+		setRecurrence(p, problem.ComputeRecurrence(list, conf.WindowHours, conf.MinOccurrences,
+			time.Duration(conf.FlappingSeconds)*time.Second))
+	}
+}
+
+func setRecurrence(p *problem.Problem, r *problem.Recurrence) {
+	p.Recurrence = r
+	if hint := problem.RecurrenceSolution(r); hint != "" {
+		p.SolutionDetails.Append(hint)
+	}
+}
+
+// Names and namespaces of the alerts of the problems, sorted.
+func alertLabels(problems []*problem.Problem) ([]string, []string) {
+	alerts, namespaces := make(map[string]bool), make(map[string]bool)
+	for _, p := range problems {
+		if p.Tags["alertname"] != "" {
+			alerts[p.Tags["alertname"]] = true
+			namespaces[p.Tags[com.Namespace]] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(alerts)), slices.Sorted(maps.Keys(namespaces))
+}
+
+// Returns the firing intervals of the alerts, the cached intervals of the namespace are reused if they
+// cover the alerts and have not expired. Historical detections are not cached.
+func (c *firingIntervals) get(ctx context.Context, input *problem.DetectorCreationInput, alerts []string,
+	namespaces []string, from time.Time, to time.Time, ttl time.Duration) (map[string][]problem.Interval, error) {
+	if input.Window != nil {
+		return prometheus.GetFiringIntervals(ctx, input, alerts, namespaces, from, to)
+	}
+	key := input.ClusterName + "/" + input.Namespace
+	c.mx.Lock()
+	entry := c.entries[key]
+	c.mx.Unlock()
+	if entry != nil && entry.covers(alerts, namespaces, to) {
+		return entry.intervals, nil
+	}
+	intervals, err := prometheus.GetFiringIntervals(ctx, input, alerts, namespaces, from, to)
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, alerts, namespaces, intervals, to.Add(ttl))
+	return intervals, nil
+}
+
+func (c *firingIntervals) put(key string, alerts []string, namespaces []string,
+	intervals map[string][]problem.Interval, expires time.Time) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for k, e := range c.entries {
+		if !e.expires.After(time.Now()) {
+			delete(c.entries, k)
+		}
+	}
+	entry := &firingEntry{
+		alerts:     make(map[string]bool, len(alerts)),
+		namespaces: make(map[string]bool, len(namespaces)),
+		intervals:  intervals,
+		expires:    expires,
+	}
+	for _, a := range alerts {
+		entry.alerts[a] = true
+	}
+	for _, ns := range namespaces {
+		entry.namespaces[ns] = true
+	}
+	c.entries[key] = entry
+}
+
+func (e *firingEntry) covers(alerts []string, namespaces []string, now time.Time) bool {
+	if !e.expires.After(now) {
+		return false
+	}
+	for _, a := range alerts {
+		if !e.alerts[a] {
+			return false
+		}
+	}
+	for _, ns := range namespaces {
+		if !e.namespaces[ns] {
+			return false
+		}
+	}
+	return true
+}
+
+func recurrenceConfig() config.RecurrenceConfig {
+	conf := config.RecurrenceConfig{}
+	if d := config.GetThelivConfig().Detection; d != nil && d.Recurrence != nil {
+		conf = *d.Recurrence
+	}
+	if conf.WindowHours <= 0 {
+		conf.WindowHours = problem.DefaultRecurrenceWindowHours
+	}
+	if conf.MinOccurrences <= 0 {
+		conf.MinOccurrences = problem.DefaultMinOccurrences
+	}
+	if conf.FlappingSeconds <= 0 {
+		conf.FlappingSeconds = problem.DefaultFlappingSeconds
+	}
+	if conf.CacheSeconds <= 0 {
+		conf.CacheSeconds = problem.DefaultRecurrenceCacheSeconds
+	}
+	return conf
+}
+
+// Intervals of the issues in the stored detections of the namespace between from and to,
+// read from the detection index in one range.
+func detectionIntervals(ctx context.Context, cluster string, namespace string, from time.Time,
+	to time.Time) (map[string][]problem.Interval, error) {
+	prefix := detectionIndexPrefix(cluster, namespace) + "/"
+	index, err := etcd.GetWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]indexedDetection, 0, len(index))
+	for k, v := range index {
+		nanos, err := strconv.ParseInt(strings.TrimPrefix(k, prefix), 10, 64)
+		if err != nil || nanos < from.UnixNano() || nanos > to.UnixNano() {
+			continue
+		}
+		entry, legacy, err := parseDetectionIndex(v)
+		if err != nil || legacy {
+			continue
+		}
+		entries = append(entries, indexedDetection{timestamp: time.Unix(0, nanos).UTC(), issues: entry.Issues})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+	return toDetectionIntervals(entries), nil
+}
+
+type indexedDetection struct {
+	timestamp time.Time
+	issues    []string
+}
+
+// An issue found in consecutive detections is one interval, from the first to the last of them.
+// Detections must be sorted by time.
+func toDetectionIntervals(detections []indexedDetection) map[string][]problem.Interval {
+	intervals := make(map[string][]problem.Interval)
+	previous := map[string]bool{}
+	for _, d := range detections {
+		current := map[string]bool{}
+		for _, key := range d.issues {
+			if current[key] {
+				continue
+			}
+			current[key] = true
+			if previous[key] {
+				intervals[key][len(intervals[key])-1].End = d.timestamp
+			} else {
+				intervals[key] = append(intervals[key], problem.Interval{Start: d.timestamp, End: d.timestamp})
+			}
+		}
+		previous = current
+	}
+	return intervals
+}
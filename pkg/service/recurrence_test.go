@@ -0,0 +1,116 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/rest"
+)
+
+func TestToDetectionIntervals(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tags := map[string]string{"alertname": "PodNotReady", "namespace": "ns1", "pod": "web-0"}
+	detection := func(minutes int, found bool) indexedDetection {
+		d := &Detection{ID: "d1", Timestamp: t0.Add(time.Duration(minutes) * time.Minute)}
+		if found {
+			d.Cards = []*problem.ReportCard{{Resources: []*problem.ReportCardResource{{Issue: &problem.ReportCardIssue{Tags: tags}}}}}
+		}
+		value, _ := json.Marshal(newDetectionIndex(d))
+		index, legacy, err := parseDetectionIndex(value)
+		assert.Nil(t, err)
+		assert.False(t, legacy)
+		return indexedDetection{timestamp: d.Timestamp, issues: index.Issues}
+	}
+	intervals := toDetectionIntervals([]indexedDetection{
+		detection(0, true), detection(10, true), detection(20, false), detection(30, true),
+	})
+	assert.Equal(t, []problem.Interval{
+		{Start: t0, End: t0.Add(10 * time.Minute)},
+		{Start: t0.Add(30 * time.Minute), End: t0.Add(30 * time.Minute)},
+	}, intervals[problem.RecurrenceKey(tags)])
+
+	// index values of the detection ID only
+	index, legacy, err := parseDetectionIndex([]byte(`"d1"`))
+	assert.Nil(t, err)
+	assert.True(t, legacy)
+	assert.Equal(t, "d1", index.ID)
+}
+
+func TestFiringCache(t *testing.T) {
+	queries := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.FormValue("query"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+	}))
+	defer server.Close()
+
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  &rest.Config{Host: "https://unused"},
+		ClusterName: "c1",
+		Namespace:   "ns1",
+		Prometheus: &config.PrometheusEndpoint{
+			Mode:         config.PrometheusDirect,
+			Address:      server.URL,
+			ClusterLabel: "cluster",
+		},
+	}
+	problems := []*problem.Problem{
+		{Tags: map[string]string{"alertname": "PodNotReady", "namespace": "ns1"}},
+		{Tags: map[string]string{"alertname": "NodeNotReady"}},
+		{Tags: map[string]string{"namespace": "ns1"}},
+	}
+	alerts, namespaces := alertLabels(problems)
+	assert.Equal(t, []string{"NodeNotReady", "PodNotReady"}, alerts)
+	assert.Equal(t, []string{"", "ns1"}, namespaces)
+
+	cache := &firingIntervals{entries: make(map[string]*firingEntry)}
+	now := time.Now()
+	ctx := context.Background()
+	_, err := cache.get(ctx, input, alerts, namespaces, now.Add(-time.Hour), now, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`ALERTS{alertstate="firing",cluster="c1",namespace=~"|ns1",alertname=~"NodeNotReady|PodNotReady"}`,
+	}, queries)
+
+	// the cached alerts are reused
+	_, err = cache.get(ctx, input, []string{"PodNotReady"}, []string{"ns1"}, now.Add(-time.Hour), now, time.Minute)
+	assert.Nil(t, err)
+	assert.Len(t, queries, 1)
+
+	// other alerts or expired entries are queried
+	_, err = cache.get(ctx, input, []string{"PodNotScheduled"}, []string{"ns1"}, now.Add(-time.Hour), now, time.Minute)
+	assert.Nil(t, err)
+	assert.Len(t, queries, 2)
+	_, err = cache.get(ctx, input, []string{"PodNotScheduled"}, []string{"ns1"}, now, now.Add(2*time.Minute),
+		time.Minute)
+	assert.Nil(t, err)
+	assert.Len(t, queries, 3)
+}
+
+func TestKeepRecurrence(t *testing.T) {
+	tags := map[string]string{"alertname": "PodNotReady", "namespace": "ns1", "pod": "web-0"}
+	recurrence := &problem.Recurrence{Occurrences: 4, WindowHours: 168, Recurring: true}
+	previous := []*problem.Problem{{Tags: tags, Recurrence: recurrence}}
+	problems := []*problem.Problem{
+		{Tags: tags, SolutionDetails: com.InitLockedSlice()},
+		{Tags: map[string]string{"alertname": "PodNotReady", "namespace": "ns1", "pod": "api-0"}},
+	}
+	keepRecurrence(previous, problems)
+	assert.Equal(t, recurrence, problems[0].Recurrence)
+	assert.Equal(t, 1, len(problems[0].SolutionDetails.GetStore()))
+	assert.Nil(t, problems[1].Recurrence)
+}
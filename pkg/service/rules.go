@@ -0,0 +1,174 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	com "github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	theErr "github.com/fidelity/theliv/pkg/err"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Registration waits for the check, so a slow Prometheus or API server can't stall it for long.
+// The steps timed out are in the warnings, GET /rules/{cluster}/check runs the check without the bound.
+const rulesCheckTimeout = 5 * time.Second
+
+var prometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+
+// RulesCheck verifies the alerting rules Theliv requires are installed on the cluster.
+type RulesCheck struct {
+	Cluster string `json:"cluster"`
+	// false if the PrometheusRule CRD of the Prometheus operator is not installed, rules may be in rule files
+	PrometheusRuleCRD bool `json:"prometheusRuleCRD"`
+	// PrometheusRule objects defining the required rules, namespace/name
+	PrometheusRules []string `json:"prometheusRules,omitempty"`
+	// required alerts not defined in any PrometheusRule, checked only if the CRD is installed
+	NotDefined []string `json:"notDefined,omitempty"`
+	// required alerts not loaded by Prometheus
+	NotLoaded        []string `json:"notLoaded,omitempty"`
+	KubeStateMetrics bool     `json:"kubeStateMetrics"`
+	Errors           []string `json:"errors,omitempty"`
+	// all the required alerts are loaded and kube-state-metrics is scraped
+	Healthy bool `json:"healthy"`
+}
+
+// CheckRules checks the PrometheusRule objects with the dynamic client, the rules loaded by Prometheus,
+// and the kube-state-metrics target of the cluster. Only the kubeconfig errors are returned,
+// other errors are in the result.
+func CheckRules(ctx context.Context, cluster string) (*RulesCheck, error) {
+	contact := fmt.Sprintf(com.Contact, config.GetThelivConfig().TeamName)
+	conf, err := config.GetConfigLoader().GetKubernetesConfig(ctx, cluster)
+	if err != nil {
+		return nil, theErr.NewCommonError(ctx, 4, com.LoadKubeConfigFailed+contact)
+	}
+	kubeconfig, err := conf.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, theErr.NewCommonError(ctx, 4, com.LoadKubeConfigFailed+contact)
+	}
+	client, err := kubeclient.NewKubeClient(ctx, kubeconfig)
+	if err != nil {
+		return nil, theErr.NewCommonError(ctx, 4, com.LoadKubeConfigFailed+contact)
+	}
+	input := &problem.DetectorCreationInput{
+		Kubeconfig:  kubeconfig,
+		ClusterName: cluster,
+		Prometheus:  conf.GetPrometheusEndpoint(),
+	}
+
+	check := &RulesCheck{Cluster: cluster}
+	required := prometheus.RequiredAlerts()
+	list, err := client.ListUnstructured(ctx, prometheusRuleGVK, "", metav1.ListOptions{})
+	switch {
+	case meta.IsNoMatchError(err):
+	case err != nil:
+		check.Errors = append(check.Errors, "failed to list PrometheusRule: "+err.Error())
+	default:
+		check.PrometheusRuleCRD = true
+		check.PrometheusRules, check.NotDefined = definedRules(list, required)
+	}
+
+	loaded, err := prometheus.GetLoadedAlerts(ctx, input)
+	if err != nil {
+		check.Errors = append(check.Errors, "failed to get prometheus rules: "+err.Error())
+	} else {
+		check.NotLoaded = missingAlerts(required, loaded)
+	}
+	if check.KubeStateMetrics, err = prometheus.IsKubeStateMetricsScraped(ctx, input); err != nil {
+		check.Errors = append(check.Errors, "failed to query kube-state-metrics target: "+err.Error())
+	}
+	check.Healthy = len(check.Errors) == 0 && len(check.NotLoaded) == 0 && check.KubeStateMetrics
+	return check, nil
+}
+
+// CheckRegisteredRules checks the rules of the newly registered cluster, the misconfigurations are logged
+// and returned as warnings for the registrant. The check is nil if it failed.
+func CheckRegisteredRules(ctx context.Context, cluster string) (*RulesCheck, []string) {
+	ctx, cancel := context.WithTimeout(ctx, rulesCheckTimeout)
+	defer cancel()
+	l := log.SWithContext(ctx)
+	check, err := CheckRules(ctx, cluster)
+	if err != nil {
+		l.Errorf("Failed to check the alerting rules of cluster %s, error is %s", cluster, err)
+		return nil, []string{"alerting rules are not checked: " + err.Error()}
+	}
+	if check.Healthy {
+		l.Infof("Alerting rules of cluster %s are loaded", cluster)
+		return check, nil
+	}
+	l.Warnf("Cluster %s is misconfigured for Theliv, alerts not loaded %v, kube-state-metrics scraped %t, errors %v",
+		cluster, check.NotLoaded, check.KubeStateMetrics, check.Errors)
+	return check, rulesWarnings(check)
+}
+
+func rulesWarnings(check *RulesCheck) []string {
+	warnings := make([]string, 0)
+	if len(check.NotLoaded) > 0 {
+		warnings = append(warnings, "alerts not loaded by Prometheus: "+strings.Join(check.NotLoaded, ", "))
+	}
+	if !check.KubeStateMetrics {
+		warnings = append(warnings, "kube-state-metrics of the cluster is not scraped")
+	}
+	return append(warnings, check.Errors...)
+}
+
+// Returns the PrometheusRule objects defining any of the required alerts, and the required alerts not defined.
+func definedRules(list *unstructured.UnstructuredList, required []string) ([]string, []string) {
+	requiredSet := make(map[string]bool, len(required))
+	for _, a := range required {
+		requiredSet[a] = true
+	}
+	objects := make([]string, 0)
+	defined := make(map[string]bool)
+	for _, item := range list.Items {
+		groups, _, _ := unstructured.NestedSlice(item.Object, "spec", "groups")
+		found := false
+		for _, g := range groups {
+			group, ok := g.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			rules, _, _ := unstructured.NestedSlice(group, "rules")
+			for _, r := range rules {
+				rule, ok := r.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if alert, _ := rule["alert"].(string); requiredSet[alert] {
+					defined[alert] = true
+					found = true
+				}
+			}
+		}
+		if found {
+			objects = append(objects, item.GetNamespace()+"/"+item.GetName())
+		}
+	}
+	sort.Strings(objects)
+	return objects, missingAlerts(required, defined)
+}
+
+func missingAlerts(required []string, found map[string]bool) []string {
+	missing := make([]string, 0)
+	for _, a := range required {
+		if !found[a] {
+			missing = append(missing, a)
+		}
+	}
+	return missing
+}
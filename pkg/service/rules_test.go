@@ -0,0 +1,43 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDefinedRules(t *testing.T) {
+	rule := func(name string, alerts ...string) unstructured.Unstructured {
+		rules := make([]interface{}, 0)
+		for _, a := range alerts {
+			rules = append(rules, map[string]interface{}{"alert": a, "expr": "up == 0"})
+		}
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "namespace": "monitoring"},
+			"spec":     map[string]interface{}{"groups": []interface{}{map[string]interface{}{"name": "g", "rules": rules}}},
+		}}
+	}
+	list := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+		rule("theliv", "PodNotReady", "NodeNotReady"), rule("other", "HighLatency"),
+	}}
+	objects, missing := definedRules(list, []string{"PodNotReady", "NodeNotReady", "PodNotRunning"})
+	assert.Equal(t, []string{"monitoring/theliv"}, objects)
+	assert.Equal(t, []string{"PodNotRunning"}, missing)
+}
+
+func TestRulesWarnings(t *testing.T) {
+	check := &RulesCheck{NotLoaded: []string{"PodNotReady", "DaemonSetUnavailable"},
+		Errors: []string{"failed to list PrometheusRule: forbidden"}}
+	assert.Equal(t, []string{
+		"alerts not loaded by Prometheus: PodNotReady, DaemonSetUnavailable",
+		"kube-state-metrics of the cluster is not scraped",
+		"failed to list PrometheusRule: forbidden",
+	}, rulesWarnings(check))
+	assert.Empty(t, rulesWarnings(&RulesCheck{KubeStateMetrics: true}))
+}
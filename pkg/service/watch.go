@@ -0,0 +1,490 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/fidelity/theliv/pkg/common"
+	"github.com/fidelity/theliv/pkg/config"
+	"github.com/fidelity/theliv/pkg/kubeclient"
+	log "github.com/fidelity/theliv/pkg/log"
+	"github.com/fidelity/theliv/pkg/observability/k8s"
+	"github.com/fidelity/theliv/pkg/prometheus"
+	"github.com/fidelity/theliv/pkg/watch"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	defaultResyncSeconds    = 600
+	defaultAlertPollSeconds = 60
+	defaultDebounceSeconds  = 10
+	// a detection waits at most this many debounce periods while the changes keep coming
+	maxDebouncePeriods = 6
+)
+
+// namespaceWatcher re-runs the detection of a namespace when triggered, triggers within the debounce period
+// are merged into one detection. Alert and node changes re-run the full detection, object changes re-run only
+// the investigators of the problems on the changed objects.
+type namespaceWatcher struct {
+	cluster   string
+	namespace string
+	input     *problem.DetectorCreationInput
+	factory   informers.SharedInformerFactory
+	trigger   chan struct{}
+	debounce  time.Duration
+
+	mx sync.Mutex
+	// objects changed since the last detection, and their controllers
+	changed map[problem.ResourceKey]bool
+	full    bool
+	// problems of the last detection, only accessed by run
+	problems []*problem.Problem
+}
+
+// StartWatch starts the background watch mode for the configured clusters, does nothing if not configured.
+// Watchers stop when ctx is done.
+func StartWatch(ctx context.Context) {
+	conf := config.GetThelivConfig().Watch
+	if conf == nil || len(conf.Clusters) == 0 {
+		return
+	}
+	for _, c := range conf.Clusters {
+		if err := watchCluster(ctx, conf, c); err != nil {
+			log.SWithContext(ctx).Errorf("Failed to watch cluster %s, error is %s", c.Name, err)
+		}
+	}
+}
+
+// Detect returns the findings kept by watch mode if the namespace is watched, otherwise runs DetectAlerts.
+// Historical detection always runs DetectAlerts.
+func Detect(ctx context.Context) (interface{}, error) {
+	input := GetDetectorInput(ctx)
+	// watched findings are grouped by the strategies in config
+	if input.Window != nil || len(input.Grouping) > 0 {
+		return DetectAlerts(ctx)
+	}
+	if snapshot := watch.GetStore().Get(input.ClusterName, input.Namespace); snapshot != nil {
+		log.SWithContext(ctx).Infof("Found watched findings updated at %s", snapshot.UpdatedAt)
+		return snapshot.Cards, nil
+	}
+	return DetectAlerts(ctx)
+}
+
+func watchCluster(ctx context.Context, conf *config.WatchConfig, c config.WatchCluster) error {
+	cluster, err := config.GetConfigLoader().GetKubernetesConfig(ctx, c.Name)
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := cluster.GetKubeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+	// the kube client is shared by the detections of all the namespaces
+	client, err := kubeclient.NewKubeClient(ctx, kubeconfig)
+	if err != nil {
+		return err
+	}
+	resync := seconds(conf.ResyncSeconds, defaultResyncSeconds)
+
+	watchers := make([]*namespaceWatcher, 0, len(c.Namespaces))
+	for _, ns := range c.Namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, resync, informers.WithNamespace(ns))
+		w := &namespaceWatcher{
+			cluster:   c.Name,
+			namespace: ns,
+			input: &problem.DetectorCreationInput{
+				Kubeconfig:     kubeconfig,
+				ClusterName:    c.Name,
+				Namespace:      ns,
+				KubeClient:     client,
+				EventRetriever: k8s.NewInformerEventRetriever(factory.Core().V1().Events().Lister()),
+				Prometheus:     cluster.GetPrometheusEndpoint(),
+			},
+			factory:  factory,
+			trigger:  make(chan struct{}, 1),
+			debounce: seconds(conf.DebounceSeconds, defaultDebounceSeconds),
+			changed:  make(map[problem.ResourceKey]bool),
+		}
+		handler := changeHandler(w.notifyChange)
+		for _, informer := range w.informers() {
+			informer.AddEventHandler(handler)
+		}
+		// events are read by investigators from the informer cache, not a trigger
+		factory.Core().V1().Events().Informer()
+		factory.Start(ctx.Done())
+		watchers = append(watchers, w)
+	}
+
+	// nodes are cluster scoped, node condition changes trigger all the namespaces
+	nodeFactory := informers.NewSharedInformerFactory(clientset, resync)
+	nodeFactory.Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if nodeConditionsChanged(oldObj, newObj) {
+				notifyAll(watchers)
+			}
+		},
+		DeleteFunc: func(obj interface{}) { notifyAll(watchers) },
+	})
+	nodeFactory.Start(ctx.Done())
+
+	for _, w := range watchers {
+		go w.run(ctx)
+		w.notifyAll()
+	}
+	input := &problem.DetectorCreationInput{Kubeconfig: kubeconfig, ClusterName: c.Name,
+		Prometheus: cluster.GetPrometheusEndpoint()}
+	go pollAlerts(ctx, input, seconds(conf.AlertPollSeconds, defaultAlertPollSeconds), watchers)
+	log.SWithContext(ctx).Infof("Watching %d namespaces of cluster %s", len(watchers), c.Name)
+	return nil
+}
+
+// Triggers on add, delete, and update with new resource version, resync does not trigger.
+func changeHandler(notify func(obj interface{})) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { notify(obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			o, ok1 := oldObj.(metav1.Object)
+			n, ok2 := newObj.(metav1.Object)
+			if !ok1 || !ok2 || o.GetResourceVersion() != n.GetResourceVersion() {
+				notify(newObj)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			notify(obj)
+		},
+	}
+}
+
+func nodeConditionsChanged(oldObj, newObj interface{}) bool {
+	o, ok1 := oldObj.(*corev1.Node)
+	n, ok2 := newObj.(*corev1.Node)
+	if !ok1 || !ok2 {
+		return true
+	}
+	status := func(node *corev1.Node) map[corev1.NodeConditionType]corev1.ConditionStatus {
+		m := make(map[corev1.NodeConditionType]corev1.ConditionStatus)
+		for _, c := range node.Status.Conditions {
+			m[c.Type] = c.Status
+		}
+		return m
+	}
+	oldStatus, newStatus := status(o), status(n)
+	if len(oldStatus) != len(newStatus) {
+		return true
+	}
+	for k, v := range oldStatus {
+		if newStatus[k] != v {
+			return true
+		}
+	}
+	return false
+}
+
+// Polls the alerts of the cluster, triggers all the namespaces if the alerts changed.
+func pollAlerts(ctx context.Context, input *problem.DetectorCreationInput, interval time.Duration,
+	watchers []*namespaceWatcher) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			alerts, err := prometheus.GetAlerts(ctx, input)
+			if err != nil {
+				log.SWithContext(ctx).Warnf("Failed to poll alerts of cluster %s, error is %s", input.ClusterName, err)
+				continue
+			}
+			if h := alertsHash(alerts.Alerts); h != last {
+				last = h
+				notifyAll(watchers)
+			}
+		}
+	}
+}
+
+// Hash of the alert labels and states, the order of alerts is ignored.
+func alertsHash(alerts []v1.Alert) string {
+	keys := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		keys = append(keys, a.Labels.String()+string(a.State))
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func notifyAll(watchers []*namespaceWatcher) {
+	for _, w := range watchers {
+		w.notifyAll()
+	}
+}
+
+// Objects watched in the namespace, their changes trigger the detection.
+var watchedObjects = []runtime.Object{
+	&corev1.Pod{},
+	&corev1.Service{},
+	&corev1.Endpoints{},
+	&appsv1.Deployment{},
+	&appsv1.ReplicaSet{},
+	&appsv1.StatefulSet{},
+	&appsv1.DaemonSet{},
+	&batchv1.Job{},
+	&networkv1.Ingress{},
+}
+
+func (w *namespaceWatcher) informers() []cache.SharedIndexInformer {
+	informers := make([]cache.SharedIndexInformer, 0, len(watchedObjects))
+	for _, obj := range watchedObjects {
+		informers = append(informers, w.informerFor(obj))
+	}
+	return informers
+}
+
+// Returns the shared informer of the object type, nil if the type is not watched.
+func (w *namespaceWatcher) informerFor(obj runtime.Object) cache.SharedIndexInformer {
+	switch obj.(type) {
+	case *corev1.Pod:
+		return w.factory.Core().V1().Pods().Informer()
+	case *corev1.Service:
+		return w.factory.Core().V1().Services().Informer()
+	case *corev1.Endpoints:
+		return w.factory.Core().V1().Endpoints().Informer()
+	case *appsv1.Deployment:
+		return w.factory.Apps().V1().Deployments().Informer()
+	case *appsv1.ReplicaSet:
+		return w.factory.Apps().V1().ReplicaSets().Informer()
+	case *appsv1.StatefulSet:
+		return w.factory.Apps().V1().StatefulSets().Informer()
+	case *appsv1.DaemonSet:
+		return w.factory.Apps().V1().DaemonSets().Informer()
+	case *batchv1.Job:
+		return w.factory.Batch().V1().Jobs().Informer()
+	case *networkv1.Ingress:
+		return w.factory.Networking().V1().Ingresses().Informer()
+	}
+	return nil
+}
+
+// Triggers the full detection, the alerts or nodes changed.
+func (w *namespaceWatcher) notifyAll() {
+	w.mx.Lock()
+	w.full = true
+	w.mx.Unlock()
+	w.notify()
+}
+
+// Triggers the investigation of the problems on the changed object.
+func (w *namespaceWatcher) notifyChange(obj interface{}) {
+	keys := w.changedKeys(obj)
+	w.mx.Lock()
+	for _, k := range keys {
+		w.changed[k] = true
+	}
+	w.mx.Unlock()
+	w.notify()
+}
+
+// Non-blocking, a pending trigger covers the new one.
+func (w *namespaceWatcher) notify() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Keys of the changed object and its controllers, e.g. a pod change re-investigates the problems of its deployment,
+// an endpoints change the problems of its service.
+func (w *namespaceWatcher) changedKeys(obj interface{}) []problem.ResourceKey {
+	o, ok1 := obj.(runtime.Object)
+	mo, ok2 := obj.(metav1.Object)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	ns := mo.GetNamespace()
+	keys := []problem.ResourceKey{problem.ObjectKey(o)}
+	if _, ok := obj.(*corev1.Endpoints); ok {
+		keys = append(keys, problem.NewResourceKey("Service", ns, mo.GetName()))
+	}
+	owner := metav1.GetControllerOf(mo)
+	if owner == nil {
+		return keys
+	}
+	keys = append(keys, problem.NewResourceKey(owner.Kind, ns, owner.Name))
+	if owner.Kind == "ReplicaSet" {
+		rs, err := w.factory.Apps().V1().ReplicaSets().Lister().ReplicaSets(ns).Get(owner.Name)
+		if err == nil {
+			if deploy := metav1.GetControllerOf(rs); deploy != nil {
+				keys = append(keys, problem.NewResourceKey(deploy.Kind, ns, deploy.Name))
+			}
+		}
+	}
+	return keys
+}
+
+// Returns the changed objects since the last detection, and if the full detection is required.
+func (w *namespaceWatcher) takeChanges() (map[problem.ResourceKey]bool, bool) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	changed, full := w.changed, w.full
+	w.changed, w.full = make(map[problem.ResourceKey]bool), false
+	return changed, full
+}
+
+func (w *namespaceWatcher) run(ctx context.Context) {
+	// investigators read events from the informer cache
+	w.factory.WaitForCacheSync(ctx.Done())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.trigger:
+		}
+		// wait until there is no change within the debounce period, changes during the wait are covered by this detection
+		deadline := time.Now().Add(maxDebouncePeriods * w.debounce)
+		for settled := false; !settled; {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.debounce):
+			}
+			select {
+			case <-w.trigger:
+				settled = !time.Now().Before(deadline)
+			default:
+				settled = true
+			}
+		}
+		w.detect(ctx)
+	}
+}
+
+func (w *namespaceWatcher) detect(ctx context.Context) {
+	changed, full := w.takeChanges()
+	ctx = SetDetectorInput(ctx, w.input)
+
+	var cards []*problem.ReportCard
+	if full || w.problems == nil {
+		// the recurrence is queried by the first detection, re-runs keep it
+		w.input.Refresh = w.problems != nil
+		problems, err := detectProblems(ctx, w.input)
+		if err != nil {
+			log.SWithContext(ctx).Errorf("Watch detection failed for %s/%s, error is %s", w.cluster, w.namespace, err)
+			return
+		}
+		keepRecurrence(w.problems, problems)
+		w.problems = problems
+		cards = aggregateProblems(ctx, w.input, nil, problems)
+	} else {
+		cards = w.reinvestigate(ctx, changed)
+	}
+	changes := watch.GetStore().Update(ctx, w.cluster, w.namespace, cards)
+	log.SWithContext(ctx).Infof("Watch detection of %s/%s generated %d report cards, %d findings changed",
+		w.cluster, w.namespace, len(cards), len(changes))
+}
+
+// Copies the recurrence of the previous problems to the problems of the same alert.
+func keepRecurrence(previous []*problem.Problem, problems []*problem.Problem) {
+	recurrences := make(map[string]*problem.Recurrence)
+	for _, p := range previous {
+		if p.Recurrence != nil {
+			recurrences[problem.RecurrenceKey(p.Tags)] = p.Recurrence
+		}
+	}
+	for _, p := range problems {
+		if r, ok := recurrences[problem.RecurrenceKey(p.Tags)]; ok && p.Recurrence == nil {
+			setRecurrence(p, r)
+		}
+	}
+}
+
+// Re-runs the investigators of the problems on the changed objects, their affected resources are reloaded from
+// the informer cache, problems of the deleted objects are dropped. Other problems keep their findings.
+func (w *namespaceWatcher) reinvestigate(ctx context.Context, changed map[problem.ResourceKey]bool) []*problem.ReportCard {
+	investigated := make([]*problem.Problem, 0, len(w.problems))
+	stale := make([]*problem.Problem, 0)
+	for _, p := range w.problems {
+		if !changed[problem.ObjectKey(p.AffectedResources.Resource)] {
+			investigated = append(investigated, p)
+			continue
+		}
+		obj, err := w.cached(p.AffectedResources.Resource)
+		if err != nil {
+			log.SWithContext(ctx).Warnf("Failed to reload %s from informer cache, error is %s",
+				problem.ObjectKey(p.AffectedResources.Resource), err)
+			investigated = append(investigated, p)
+			continue
+		}
+		if obj != nil {
+			stale = append(stale, refreshProblem(p, obj))
+		}
+	}
+	log.SWithContext(ctx).Infof("Re-investigating %d of %d problems of %s/%s", len(stale), len(w.problems),
+		w.cluster, w.namespace)
+	w.problems = append(slices.Clone(investigated), stale...)
+	return aggregateProblems(ctx, w.input, investigated, stale)
+}
+
+// Copy of the problem to investigate again, with the reloaded resource and without findings.
+func refreshProblem(p *problem.Problem, obj runtime.Object) *problem.Problem {
+	refreshed := *p
+	refreshed.Tags = maps.Clone(p.Tags)
+	refreshed.SolutionDetails = common.InitLockedSlice()
+	refreshed.UsefulCommands = common.InitLockedSlice()
+	refreshed.Findings = problem.InitFindings()
+	refreshed.AffectedResources.Resource = obj
+	return &refreshed
+}
+
+// Returns the object from the informer cache, nil if deleted. Objects not watched, e.g. nodes, are returned as is.
+func (w *namespaceWatcher) cached(obj runtime.Object) (runtime.Object, error) {
+	mo, ok := obj.(metav1.Object)
+	informer := w.informerFor(obj)
+	if !ok || informer == nil {
+		return obj, nil
+	}
+	item, exists, err := informer.GetIndexer().GetByKey(mo.GetNamespace() + "/" + mo.GetName())
+	if err != nil || !exists {
+		return nil, err
+	}
+	return item.(runtime.Object).DeepCopyObject(), nil
+}
+
+func seconds(value int, defaultValue int) time.Duration {
+	if value <= 0 {
+		value = defaultValue
+	}
+	return time.Duration(value) * time.Second
+}
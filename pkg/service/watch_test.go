@@ -0,0 +1,87 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package service
+
+import (
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestWatcher(t *testing.T, objects ...runtime.Object) *namespaceWatcher {
+	w := &namespaceWatcher{
+		namespace: "team-a",
+		factory:   informers.NewSharedInformerFactory(fake.NewClientset(), 0),
+		trigger:   make(chan struct{}, 1),
+		changed:   make(map[problem.ResourceKey]bool),
+	}
+	for _, obj := range objects {
+		assert.NoError(t, w.informerFor(obj).GetIndexer().Add(obj))
+	}
+	return w
+}
+
+func controlledBy(kind string, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func TestWatcherChangedKeys(t *testing.T) {
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f", Namespace: "team-a",
+		OwnerReferences: controlledBy("Deployment", "web")}}
+	w := newTestWatcher(t, rs)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-5d8f-x2c", Namespace: "team-a",
+		OwnerReferences: controlledBy("ReplicaSet", "web-5d8f")}}
+	w.notifyChange(pod)
+	w.notifyChange(&corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "web-svc", Namespace: "team-a"}})
+
+	changed, full := w.takeChanges()
+	assert.False(t, full)
+	assert.Equal(t, map[problem.ResourceKey]bool{
+		"Pod/team-a/web-5d8f-x2c":    true,
+		"ReplicaSet/team-a/web-5d8f": true,
+		"Deployment/team-a/web":      true,
+		"Endpoints/team-a/web-svc":   true,
+		"Service/team-a/web-svc":     true,
+	}, changed)
+
+	w.notifyAll()
+	changed, full = w.takeChanges()
+	assert.True(t, full)
+	assert.Empty(t, changed)
+}
+
+func TestWatcherCached(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "team-a"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+	w := newTestWatcher(t, pod)
+
+	stale := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "team-a"},
+		Status: corev1.PodStatus{Phase: corev1.PodPending}}
+	obj, err := w.cached(stale)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.PodRunning, obj.(*corev1.Pod).Status.Phase)
+	assert.NotSame(t, pod, obj)
+
+	// deleted
+	obj, err = w.cached(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "team-a"}})
+	assert.NoError(t, err)
+	assert.Nil(t, obj)
+
+	// nodes are not watched in the namespace
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	obj, err = w.cached(node)
+	assert.NoError(t, err)
+	assert.Same(t, node, obj)
+}
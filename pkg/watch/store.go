@@ -0,0 +1,132 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package watch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fidelity/theliv/internal/problem"
+)
+
+type ChangeType string
+
+const (
+	FindingAppeared ChangeType = "appeared"
+	FindingResolved ChangeType = "resolved"
+)
+
+// FindingChange is published when a finding appears or resolves in a watched namespace.
+type FindingChange struct {
+	Type      ChangeType         `json:"type"`
+	Cluster   string             `json:"cluster"`
+	Namespace string             `json:"namespace"`
+	Card      *problem.CardDiff  `json:"card"`
+	Finding   *problem.IssueDiff `json:"finding"`
+	Time      time.Time          `json:"time"`
+}
+
+// Subscriber receives the finding changes of all the watched namespaces.
+// It is called synchronously by the watcher, long running work should be done in another goroutine.
+type Subscriber interface {
+	OnFindingChange(ctx context.Context, change FindingChange)
+}
+
+// Snapshot is the current findings of a watched namespace.
+type Snapshot struct {
+	Cluster   string                `json:"cluster"`
+	Namespace string                `json:"namespace"`
+	Cards     []*problem.ReportCard `json:"cards"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// Store keeps the snapshots of the watched namespaces in memory, and publishes the changes to subscribers.
+type Store struct {
+	mx          sync.RWMutex
+	snapshots   map[string]*Snapshot
+	subscribers map[int]Subscriber
+	nextID      int
+}
+
+var defaultStore = NewStore()
+
+// GetStore returns the store shared by the watchers and the detect API.
+func GetStore() *Store {
+	return defaultStore
+}
+
+func NewStore() *Store {
+	return &Store{
+		snapshots:   make(map[string]*Snapshot),
+		subscribers: make(map[int]Subscriber),
+	}
+}
+
+// Get returns the snapshot of the namespace, nil if the namespace is not watched or not detected yet.
+func (s *Store) Get(cluster string, namespace string) *Snapshot {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.snapshots[snapshotKey(cluster, namespace)]
+}
+
+// Update replaces the snapshot of the namespace, the findings appeared or resolved since the previous snapshot
+// are published. For the first snapshot of a namespace, all the findings are appeared.
+func (s *Store) Update(ctx context.Context, cluster string, namespace string, cards []*problem.ReportCard) []FindingChange {
+	now := time.Now()
+	s.mx.Lock()
+	key := snapshotKey(cluster, namespace)
+	var previous []*problem.ReportCard
+	if old, ok := s.snapshots[key]; ok {
+		previous = old.Cards
+	}
+	s.snapshots[key] = &Snapshot{Cluster: cluster, Namespace: namespace, Cards: cards, UpdatedAt: now}
+	subscribers := make([]Subscriber, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	s.mx.Unlock()
+
+	changes := make([]FindingChange, 0)
+	for _, card := range problem.DiffCards(previous, cards) {
+		for _, issue := range card.Issues {
+			change := FindingChange{Cluster: cluster, Namespace: namespace, Card: card, Finding: issue, Time: now}
+			switch issue.Status {
+			case problem.DiffNew:
+				change.Type = FindingAppeared
+			case problem.DiffResolved:
+				change.Type = FindingResolved
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	for _, change := range changes {
+		for _, sub := range subscribers {
+			sub.OnFindingChange(ctx, change)
+		}
+	}
+	return changes
+}
+
+// Subscribe registers the subscriber, call the returned function to unsubscribe.
+func (s *Store) Subscribe(sub Subscriber) func() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.subscribers[id] = sub
+	return func() {
+		s.mx.Lock()
+		defer s.mx.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+func snapshotKey(cluster string, namespace string) string {
+	return cluster + "/" + namespace
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright FMR LLC <opensource@fidelity.com>
+ *
+ * SPDX-License-Identifier: Apache
+ */
+package watch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fidelity/theliv/internal/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	changes []FindingChange
+}
+
+func (r *recorder) OnFindingChange(ctx context.Context, change FindingChange) {
+	r.changes = append(r.changes, change)
+}
+
+func newCard(name string, codes ...problem.ErrorCode) *problem.ReportCard {
+	card := &problem.ReportCard{Name: name, TopResourceType: "Deployment"}
+	for _, code := range codes {
+		card.Resources = append(card.Resources, &problem.ReportCardResource{Issue: &problem.ReportCardIssue{Code: code}})
+	}
+	return card
+}
+
+func TestStoreUpdate(t *testing.T) {
+	store := NewStore()
+	rec := &recorder{}
+	unsubscribe := store.Subscribe(rec)
+	ctx := context.Background()
+
+	assert.Nil(t, store.Get("c1", "ns1"))
+	store.Update(ctx, "c1", "ns1", []*problem.ReportCard{newCard("web", problem.CrashLoopErr)})
+	assert.Len(t, rec.changes, 1)
+	assert.Equal(t, FindingAppeared, rec.changes[0].Type)
+
+	// the same findings, nothing published
+	store.Update(ctx, "c1", "ns1", []*problem.ReportCard{newCard("web", problem.CrashLoopErr)})
+	assert.Len(t, rec.changes, 1)
+
+	store.Update(ctx, "c1", "ns1", []*problem.ReportCard{newCard("web", problem.OOMKilledErr)})
+	assert.Len(t, rec.changes, 3)
+	types := map[problem.ErrorCode]ChangeType{}
+	for _, c := range rec.changes[1:] {
+		types[c.Finding.Issue.Code] = c.Type
+	}
+	assert.Equal(t, map[problem.ErrorCode]ChangeType{
+		problem.CrashLoopErr: FindingResolved,
+		problem.OOMKilledErr: FindingAppeared,
+	}, types)
+	assert.Len(t, store.Get("c1", "ns1").Cards, 1)
+
+	unsubscribe()
+	store.Update(ctx, "c1", "ns1", nil)
+	assert.Len(t, rec.changes, 3)
+}